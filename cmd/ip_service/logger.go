@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newLogger creates the service logger like logger.New, but with a level that can be changed at runtime
+func newLogger(name, logPath string, production bool, level string) (*logger.Log, zap.AtomicLevel, error) {
+	var zc zap.Config
+
+	switch production {
+	case true:
+		zc = zap.NewProductionConfig()
+	case false:
+		zc = zap.NewDevelopmentConfig()
+		zc.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+
+	zc.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	zc.DisableCaller = true
+	zc.DisableStacktrace = true
+
+	zc.Level.SetLevel(configLevel(production, level))
+
+	if logPath != "" {
+		if err := os.MkdirAll(logPath, fs.ModeDir); err != nil {
+			return nil, zc.Level, err
+		}
+
+		zc.OutputPaths = []string{
+			filepath.Join(logPath, fmt.Sprintf("%s.log", name)),
+		}
+	}
+
+	z, err := zc.Build()
+	if err != nil {
+		return nil, zc.Level, err
+	}
+
+	return &logger.Log{Logger: zapr.NewLogger(z).WithName(name)}, zc.Level, nil
+}
+
+// configLevel maps a config log level to zap, logr verbosity V(n) is zap level -n.
+// Without a level, production logs info and development logs debug.
+func configLevel(production bool, level string) zapcore.Level {
+	switch level {
+	case "":
+		if production {
+			return zapcore.InfoLevel
+		}
+		return zapcore.DebugLevel
+	case "trace":
+		return zapcore.Level(-2)
+	case "debug":
+		return zapcore.DebugLevel
+	case "warn":
+		return zapcore.WarnLevel
+	case "error":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
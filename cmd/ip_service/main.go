@@ -3,12 +3,21 @@ package main
 import (
 	"context"
 	"ip_service/internal/apiv1"
+	"ip_service/internal/bgp"
+	"ip_service/internal/blocklist"
+	"ip_service/internal/cloud"
+	"ip_service/internal/feeds"
+	"ip_service/internal/georange"
 	"ip_service/internal/httpserver"
 	"ip_service/internal/lctree"
 	"ip_service/internal/maxmind"
+	"ip_service/internal/mmdbexport"
+	"ip_service/internal/prefixlabels"
+	"ip_service/internal/resolver"
 	"ip_service/internal/store"
 	"ip_service/internal/whois"
 	"ip_service/pkg/configuration"
+	"os"
 	"os/signal"
 	"runtime"
 	"runtime/debug"
@@ -34,7 +43,7 @@ func main() {
 		panic(err)
 	}
 
-	log, err := logger.New("ip_service", cfg.IPService.Log.FolderPath, cfg.IPService.Production)
+	log, logLevel, err := newLogger("ip_service", cfg.IPService.Log.FolderPath, cfg.IPService.Production, cfg.IPService.Log.Level)
 	if err != nil {
 		panic(err)
 	}
@@ -75,11 +84,126 @@ func main() {
 	runtime.GC()
 	debug.FreeOSMemory()
 
-	apiv1, err := apiv1.New(ctx, max, whoisService, store, cfg, tracer, log.New("apiv1"))
+	// a nil *feeds.Service is not a nil AnonymityFeeds, so the optional lookups are only set when configured
+	opts := apiv1.Options{}
+
+	// csv range tables are asked before maxmind when primary, as a fallback otherwise
+	geoProviders := []apiv1.GeoProvider{max}
+	var geoRange *georange.Service
+	if len(cfg.IPService.GeoRange.Sources) > 0 {
+		geoRange, err = georange.New(ctx, cfg, log.New("georange"))
+		services["georange"] = geoRange
+		if err != nil {
+			panic(err)
+		}
+		opts.Datasets = append(opts.Datasets, geoRange)
+		if cfg.IPService.GeoRange.Primary {
+			geoProviders = []apiv1.GeoProvider{geoRange, max}
+		} else {
+			geoProviders = append(geoProviders, geoRange)
+		}
+	}
+
+	prefixLabels, err := prefixlabels.New(ctx, cfg, log.New("prefixlabels"))
+	services["prefixlabels"] = prefixLabels
+	if err != nil {
+		panic(err)
+	}
+	opts.Labels = prefixLabels
+
+	var feedService *feeds.Service
+	if len(cfg.IPService.Feeds.Sources) > 0 {
+		feedService, err = feeds.New(ctx, cfg, log.New("feeds"))
+		services["feeds"] = feedService
+		if err != nil {
+			panic(err)
+		}
+		opts.Datasets = append(opts.Datasets, feedService)
+		opts.Feeds = feedService
+	}
+
+	var blocklistService *blocklist.Service
+	if len(cfg.IPService.Blocklists.Sources) > 0 {
+		blocklistService, err = blocklist.New(ctx, cfg, log.New("blocklist"))
+		services["blocklist"] = blocklistService
+		if err != nil {
+			panic(err)
+		}
+		opts.Datasets = append(opts.Datasets, blocklistService)
+		opts.Blocklists = blocklistService
+	}
+
+	var cloudService *cloud.Service
+	if len(cfg.IPService.Cloud.Sources) > 0 {
+		cloudService, err = cloud.New(ctx, cfg, log.New("cloud"))
+		services["cloud"] = cloudService
+		if err != nil {
+			panic(err)
+		}
+		opts.Datasets = append(opts.Datasets, cloudService)
+		opts.Cloud = cloudService
+	}
+
+	var bgpService *bgp.Service
+	if cfg.IPService.BGP.FilePath != "" {
+		bgpService, err = bgp.New(ctx, cfg, log.New("bgp"))
+		services["bgp"] = bgpService
+		if err != nil {
+			panic(err)
+		}
+		opts.Datasets = append(opts.Datasets, bgpService)
+		opts.Routes = bgpService
+	}
+
+	resolverService, err := resolver.New(ctx, cfg, log.New("resolver"))
+	services["resolver"] = resolverService
 	if err != nil {
 		panic(err)
 	}
-	httpserver, err := httpserver.New(ctx, cfg, apiv1, tracer, log.New("httpserver"))
+
+	exporter, err := mmdbexport.New(ctx, max, whoisService, prefixLabels, log.New("mmdbexport"))
+	services["mmdbexport"] = exporter
+	if err != nil {
+		panic(err)
+	}
+
+	opts.Geo = apiv1.NewGeoChain(log.New("geo"), geoProviders...)
+	opts.Resolver = resolverService
+	opts.Exporter = exporter
+
+	apiv1, err := apiv1.New(ctx, max, whoisService, store, opts, cfg, tracer, log.New("apiv1"))
+	services["apiv1"] = apiv1
+	if err != nil {
+		panic(err)
+	}
+
+	// cached replies are stale as soon as a dataset is reloaded
+	max.OnReload(apiv1.InvalidateCache)
+	ipTree.OnReload(apiv1.InvalidateCache)
+	prefixLabels.OnReload(apiv1.InvalidateCache)
+	if geoRange != nil {
+		geoRange.OnReload(apiv1.InvalidateCache)
+	}
+	if feedService != nil {
+		feedService.OnReload(apiv1.InvalidateCache)
+	}
+	if blocklistService != nil {
+		blocklistService.OnReload(apiv1.InvalidateCache)
+	}
+	if cloudService != nil {
+		cloudService.OnReload(apiv1.InvalidateCache)
+	}
+	if bgpService != nil {
+		bgpService.OnReload(apiv1.InvalidateCache)
+	}
+
+	// production requires a restart, so it is fixed for the admin log level setter
+	production := cfg.IPService.Production
+	apiv1.OnLogLevel(func(level string) {
+		logLevel.SetLevel(configLevel(production, level))
+	})
+
+	httpserver, err := httpserver.New(ctx, cfg, apiv1, store, tracer, log.New("httpserver"))
 	services["httpserver"] = httpserver
 	if err != nil {
 		panic(err)
@@ -96,6 +220,50 @@ func main() {
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			mainLog.Info("SIGHUP, reloading configuration")
+			newCfg, err := configuration.Parse(ctx, mainLog.New("configuration"))
+			if err != nil {
+				mainLog.Error(err, "configuration reload failed, keeping the running configuration")
+				continue
+			}
+
+			for _, change := range configuration.Diff(cfg, newCfg) {
+				if change.RestartRequired {
+					mainLog.Info("configuration change requires a restart", "path", change.Path)
+					continue
+				}
+				mainLog.Info("configuration change applied", "path", change.Path)
+			}
+
+			logLevel.SetLevel(configLevel(newCfg.IPService.Production, newCfg.IPService.Log.Level))
+			httpserver.Reload(ctx, newCfg)
+			max.Reload(ctx, newCfg)
+			whoisService.Reload(ctx, newCfg)
+			prefixLabels.Reload(ctx, newCfg)
+			if geoRange != nil {
+				geoRange.Reload(ctx, newCfg)
+			}
+			if feedService != nil {
+				feedService.Reload(ctx, newCfg)
+			}
+			if blocklistService != nil {
+				blocklistService.Reload(ctx, newCfg)
+			}
+			if cloudService != nil {
+				cloudService.Reload(ctx, newCfg)
+			}
+			if bgpService != nil {
+				bgpService.Reload(ctx, newCfg)
+			}
+
+			cfg = newCfg
+		}
+	}()
+
 	<-ctx.Done()
 	mainLog.Info("HALTING SIGNAL!")
 
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+var host = "172.17.0.1:8181"
+
+func init() {
+	if h := os.Getenv("IP_SERVICE_HOST"); h != "" {
+		host = h
+	}
+}
+
+// mmdb_export downloads the merged mmdb from a running ip_service and installs it atomically,
+// so nginx, HAProxy or Suricata never read a partial file.
+func main() {
+	var (
+		url    = flag.String("url", "", "export url, defaults to http://<IP_SERVICE_HOST>/admin/export/mmdb")
+		token  = flag.String("token", os.Getenv("IP_SERVICE_ADMIN_TOKEN"), "admin token, defaults to IP_SERVICE_ADMIN_TOKEN")
+		output = flag.String("output", "ip_service.mmdb", "path of the mmdb file")
+	)
+	flag.Parse()
+
+	if *url == "" {
+		*url = fmt.Sprintf("http://%s/admin/export/mmdb", host)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if err := export(ctx, *url, *token, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "export failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func export(ctx context.Context, url, token, output string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("errors http status code: %d, %s", resp.StatusCode, body)
+	}
+
+	reader, err := maxminddb.FromBytes(body)
+	if err != nil {
+		return fmt.Errorf("invalid mmdb: %w", err)
+	}
+	if err := reader.Verify(); err != nil {
+		return fmt.Errorf("invalid mmdb: %w", err)
+	}
+
+	// write next to output, so the rename is atomic
+	tmp, err := os.CreateTemp(filepath.Dir(output), ".mmdb_export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), output); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s, %d bytes, %d nodes, built %s\n", output, len(body), reader.Metadata.NodeCount, time.Unix(int64(reader.Metadata.BuildEpoch), 0).UTC().Format(time.RFC3339))
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package apiv1
+
+import (
+	"context"
+	"fmt"
+	"ip_service/pkg/model"
+	"ip_service/pkg/rpsl"
+	"net"
+	"slices"
+	"strings"
+)
+
+// RoutedPrefixes returns the announced prefix holding an IP, e.g. bgp.Service
+type RoutedPrefixes interface {
+	LookupRoute(ctx context.Context, ip net.IP) *model.BGPRoute
+}
+
+// lookupRoute returns the announced prefix holding ip compared with the origins of its IRR route objects,
+// nil without a RIB dump or when ip is not routed
+func (c *Client) lookupRoute(ctx context.Context, ip net.IP, irr rpsl.ASN) *model.BGPRoute {
+	if c.routes == nil {
+		return nil
+	}
+	route := c.routes.LookupRoute(ctx, ip)
+	if route == nil {
+		return nil
+	}
+
+	for origin, object := range irr {
+		if object != nil && object.Origin != "" {
+			origin = object.Origin
+		}
+		route.IRROrigins = append(route.IRROrigins, strings.ToUpper(strings.TrimSpace(origin)))
+	}
+	slices.Sort(route.IRROrigins)
+	route.IRROrigins = slices.Compact(route.IRROrigins)
+
+	if len(route.IRROrigins) > 0 {
+		route.IRROriginMismatch = !slices.ContainsFunc(route.OriginASNs, func(asn uint32) bool {
+			_, found := slices.BinarySearch(route.IRROrigins, fmt.Sprintf("AS%d", asn))
+			return found
+		})
+	}
+
+	return route
+}
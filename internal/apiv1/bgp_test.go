@@ -0,0 +1,87 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"ip_service/pkg/rpsl"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRoutes is an in-memory RoutedPrefixes keyed by IP
+type fakeRoutes map[string]*model.BGPRoute
+
+func (f fakeRoutes) LookupRoute(ctx context.Context, ip net.IP) *model.BGPRoute {
+	route, ok := f[ip.String()]
+	if !ok {
+		return nil
+	}
+	copied := *route
+	return &copied
+}
+
+func TestLookupRoute(t *testing.T) {
+	c := &Client{
+		routes: fakeRoutes{
+			"130.242.1.1": {Prefix: "130.242.0.0/16", OriginASNs: []uint32{1653}, ASPathLength: 2, PeersSeen: 3, Peers: 3},
+			"130.242.1.2": {Prefix: "130.242.0.0/16", OriginASNs: []uint32{64500, 1653}, ASPathLength: 2, PeersSeen: 3, Peers: 3},
+		},
+	}
+
+	tts := []struct {
+		name         string
+		ip           string
+		irr          rpsl.ASN
+		wantOrigins  []string
+		wantMismatch bool
+		wantNil      bool
+	}{
+		{
+			name:        "registered origin",
+			ip:          "130.242.1.1",
+			irr:         rpsl.ASN{"AS1653": {Network: "130.242.0.0/16", Origin: "AS1653"}},
+			wantOrigins: []string{"AS1653"},
+		},
+		{
+			name:         "unregistered origin",
+			ip:           "130.242.1.1",
+			irr:          rpsl.ASN{"AS64501": {Network: "130.242.0.0/16", Origin: "as64501"}, "AS64502": {Network: "130.242.0.0/16", Origin: "AS64502"}},
+			wantOrigins:  []string{"AS64501", "AS64502"},
+			wantMismatch: true,
+		},
+		{
+			name:        "one of the MOAS origins is registered",
+			ip:          "130.242.1.2",
+			irr:         rpsl.ASN{"AS1653": {Network: "130.242.0.0/16", Origin: "AS1653"}},
+			wantOrigins: []string{"AS1653"},
+		},
+		{
+			name: "no route object",
+			ip:   "130.242.1.1",
+		},
+		{
+			name:    "not routed",
+			ip:      "130.242.1.3",
+			irr:     rpsl.ASN{"AS1653": {Network: "130.242.0.0/16", Origin: "AS1653"}},
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.lookupRoute(context.TODO(), net.ParseIP(tt.ip), tt.irr)
+			if tt.wantNil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.wantOrigins, got.IRROrigins)
+			assert.Equal(t, tt.wantMismatch, got.IRROriginMismatch)
+		})
+	}
+
+	// without a RIB dump there is no bgp section
+	c.routes = nil
+	assert.Nil(t, c.lookupRoute(context.TODO(), net.ParseIP("130.242.1.1"), nil))
+}
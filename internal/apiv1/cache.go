@@ -0,0 +1,121 @@
+package apiv1
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"ip_service/pkg/model"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+const (
+	defaultLookupCacheSize = 10000
+	defaultLookupCacheTTL  = time.Hour
+)
+
+// replyCacheKey identifies a cached reply
+type replyCacheKey struct {
+	ip       string
+	language string
+}
+
+// replyCache holds assembled replies of one kind, a nil replyCache always assembles
+type replyCache[V any] struct {
+	name  string
+	cache *ttlcache.Cache[replyCacheKey, V]
+	// generation is bumped on invalidation, replies assembled from an older generation are not cached
+	generation atomic.Uint64
+}
+
+func newReplyCache[V any](name string, cfg model.LookupCache) *replyCache[V] {
+	size := cfg.Size
+	if size <= 0 {
+		size = defaultLookupCacheSize
+	}
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = defaultLookupCacheTTL
+	}
+
+	r := &replyCache[V]{
+		name: name,
+		cache: ttlcache.New(
+			ttlcache.WithTTL[replyCacheKey, V](ttl),
+			ttlcache.WithCapacity[replyCacheKey, V](uint64(size)),
+			ttlcache.WithDisableTouchOnHit[replyCacheKey, V](),
+		),
+	}
+
+	r.cache.OnEviction(func(ctx context.Context, reason ttlcache.EvictionReason, item *ttlcache.Item[replyCacheKey, V]) {
+		metrics.evictions.WithLabelValues(name, evictionReason(reason)).Inc()
+	})
+
+	go r.cache.Start()
+
+	return r
+}
+
+func evictionReason(reason ttlcache.EvictionReason) string {
+	switch reason {
+	case ttlcache.EvictionReasonCapacityReached:
+		return "capacity"
+	case ttlcache.EvictionReasonExpired:
+		return "expired"
+	default:
+		return "deleted"
+	}
+}
+
+// get returns the cached reply for key, or assembles and caches it
+func (r *replyCache[V]) get(key replyCacheKey, assemble func() (V, error)) (V, error) {
+	if r == nil {
+		return assemble()
+	}
+
+	if item := r.cache.Get(key); item != nil {
+		metrics.hits.WithLabelValues(r.name).Inc()
+		return item.Value(), nil
+	}
+	metrics.misses.WithLabelValues(r.name).Inc()
+
+	generation := r.generation.Load()
+
+	reply, err := assemble()
+	if err != nil {
+		return reply, err
+	}
+
+	if r.generation.Load() == generation {
+		r.cache.Set(key, reply, ttlcache.DefaultTTL)
+	}
+
+	return reply, nil
+}
+
+func (r *replyCache[V]) invalidate() {
+	if r == nil {
+		return
+	}
+	r.generation.Add(1)
+	r.cache.DeleteAll()
+}
+
+func (r *replyCache[V]) close() {
+	if r == nil {
+		return
+	}
+	r.cache.Stop()
+}
+
+// InvalidateCache flushes all cached replies, it is registered as a reload hook for the datasets
+func (c *Client) InvalidateCache(ctx context.Context) {
+	if c.allCache == nil && c.lookupCache == nil {
+		return
+	}
+	c.log.Debug("invalidate lookup cache")
+	c.allCache.invalidate()
+	c.lookupCache.invalidate()
+	metrics.invalidations.Inc()
+}
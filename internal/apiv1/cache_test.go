@@ -0,0 +1,88 @@
+package apiv1
+
+import (
+	"errors"
+	"testing"
+
+	"ip_service/pkg/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplyCache(t *testing.T) {
+	cache := newReplyCache[*model.ReplyLookUp]("test", model.LookupCache{Size: 2})
+	defer cache.close()
+
+	assembled := 0
+	assemble := func() (*model.ReplyLookUp, error) {
+		assembled++
+		return &model.ReplyLookUp{IP: "192.0.2.1"}, nil
+	}
+
+	key := replyCacheKey{ip: "192.0.2.1", language: "en"}
+
+	got, err := cache.get(key, assemble)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.1", got.IP)
+
+	_, err = cache.get(key, assemble)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, assembled, "second get should be a hit")
+
+	_, err = cache.get(replyCacheKey{ip: "192.0.2.1", language: "sv"}, assemble)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, assembled, "language is part of the key")
+
+	cache.invalidate()
+	_, err = cache.get(key, assemble)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, assembled, "invalidate flushes the cache")
+
+	// a reply assembled while the cache was invalidated is not cached
+	_, err = cache.get(replyCacheKey{ip: "198.51.100.1"}, func() (*model.ReplyLookUp, error) {
+		cache.invalidate()
+		return assemble()
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, cache.cache.Get(replyCacheKey{ip: "198.51.100.1"}))
+
+	// errors are not cached
+	_, err = cache.get(replyCacheKey{ip: "203.0.113.1"}, func() (*model.ReplyLookUp, error) {
+		return nil, errors.New("lookup failed")
+	})
+	assert.Error(t, err)
+	assert.Nil(t, cache.cache.Get(replyCacheKey{ip: "203.0.113.1"}))
+}
+
+func TestReplyCacheNil(t *testing.T) {
+	var cache *replyCache[*model.ReplyLookUp]
+
+	got, err := cache.get(replyCacheKey{}, func() (*model.ReplyLookUp, error) {
+		return &model.ReplyLookUp{IP: "192.0.2.1"}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.1", got.IP)
+
+	cache.invalidate()
+	cache.close()
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tts := []struct {
+		have string
+		want string
+	}{
+		{have: "", want: "en"},
+		{have: "de", want: "de"},
+		{have: "sv-SE", want: "en"},
+		{have: "fr-CA", want: "fr"},
+		{have: "pt", want: "pt-BR"},
+		{have: "zh-cn", want: "zh-CN"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.have, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchLanguage(tt.have))
+		})
+	}
+}
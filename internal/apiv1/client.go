@@ -6,7 +6,10 @@ import (
 	"ip_service/internal/store"
 	"ip_service/internal/whois"
 	"github.com/SUNET/vc/pkg/logger"
+	"ip_service/pkg/ipv6transition"
 	"ip_service/pkg/model"
+	"ip_service/pkg/oui"
+	"time"
 	"github.com/SUNET/vc/pkg/trace"
 )
 
@@ -20,22 +23,111 @@ type Client struct {
 	log    *logger.Log
 	tp     *trace.Tracer
 	max    *maxmind.Service
+	geo    GeoProvider
+	labels PrefixLabeler
+	feeds  AnonymityFeeds
 	whois  *whois.Service
 	store  *store.Service
+
+	// blocklists is nil when no blocklist is configured
+	blocklists Blocklists
+
+	// cloud is nil when no cloud range is configured
+	cloud CloudRanges
+
+	// routes is nil when no MRT RIB dump is configured
+	routes RoutedPrefixes
+
+	// exporter is nil when the mmdb export is not available
+	exporter Exporter
+
+	// datasets are the configured range, feed, list and dump datasets
+	datasets []Dataset
+
+	// transition decodes with the configured NAT64 prefixes, and oui holds the configured registry
+	transition *ipv6transition.Decoder
+	oui        *oui.Registry
+
+	// resolver is nil when reverse DNS is not available, it is then left out
+	resolver   Resolver
+	dnsTimeout time.Duration
+
+	allCache    *replyCache[*model.ReplyIPInformation]
+	lookupCache *replyCache[*model.ReplyLookUp]
+
+	// setLogLevel is registered with OnLogLevel
+	setLogLevel func(level string)
+}
+
+// Options holds the optional lookups of the public api, a nil lookup leaves its part out of the replies
+type Options struct {
+	// Geo answers the geo and ASN lookups, defaults to the maxmind databases
+	Geo        GeoProvider
+	Labels     PrefixLabeler
+	Feeds      AnonymityFeeds
+	Blocklists Blocklists
+	Cloud      CloudRanges
+	Routes     RoutedPrefixes
+	Resolver   Resolver
+	Exporter   Exporter
+	// Datasets are listed and updated by the admin API and probed for readiness
+	Datasets []Dataset
 }
 
-// New creates a new instance of public api
-func New(ctx context.Context, max *maxmind.Service, whois *whois.Service, store *store.Service, config *model.Cfg, tp *trace.Tracer, log *logger.Log) (*Client, error) {
+// New creates a new instance of public api, lookups use the lookups of opts, dataset operations use max
+func New(ctx context.Context, max *maxmind.Service, whois *whois.Service, store *store.Service, opts Options, config *model.Cfg, tp *trace.Tracer, log *logger.Log) (*Client, error) {
 	c := &Client{
-		config: config,
-		log:    log,
-		tp:     tp,
-		max:    max,
-		whois:  whois,
-		store:  store,
+		config:     config,
+		log:        log,
+		tp:         tp,
+		max:        max,
+		geo:        opts.Geo,
+		labels:     opts.Labels,
+		feeds:      opts.Feeds,
+		blocklists: opts.Blocklists,
+		cloud:      opts.Cloud,
+		routes:     opts.Routes,
+		resolver:   opts.Resolver,
+		exporter:   opts.Exporter,
+		datasets:   opts.Datasets,
+		whois:      whois,
+		store:      store,
+	}
+
+	if c.geo == nil {
+		c.geo = NewGeoChain(log, max)
+	}
+
+	if config.IPService != nil {
+		var err error
+		c.transition, err = ipv6transition.NewDecoder(config.IPService.IPv6Transition.NAT64Prefixes)
+		if err != nil {
+			return nil, err
+		}
+		if path := config.IPService.IPv6Transition.OUIFilePath; path != "" {
+			c.oui, err = oui.Load(path)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		c.dnsTimeout = dnsTimeout(config.IPService.DNS)
+	}
+
+	if config.IPService != nil && config.IPService.LookupCache.Enable {
+		c.allCache = newReplyCache[*model.ReplyIPInformation]("all", config.IPService.LookupCache)
+		c.lookupCache = newReplyCache[*model.ReplyLookUp]("lookup", config.IPService.LookupCache)
 	}
 
 	c.log.Info("Started")
 
 	return c, nil
 }
+
+// Close closes the public api
+func (c *Client) Close(ctx context.Context) error {
+	c.allCache.close()
+	c.lookupCache.close()
+	c.log.Info("Quit")
+	return nil
+}
@@ -0,0 +1,20 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+)
+
+// CloudRanges returns the cloud or CDN range holding an IP, e.g. cloud.Service
+type CloudRanges interface {
+	LookupCloud(ctx context.Context, ip net.IP) *model.CloudRange
+}
+
+// lookupCloud returns the provider range holding ip, nil without cloud ranges
+func (c *Client) lookupCloud(ctx context.Context, ip net.IP) *model.CloudRange {
+	if c.cloud == nil {
+		return nil
+	}
+	return c.cloud.LookupCloud(ctx, ip)
+}
@@ -0,0 +1,52 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/contexthandler"
+	"ip_service/pkg/model"
+	"net"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCloud is an in-memory CloudRanges keyed by IP
+type fakeCloud map[string]*model.CloudRange
+
+func (f fakeCloud) LookupCloud(ctx context.Context, ip net.IP) *model.CloudRange {
+	return f[ip.String()]
+}
+
+func TestAllJSONCloud(t *testing.T) {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	c := &Client{
+		config: &model.Cfg{},
+		log:    logger.NewSimple("test"),
+		tp:     tracer,
+		geo:    &fakeGeo{},
+		cloud: fakeCloud{
+			"130.242.1.1": {Provider: model.CloudProviderAWS, Service: "EC2", Region: "eu-north-1", Prefix: "130.242.1.0/24"},
+		},
+	}
+
+	ctx := contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: "130.242.1.1"})
+	got, err := c.Index(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &model.CloudRange{Provider: "aws", Service: "EC2", Region: "eu-north-1", Prefix: "130.242.1.0/24"}, got.Cloud)
+
+	ctx = contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: "130.242.1.2"})
+	got, err = c.Index(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, got.Cloud)
+
+	// without cloud ranges there is no cloud section
+	c.cloud = nil
+	got, err = c.Index(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, got.Cloud)
+}
@@ -0,0 +1,23 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+)
+
+// AnonymityFeeds returns the anonymity and proxy flags of an IP, e.g. feeds.Service
+type AnonymityFeeds interface {
+	LookupFeeds(ctx context.Context, ip net.IP) *model.FeedMatch
+}
+
+// lookupFeeds returns the flags of the feeds listing ip, all false without feeds
+func (c *Client) lookupFeeds(ctx context.Context, ip net.IP) *model.FeedMatch {
+	if c.feeds == nil {
+		return &model.FeedMatch{}
+	}
+	if match := c.feeds.LookupFeeds(ctx, ip); match != nil {
+		return match
+	}
+	return &model.FeedMatch{}
+}
@@ -0,0 +1,56 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/contexthandler"
+	"ip_service/pkg/model"
+	"net"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFeeds is an in-memory AnonymityFeeds keyed by IP
+type fakeFeeds map[string]*model.FeedMatch
+
+func (f fakeFeeds) LookupFeeds(ctx context.Context, ip net.IP) *model.FeedMatch {
+	return f[ip.String()]
+}
+
+func TestAllJSONFeeds(t *testing.T) {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	c := &Client{
+		config: &model.Cfg{},
+		log:    logger.NewSimple("test"),
+		tp:     tracer,
+		geo:    &fakeGeo{},
+		feeds: fakeFeeds{
+			"130.242.1.1": {IsTorExit: true, IsRelay: true, Feeds: []string{"tor", "icloud"}},
+		},
+	}
+
+	ctx := contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: "130.242.1.1"})
+	got, err := c.Index(ctx)
+	require.NoError(t, err)
+	assert.True(t, got.IsTorExit)
+	assert.False(t, got.IsVPN)
+	assert.True(t, got.IsRelay)
+	assert.Equal(t, []string{"tor", "icloud"}, got.Feeds)
+
+	ctx = contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: "130.242.1.2"})
+	got, err = c.Index(ctx)
+	require.NoError(t, err)
+	assert.False(t, got.IsTorExit)
+	assert.Nil(t, got.Feeds)
+
+	// without feeds every flag is false
+	c.feeds = nil
+	got, err = c.Index(ctx)
+	require.NoError(t, err)
+	assert.False(t, got.IsRelay)
+}
@@ -0,0 +1,68 @@
+package apiv1
+
+import (
+	"context"
+	"errors"
+	"ip_service/pkg/model"
+	"net"
+
+	"github.com/SUNET/vc/pkg/logger"
+)
+
+// GeoProvider looks up the normalized geolocation and origin AS of an IP, e.g. maxmind.Service
+type GeoProvider interface {
+	LookupGeo(ctx context.Context, ip net.IP) (*model.GeoRecord, error)
+	LookupASN(ctx context.Context, ip net.IP) (*model.ASNRecord, error)
+}
+
+// geoChain asks its providers in order and returns the first non empty record.
+// A provider that fails is logged and skipped, so later providers are fallbacks.
+type geoChain struct {
+	log       *logger.Log
+	providers []GeoProvider
+}
+
+// NewGeoChain returns a GeoProvider asking providers in order
+func NewGeoChain(log *logger.Log, providers ...GeoProvider) GeoProvider {
+	return &geoChain{log: log, providers: providers}
+}
+
+func (g *geoChain) LookupGeo(ctx context.Context, ip net.IP) (*model.GeoRecord, error) {
+	return lookupChain(ctx, g, ip, GeoProvider.LookupGeo)
+}
+
+func (g *geoChain) LookupASN(ctx context.Context, ip net.IP) (*model.ASNRecord, error) {
+	return lookupChain(ctx, g, ip, GeoProvider.LookupASN)
+}
+
+// lookupChain returns the first non empty record, else the first empty one, else the errors of all providers
+func lookupChain[R interface{ Empty() bool }](ctx context.Context, g *geoChain, ip net.IP, lookup func(GeoProvider, context.Context, net.IP) (R, error)) (R, error) {
+	var (
+		first R
+		found bool
+		errs  []error
+	)
+
+	for _, provider := range g.providers {
+		record, err := lookup(provider, ctx, ip)
+		if err != nil {
+			g.log.Debug("geo provider failed, trying the next", "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		if !record.Empty() {
+			return record, nil
+		}
+		if !found {
+			first, found = record, true
+		}
+	}
+
+	if !found && len(errs) == 0 {
+		return first, errors.New("no geo provider")
+	}
+	if !found {
+		return first, errors.Join(errs...)
+	}
+	return first, nil
+}
@@ -0,0 +1,140 @@
+package apiv1
+
+import (
+	"context"
+	"errors"
+	"ip_service/pkg/contexthandler"
+	"ip_service/pkg/model"
+	"net"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGeo is an in-memory GeoProvider keyed by IP
+type fakeGeo struct {
+	geo map[string]*model.GeoRecord
+	asn map[string]*model.ASNRecord
+	err error
+}
+
+func (f *fakeGeo) LookupGeo(ctx context.Context, ip net.IP) (*model.GeoRecord, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if record, ok := f.geo[ip.String()]; ok {
+		return record, nil
+	}
+	return &model.GeoRecord{}, nil
+}
+
+func (f *fakeGeo) LookupASN(ctx context.Context, ip net.IP) (*model.ASNRecord, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if record, ok := f.asn[ip.String()]; ok {
+		return record, nil
+	}
+	return &model.ASNRecord{}, nil
+}
+
+func TestGeoChain(t *testing.T) {
+	primary := &fakeGeo{
+		geo: map[string]*model.GeoRecord{"192.0.2.1": {CountryISO: "SE", Provider: "primary"}},
+		asn: map[string]*model.ASNRecord{"192.0.2.1": {ASN: 1653, Provider: "primary"}},
+	}
+	fallback := &fakeGeo{
+		geo: map[string]*model.GeoRecord{
+			"192.0.2.1": {CountryISO: "NO", Provider: "fallback"},
+			"192.0.2.2": {CountryISO: "FI", Provider: "fallback"},
+		},
+	}
+	broken := &fakeGeo{err: errors.New("not loaded")}
+
+	tts := []struct {
+		name         string
+		chain        GeoProvider
+		ip           string
+		wantProvider string
+		wantErr      bool
+	}{
+		{
+			name:         "first provider wins",
+			chain:        NewGeoChain(logger.NewSimple("test"), primary, fallback),
+			ip:           "192.0.2.1",
+			wantProvider: "primary",
+		},
+		{
+			name:         "empty record falls through",
+			chain:        NewGeoChain(logger.NewSimple("test"), primary, fallback),
+			ip:           "192.0.2.2",
+			wantProvider: "fallback",
+		},
+		{
+			name:         "failing provider falls through",
+			chain:        NewGeoChain(logger.NewSimple("test"), broken, fallback),
+			ip:           "192.0.2.2",
+			wantProvider: "fallback",
+		},
+		{
+			name:  "no provider has the ip",
+			chain: NewGeoChain(logger.NewSimple("test"), broken, primary),
+			ip:    "192.0.2.3",
+		},
+		{
+			name:    "all providers fail",
+			chain:   NewGeoChain(logger.NewSimple("test"), broken),
+			ip:      "192.0.2.1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.chain.LookupGeo(context.TODO(), net.ParseIP(tt.ip))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantProvider, got.Provider)
+		})
+	}
+}
+
+func TestAllJSONFakeGeo(t *testing.T) {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	c := &Client{
+		config: &model.Cfg{},
+		log:    logger.NewSimple("test"),
+		tp:     tracer,
+		geo: &fakeGeo{
+			geo: map[string]*model.GeoRecord{"130.242.1.1": {
+				City:       map[string]string{"en": "Uppsala", "de": "Upsala"},
+				Country:    map[string]string{"en": "Sweden", "de": "Schweden"},
+				CountryISO: "SE",
+				IsEU:       true,
+				Region:     map[string]string{"en": "Uppsala County"},
+				RegionCode: "C",
+			}},
+			asn: map[string]*model.ASNRecord{"130.242.1.1": {ASN: 1653, Organization: "SUNET"}},
+		},
+	}
+
+	ctx := contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: "130.242.1.1", Language: "de"})
+	got, err := c.Index(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Upsala", got.City)
+	assert.Equal(t, "Schweden", got.Country)
+	assert.Equal(t, "Uppsala County", got.Region)
+	assert.Equal(t, "C", got.RegionCode)
+	assert.Equal(t, uint(1653), got.ASN)
+	assert.Equal(t, "SUNET", got.ASNOrganization)
+	assert.Equal(t, &model.Coordinates{}, got.Coordinates)
+}
@@ -325,24 +325,45 @@ func (c *Client) Collision(ctx context.Context, indata *CollisionRequest) (*Coll
 
 // Status return status
 //
-//	@Summary		Status of the service
+//	@Summary		Readiness of the service
 //	@ID				status
-//	@Description	returns the status of the service
+//	@Description	returns the status of the maxmind, whois, lctree, store and configured dataset probes, 503 when a probe fails
 //	@Tags			ip_service
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	model.StatusReply		"Success"
-//	@Failure		400	{object}	helpers.ErrorResponse	"Bad Request"
-//	@Router			/health [get]
+//	@Failure		503	{object}	model.StatusReply		"Service Unavailable"
+//	@Router			/health/ready [get]
 func (c *Client) Status(ctx context.Context) (*model.StatusReply, error) {
 	ctx, span := c.tp.Start(ctx, "apiv1:Status")
 	defer span.End()
 
 	probes := model.StatusProbes{}
 	probes = append(probes, c.store.Status(ctx))
-	//probes = append(probes, c.max.Status(ctx))
+	probes = append(probes, c.max.Status(ctx))
+	probes = append(probes, c.whois.Status(ctx))
+	probes = append(probes, c.whois.TreeStatus(ctx))
+	for _, dataset := range c.datasets {
+		probes = append(probes, dataset.Status(ctx))
+	}
 
 	status := probes.Check("ip_service")
 
 	return status, nil
 }
+
+// Live return liveness
+//
+//	@Summary		Liveness of the service
+//	@ID				live
+//	@Description	returns ok as long as the service answers requests, without probing its dependencies
+//	@Tags			ip_service
+//	@Produce		json
+//	@Success		200	{object}	model.StatusReply	"Success"
+//	@Router			/health/live [get]
+func (c *Client) Live(ctx context.Context) (*model.StatusReply, error) {
+	_, span := c.tp.Start(ctx, "apiv1:Live")
+	defer span.End()
+
+	return model.StatusProbes{}.Check("ip_service"), nil
+}
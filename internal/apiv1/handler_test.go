@@ -2,17 +2,15 @@ package apiv1
 
 import (
 	"context"
-	"ip_service/internal/maxmind"
+	"ip_service/internal/maxmind/maxmindtest"
 	"ip_service/internal/store"
 	"ip_service/pkg/contexthandler"
 	"github.com/SUNET/vc/pkg/logger"
 	"ip_service/pkg/model"
 	"github.com/SUNET/vc/pkg/trace"
 	"path/filepath"
-	"sync"
 	"testing"
 
-	"github.com/oschwald/geoip2-golang"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,13 +21,13 @@ const (
 )
 
 func mockClient(t *testing.T) *Client {
-	dbCity, err := geoip2.Open(filepath.Join("..", "..", "testdata", "GeoLite2-city-Test.mmdb"))
-	assert.NoError(t, err)
-
-	dbASN, err := geoip2.Open(filepath.Join("..", "..", "testdata", "GeoLite2-asn-Test.mmdb"))
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
 	assert.NoError(t, err)
 
-	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	max, err := maxmindtest.New(t, tracer, logger.NewSimple("test-maxmind"), map[string]string{
+		model.MaxmindDBTypeCity: filepath.Join("..", "..", "testdata", "GeoLite2-city-Test.mmdb"),
+		model.MaxmindDBTypeASN:  filepath.Join("..", "..", "testdata", "GeoLite2-asn-Test.mmdb"),
+	})
 	assert.NoError(t, err)
 
 	log := logger.NewSimple("testing")
@@ -38,20 +36,8 @@ func mockClient(t *testing.T) *Client {
 		config: &model.Cfg{},
 		log:    log,
 		tp:     tracer,
-		max: &maxmind.Service{
-			DBCity: dbCity,
-			DBASN:  dbASN,
-			TP:     tracer,
-			Log:    logger.NewSimple("test-maxmind"),
-			DBMeta: map[string]*maxmind.DBObject{
-				model.MaxmindDBTypeCity: {
-					MU: sync.RWMutex{},
-				},
-				model.MaxmindDBTypeASN: {
-					MU: sync.RWMutex{},
-				},
-			},
-		},
+		max:    max,
+		geo:    NewGeoChain(log, max),
 		store: &store.Service{
 			TP: tracer,
 		},
@@ -0,0 +1,438 @@
+package apiv1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+	"strings"
+)
+
+// Dataset is a dataset loaded on a schedule besides maxmind and IRR, e.g. georange.Service
+type Dataset interface {
+	// State returns the load state of each source of the dataset
+	State(ctx context.Context) []*model.DatasetState
+	// Status is the readiness probe of the dataset
+	Status(ctx context.Context) *model.StatusProbe
+	// TriggerUpdate queues a reload of every source of the dataset
+	TriggerUpdate(ctx context.Context)
+}
+
+// AdminDatasetsReply is the reply for the AdminDatasets handler
+type AdminDatasetsReply struct {
+	Datasets []*model.DatasetState `json:"datasets"`
+}
+
+// AdminDatasets handler return the download and parse state of the datasets
+//
+//	@Summary		Dataset state
+//	@ID				adminDatasets
+//	@Description	returns the download and parse state of the maxmind, IRR and configured range, feed, list and dump datasets
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	AdminDatasetsReply		"Success"
+//	@Failure		401	{object}	helpers.ErrorResponse	"Unauthorized"
+//	@Router			/admin/datasets [get]
+func (c *Client) AdminDatasets(ctx context.Context) (*AdminDatasetsReply, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:AdminDatasets")
+	defer span.End()
+
+	reply := &AdminDatasetsReply{
+		Datasets: c.max.State(ctx),
+	}
+	reply.Datasets = append(reply.Datasets, c.whois.State(ctx)...)
+	for _, dataset := range c.datasets {
+		reply.Datasets = append(reply.Datasets, dataset.State(ctx)...)
+	}
+
+	return reply, nil
+}
+
+// AdminUpdateDatasetRequest is the request for the AdminUpdateDataset handler
+type AdminUpdateDatasetRequest struct {
+	// Name is a dataset name, or a kind to update all its sources, e.g. irr or georange
+	Name string `uri:"name" validate:"required"`
+	// Force downloads a maxmind database even if the remote version is unchanged
+	Force bool `query:"force"`
+}
+
+// AdminUpdateDataset handler triggers an update of a dataset
+//
+//	@Summary		Trigger dataset update
+//	@ID				adminUpdateDataset
+//	@Description	queues an update of a maxmind database (ASN, City), the RPSL sources (irr or a source name) or another dataset (its kind or a source name)
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200		{object}	AdminDatasetsReply		"Success"
+//	@Failure		400		{object}	helpers.ErrorResponse	"Bad Request"
+//	@Failure		401		{object}	helpers.ErrorResponse	"Unauthorized"
+//	@Param			name	path		string					true	"dataset"
+//	@Param			force	query		bool					false	"force download"
+//	@Router			/admin/datasets/{name}/update [post]
+func (c *Client) AdminUpdateDataset(ctx context.Context, indata *AdminUpdateDatasetRequest) (*AdminDatasetsReply, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:AdminUpdateDataset")
+	defer span.End()
+
+	if err := helpers.Check(indata); err != nil {
+		return nil, err
+	}
+
+	kind := ""
+	if indata.Name == model.DatasetKindIRR {
+		kind = model.DatasetKindIRR
+	}
+	datasets, _ := c.AdminDatasets(ctx)
+	for _, dataset := range datasets.Datasets {
+		if dataset.Name == indata.Name {
+			kind = dataset.Kind
+		}
+	}
+
+	var err error
+	switch kind {
+	case model.DatasetKindMaxmind:
+		err = c.max.TriggerUpdate(ctx, indata.Name, indata.Force)
+	case model.DatasetKindIRR:
+		// the RPSL sources are merged into one tree, so they are always updated together
+		err = c.whois.TriggerUpdate(ctx)
+	default:
+		err = helpers.ErrUnknownDataset
+		if dataset := c.dataset(ctx, indata.Name); dataset != nil {
+			// the sources of a dataset are loaded together, so they are always updated together
+			dataset.TriggerUpdate(ctx)
+			err = nil
+		}
+	}
+	if err != nil {
+		c.log.Error(err, "failed to trigger update", "dataset", indata.Name)
+		return nil, err
+	}
+
+	return c.AdminDatasets(ctx)
+}
+
+// dataset returns the dataset of kind name or with a source named name, nil if there is none
+func (c *Client) dataset(ctx context.Context, name string) Dataset {
+	for _, dataset := range c.datasets {
+		for _, state := range dataset.State(ctx) {
+			if state.Kind == name || state.Name == name {
+				return dataset
+			}
+		}
+	}
+	return nil
+}
+
+// AdminRollbackDatasetRequest is the request for the AdminRollbackDataset handler
+type AdminRollbackDatasetRequest struct {
+	// Name is a maxmind dataset name
+	Name string `uri:"name" validate:"required"`
+}
+
+// AdminRollbackDataset handler replaces a maxmind database with the newest kept previous version
+//
+//	@Summary		Roll back dataset
+//	@ID				adminRollbackDataset
+//	@Description	replaces a downloaded maxmind database (ASN, City) with the newest kept previous version
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200		{object}	AdminDatasetsReply		"Success"
+//	@Failure		400		{object}	helpers.ErrorResponse	"Bad Request"
+//	@Failure		401		{object}	helpers.ErrorResponse	"Unauthorized"
+//	@Param			name	path		string					true	"dataset"
+//	@Router			/admin/datasets/{name}/rollback [post]
+func (c *Client) AdminRollbackDataset(ctx context.Context, indata *AdminRollbackDatasetRequest) (*AdminDatasetsReply, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:AdminRollbackDataset")
+	defer span.End()
+
+	if err := helpers.Check(indata); err != nil {
+		return nil, err
+	}
+
+	if err := c.max.Rollback(ctx, indata.Name); err != nil {
+		c.log.Error(err, "failed to roll back", "dataset", indata.Name)
+		return nil, err
+	}
+
+	return c.AdminDatasets(ctx)
+}
+
+// Exporter writes the loaded datasets as one MaxMind DB file, e.g. mmdbexport.Service
+type Exporter interface {
+	Export(ctx context.Context, w io.Writer) error
+}
+
+// FileReply is a file, sent as is whatever content type the request accepts
+type FileReply struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// AdminExportMMDB handler returns the loaded datasets as a MaxMind DB file
+//
+//	@Summary		Export mmdb
+//	@ID				adminExportMMDB
+//	@Description	returns a MaxMind DB file of the maxmind City and ASN records, IRR origins and prefix labels of every network
+//	@Tags			admin
+//	@Produce		application/octet-stream
+//	@Success		200	{file}		file					"Success"
+//	@Failure		400	{object}	helpers.ErrorResponse	"Bad Request"
+//	@Failure		401	{object}	helpers.ErrorResponse	"Unauthorized"
+//	@Router			/admin/export/mmdb [get]
+func (c *Client) AdminExportMMDB(ctx context.Context) (*FileReply, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:AdminExportMMDB")
+	defer span.End()
+
+	if c.exporter == nil {
+		return nil, errors.New("mmdb export not available")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := c.exporter.Export(ctx, buf); err != nil {
+		c.log.Error(err, "failed to export mmdb")
+		return nil, err
+	}
+
+	return &FileReply{
+		Name:        "ip_service.mmdb",
+		ContentType: "application/octet-stream",
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// AdminStoreKeyRequest is the request for the AdminGetStoreKey and AdminSetStoreKey handlers
+type AdminStoreKeyRequest struct {
+	Key   string `uri:"*"`
+	Value string `json:"value"`
+}
+
+// AdminStoreKeyReply is the reply for the AdminGetStoreKey and AdminSetStoreKey handlers
+type AdminStoreKeyReply struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// AdminGetStoreKey handler return the value of a store key
+//
+//	@Summary		Get store key
+//	@ID				adminGetStoreKey
+//	@Description	returns the value of a store key, an unset key has an empty value
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	AdminStoreKeyReply		"Success"
+//	@Failure		400	{object}	helpers.ErrorResponse	"Bad Request"
+//	@Failure		401	{object}	helpers.ErrorResponse	"Unauthorized"
+//	@Param			key	path		string					true	"store key"
+//	@Router			/admin/store/{key} [get]
+func (c *Client) AdminGetStoreKey(ctx context.Context, indata *AdminStoreKeyRequest) (*AdminStoreKeyReply, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:AdminGetStoreKey")
+	defer span.End()
+
+	if !validStoreKey(indata.Key) {
+		return nil, helpers.ErrInvalidStoreKey
+	}
+
+	return &AdminStoreKeyReply{
+		Key:   indata.Key,
+		Value: c.store.KV.Get(ctx, indata.Key),
+	}, nil
+}
+
+// AdminSetStoreKey handler sets the value of a store key
+//
+//	@Summary		Set store key
+//	@ID				adminSetStoreKey
+//	@Description	sets the value of a store key
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	AdminStoreKeyReply		"Success"
+//	@Failure		400	{object}	helpers.ErrorResponse	"Bad Request"
+//	@Failure		401	{object}	helpers.ErrorResponse	"Unauthorized"
+//	@Param			key	path		string					true	"store key"
+//	@Param			req	body		AdminStoreKeyRequest	true	"value"
+//	@Router			/admin/store/{key} [put]
+func (c *Client) AdminSetStoreKey(ctx context.Context, indata *AdminStoreKeyRequest) (*AdminStoreKeyReply, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:AdminSetStoreKey")
+	defer span.End()
+
+	if !validStoreKey(indata.Key) {
+		return nil, helpers.ErrInvalidStoreKey
+	}
+
+	if err := c.store.KV.Set(ctx, indata.Key, indata.Value); err != nil {
+		c.log.Error(err, "failed to set store key", "key", indata.Key)
+		return nil, err
+	}
+
+	return &AdminStoreKeyReply{
+		Key:   indata.Key,
+		Value: indata.Value,
+	}, nil
+}
+
+// validStoreKey reports whether k is a slash separated key without empty or relative segments
+func validStoreKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for segment := range strings.SplitSeq(k, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// AdminAPIKeysReply is the reply for the AdminListAPIKeys handler
+type AdminAPIKeysReply struct {
+	Keys []*model.APIKey `json:"keys"`
+}
+
+// AdminListAPIKeys handler returns the api keys in the store
+//
+//	@Summary		List api keys
+//	@ID				adminListAPIKeys
+//	@Description	returns the name, tier and sha256 hash of the api keys in the store, the keys of the config are not listed
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	AdminAPIKeysReply		"Success"
+//	@Failure		401	{object}	helpers.ErrorResponse	"Unauthorized"
+//	@Router			/admin/api_keys [get]
+func (c *Client) AdminListAPIKeys(ctx context.Context) (*AdminAPIKeysReply, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:AdminListAPIKeys")
+	defer span.End()
+
+	keys, err := c.store.KV.ListAPIKeys(ctx)
+	if err != nil {
+		c.log.Error(err, "failed to list api keys")
+		return nil, err
+	}
+
+	return &AdminAPIKeysReply{Keys: keys}, nil
+}
+
+// AdminAddAPIKeyRequest is the request for the AdminAddAPIKey handler
+type AdminAddAPIKeyRequest struct {
+	Name string `json:"name" validate:"required"`
+	Key  string `json:"key" validate:"required,min=16"`
+	Tier string `json:"tier" validate:"required"`
+}
+
+// AdminAddAPIKey handler adds an api key to the store
+//
+//	@Summary		Add api key
+//	@ID				adminAddAPIKey
+//	@Description	adds an api key to a configured tier, only the sha256 hash of the key is stored
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	model.APIKey			"Success"
+//	@Failure		400	{object}	helpers.ErrorResponse	"Bad Request"
+//	@Failure		401	{object}	helpers.ErrorResponse	"Unauthorized"
+//	@Param			req	body		AdminAddAPIKeyRequest	true	"api key"
+//	@Router			/admin/api_keys [post]
+func (c *Client) AdminAddAPIKey(ctx context.Context, indata *AdminAddAPIKeyRequest) (*model.APIKey, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:AdminAddAPIKey")
+	defer span.End()
+
+	if err := helpers.Check(indata); err != nil {
+		return nil, err
+	}
+
+	if _, ok := c.config.IPService.APIServer.APIKeys.Tiers[indata.Tier]; !ok {
+		return nil, helpers.ErrUnknownAPITier
+	}
+
+	apiKey := &model.APIKey{Name: indata.Name, Key: indata.Key, Tier: indata.Tier}
+	if err := c.store.KV.AddAPIKey(ctx, apiKey); err != nil {
+		c.log.Error(err, "failed to add api key", "name", indata.Name)
+		return nil, err
+	}
+	c.log.Info("API key added", "name", apiKey.Name, "tier", apiKey.Tier)
+
+	return apiKey, nil
+}
+
+// AdminDelAPIKeyRequest is the request for the AdminDelAPIKey handler
+type AdminDelAPIKeyRequest struct {
+	Hash string `uri:"hash" validate:"required,hexadecimal,len=64"`
+}
+
+// AdminDelAPIKey handler revokes an api key in the store
+//
+//	@Summary		Revoke api key
+//	@ID				adminDelAPIKey
+//	@Description	removes an api key from the store by the sha256 hash of the key, it is refused from the next request
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200		{object}	AdminAPIKeysReply		"Success"
+//	@Failure		400		{object}	helpers.ErrorResponse	"Bad Request"
+//	@Failure		401		{object}	helpers.ErrorResponse	"Unauthorized"
+//	@Param			hash	path		string					true	"sha256 hash of the key"
+//	@Router			/admin/api_keys/{hash} [delete]
+func (c *Client) AdminDelAPIKey(ctx context.Context, indata *AdminDelAPIKeyRequest) (*AdminAPIKeysReply, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:AdminDelAPIKey")
+	defer span.End()
+
+	if err := helpers.Check(indata); err != nil {
+		return nil, err
+	}
+
+	if err := c.store.KV.DelAPIKey(ctx, indata.Hash); err != nil {
+		c.log.Error(err, "failed to revoke api key", "hash", indata.Hash)
+		return nil, err
+	}
+	c.log.Info("API key revoked", "hash", indata.Hash)
+
+	return c.AdminListAPIKeys(ctx)
+}
+
+// AdminLogLevelRequest is the request for the AdminLogLevel handler
+type AdminLogLevelRequest struct {
+	Level string `json:"level" validate:"required,oneof=trace debug info warn error"`
+}
+
+// AdminLogLevelReply is the reply for the AdminLogLevel handler
+type AdminLogLevelReply struct {
+	Level string `json:"level"`
+}
+
+// AdminLogLevel handler changes the log level until the next restart or config reload
+//
+//	@Summary		Change log level
+//	@ID				adminLogLevel
+//	@Description	changes the log level until the next restart or config reload
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	AdminLogLevelReply		"Success"
+//	@Failure		400	{object}	helpers.ErrorResponse	"Bad Request"
+//	@Failure		401	{object}	helpers.ErrorResponse	"Unauthorized"
+//	@Param			req	body		AdminLogLevelRequest	true	"level"
+//	@Router			/admin/log/level [put]
+func (c *Client) AdminLogLevel(ctx context.Context, indata *AdminLogLevelRequest) (*AdminLogLevelReply, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:AdminLogLevel")
+	defer span.End()
+
+	if err := helpers.Check(indata); err != nil {
+		return nil, err
+	}
+
+	if c.setLogLevel == nil {
+		return nil, helpers.ErrNotValidEndpoint
+	}
+
+	c.setLogLevel(indata.Level)
+	c.log.Info("Log level changed", "level", indata.Level)
+
+	return &AdminLogLevelReply{Level: indata.Level}, nil
+}
+
+// OnLogLevel registers the function that applies a log level changed with AdminLogLevel
+func (c *Client) OnLogLevel(fn func(level string)) {
+	c.setLogLevel = fn
+}
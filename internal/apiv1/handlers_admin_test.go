@@ -0,0 +1,111 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidStoreKey(t *testing.T) {
+	tts := []struct {
+		have string
+		want bool
+	}{
+		{have: "started", want: true},
+		{have: "ASN/remote_version", want: true},
+		{have: "", want: false},
+		{have: "/started", want: false},
+		{have: "ASN//remote_version", want: false},
+		{have: "../etc/passwd", want: false},
+		{have: "ASN/./remote_version", want: false},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.have, func(t *testing.T) {
+			assert.Equal(t, tt.want, validStoreKey(tt.have))
+		})
+	}
+}
+
+// fakeDataset is a dataset of kind with a source for each name
+type fakeDataset struct {
+	kind     string
+	names    []string
+	triggers int
+}
+
+func (f *fakeDataset) State(ctx context.Context) []*model.DatasetState {
+	states := []*model.DatasetState{}
+	for _, name := range f.names {
+		states = append(states, &model.DatasetState{Name: name, Kind: f.kind})
+	}
+	return states
+}
+
+func (f *fakeDataset) Status(ctx context.Context) *model.StatusProbe {
+	return &model.StatusProbe{Name: f.kind, Healthy: true}
+}
+
+func (f *fakeDataset) TriggerUpdate(ctx context.Context) {
+	f.triggers++
+}
+
+func TestDataset(t *testing.T) {
+	geoRange := &fakeDataset{kind: model.DatasetKindGeoRange, names: []string{"local", "remote"}}
+	feeds := &fakeDataset{kind: model.DatasetKindFeeds, names: []string{"tor"}}
+	c := &Client{datasets: []Dataset{geoRange, feeds}}
+
+	tts := []struct {
+		name string
+		want Dataset
+	}{
+		{name: "georange", want: geoRange},
+		{name: "remote", want: geoRange},
+		{name: "tor", want: feeds},
+		{name: "ASN", want: nil},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, c.dataset(context.TODO(), tt.name))
+		})
+	}
+}
+
+func TestAdminAPIKeys(t *testing.T) {
+	c := mockIRRClient(t)
+	c.config.IPService.APIServer.APIKeys.Tiers = map[string]model.APITier{"basic": {}}
+
+	added, err := c.AdminAddAPIKey(t.Context(), &AdminAddAPIKeyRequest{Name: "customer", Key: "a-long-random-secret", Tier: "basic"})
+	require.NoError(t, err)
+	assert.Equal(t, model.HashAPIKey("a-long-random-secret"), added.Hash)
+
+	_, err = c.AdminAddAPIKey(t.Context(), &AdminAddAPIKeyRequest{Name: "other", Key: "another-random-secret", Tier: "missing"})
+	assert.ErrorIs(t, err, helpers.ErrUnknownAPITier)
+
+	_, err = c.AdminAddAPIKey(t.Context(), &AdminAddAPIKeyRequest{Name: "short", Key: "short", Tier: "basic"})
+	assert.Error(t, err)
+
+	got, err := c.store.KV.GetAPIKey(t.Context(), "a-long-random-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "customer", got.Name)
+
+	list, err := c.AdminListAPIKeys(t.Context())
+	require.NoError(t, err)
+	require.Len(t, list.Keys, 1)
+	assert.Equal(t, "customer", list.Keys[0].Name)
+
+	list, err = c.AdminDelAPIKey(t.Context(), &AdminDelAPIKeyRequest{Hash: added.Hash})
+	require.NoError(t, err)
+	assert.Empty(t, list.Keys)
+
+	_, err = c.store.KV.GetAPIKey(t.Context(), "a-long-random-secret")
+	assert.ErrorIs(t, err, helpers.ErrAPIKeyNotFound)
+
+	_, err = c.AdminDelAPIKey(t.Context(), &AdminDelAPIKeyRequest{Hash: added.Hash})
+	assert.ErrorIs(t, err, helpers.ErrAPIKeyNotFound)
+}
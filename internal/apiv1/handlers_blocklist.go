@@ -0,0 +1,80 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// Blocklists returns the blocklists holding an IP, e.g. blocklist.Service
+type Blocklists interface {
+	LookupBlocklists(ctx context.Context, ip net.IP) []model.BlocklistMatch
+	Lists() []string
+}
+
+type BlocklistRequest struct {
+	IP string `uri:"ip"`
+}
+
+// Blocklist handler return the blocklists listing an IP
+//
+//	@Summary		Blocklist membership
+//	@ID				blocklist
+//	@Description	takes path parameter ip and returns the blocklists listing it, with the listed prefix, listing reference and list age
+//	@Tags			ip_service
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	model.ReplyBlocklist	"Success"
+//	@Failure		400	{object}	helpers.ErrorResponse	"Bad Request"
+//	@Param			ip	path		string					true	"ip"
+//	@Router			/blocklist/{ip} [get]
+func (c *Client) Blocklist(ctx context.Context, indata *BlocklistRequest) (*model.ReplyBlocklist, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:Blocklist")
+	defer span.End()
+
+	if c.blocklists == nil {
+		return nil, helpers.ErrNoBlocklists
+	}
+
+	ip, err := netip.ParseAddr(indata.IP)
+	if err != nil {
+		c.log.Error(err, "failed to parse ip", "ip", indata.IP)
+		return nil, err
+	}
+
+	reply := &model.ReplyBlocklist{
+		IP:      ip.Unmap().String(),
+		Matches: c.blocklists.LookupBlocklists(ctx, net.IP(ip.AsSlice())),
+		Lists:   c.blocklists.Lists(),
+	}
+	if reply.Matches == nil {
+		reply.Matches = []model.BlocklistMatch{}
+	}
+	reply.Listed = len(reply.Matches) > 0
+
+	return reply, nil
+}
+
+// lookupBlocklists returns the blocklists listing ip, nil without blocklists
+func (c *Client) lookupBlocklists(ctx context.Context, ip net.IP) []model.BlocklistMatch {
+	if c.blocklists == nil {
+		return nil
+	}
+	return c.blocklists.LookupBlocklists(ctx, ip)
+}
+
+// listAges returns a copy of matches with the age of each list as of now
+func listAges(matches []model.BlocklistMatch, now time.Time) []model.BlocklistMatch {
+	if matches == nil {
+		return nil
+	}
+	reply := make([]model.BlocklistMatch, len(matches))
+	for i, match := range matches {
+		match.ListAge = int64(now.Sub(match.ListUpdated).Seconds())
+		reply[i] = match
+	}
+	return reply
+}
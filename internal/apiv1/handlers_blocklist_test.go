@@ -0,0 +1,70 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBlocklists is an in-memory Blocklists keyed by IP
+type fakeBlocklists map[string][]model.BlocklistMatch
+
+func (f fakeBlocklists) LookupBlocklists(ctx context.Context, ip net.IP) []model.BlocklistMatch {
+	return f[ip.String()]
+}
+
+func (f fakeBlocklists) Lists() []string {
+	return []string{"drop", "internal"}
+}
+
+func TestBlocklist(t *testing.T) {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	c := &Client{
+		config: &model.Cfg{},
+		log:    logger.NewSimple("test"),
+		tp:     tracer,
+		blocklists: fakeBlocklists{
+			"1.10.16.1": {{List: "drop", Prefix: "1.10.16.0/20", Reference: "SBL256894"}},
+		},
+	}
+
+	got, err := c.Blocklist(context.TODO(), &BlocklistRequest{IP: "::ffff:1.10.16.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "1.10.16.1", got.IP)
+	assert.True(t, got.Listed)
+	assert.Equal(t, []model.BlocklistMatch{{List: "drop", Prefix: "1.10.16.0/20", Reference: "SBL256894"}}, got.Matches)
+	assert.Equal(t, []string{"drop", "internal"}, got.Lists)
+
+	got, err = c.Blocklist(context.TODO(), &BlocklistRequest{IP: "130.242.1.1"})
+	require.NoError(t, err)
+	assert.False(t, got.Listed)
+	assert.Equal(t, []model.BlocklistMatch{}, got.Matches)
+
+	_, err = c.Blocklist(context.TODO(), &BlocklistRequest{IP: "not-an-ip"})
+	assert.Error(t, err)
+
+	c.blocklists = nil
+	_, err = c.Blocklist(context.TODO(), &BlocklistRequest{IP: "1.10.16.1"})
+	assert.ErrorIs(t, err, helpers.ErrNoBlocklists)
+}
+
+func TestListAges(t *testing.T) {
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cached := []model.BlocklistMatch{{List: "drop", Prefix: "1.10.16.0/20", ListUpdated: updated, ListAge: 60}}
+
+	got := listAges(cached, updated.Add(time.Hour))
+	assert.Equal(t, int64(3600), got[0].ListAge)
+	assert.Equal(t, int64(60), cached[0].ListAge, "the cached matches are not changed")
+
+	assert.Nil(t, listAges(nil, updated))
+}
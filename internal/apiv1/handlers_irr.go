@@ -0,0 +1,170 @@
+package apiv1
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"ip_service/pkg/model"
+	"ip_service/pkg/rpsl"
+	"maps"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// IRRChangesRequest is the request for the IRRChanges handler
+type IRRChangesRequest struct {
+	// Since is a RFC3339 timestamp, defaults to 24 hours ago
+	Since string `query:"since"`
+	// Prefix limits the reply to route objects overlapping the prefix
+	Prefix string `query:"prefix"`
+}
+
+// IRRChangesReply is the reply for the IRRChanges handler
+type IRRChangesReply struct {
+	Since      time.Time             `json:"since"`
+	ChangeSets []*model.IRRChangeSet `json:"change_sets"`
+}
+
+// IRRChanges handler return the IRR route object changes since the given time
+//
+//	@Summary		IRR route object changes
+//	@ID				irrChanges
+//	@Description	returns added, removed and modified route objects per source since the given time, as JSON or Atom
+//	@Tags			ip_service
+//	@Accept			json
+//	@Produce		json
+//	@Produce		application/atom+xml
+//	@Success		200		{object}	IRRChangesReply			"Success"
+//	@Failure		400		{object}	helpers.ErrorResponse	"Bad Request"
+//	@Param			since	query		string					false	"RFC3339 timestamp"
+//	@Param			prefix	query		string					false	"prefix"
+//	@Router			/irr/changes [get]
+func (c *Client) IRRChanges(ctx context.Context, indata *IRRChangesRequest) (*IRRChangesReply, error) {
+	ctx, span := c.tp.Start(ctx, "apiv1:IRRChanges")
+	defer span.End()
+
+	reply := &IRRChangesReply{
+		Since: time.Now().Add(-24 * time.Hour),
+	}
+
+	if indata.Since != "" {
+		since, err := time.Parse(time.RFC3339, indata.Since)
+		if err != nil {
+			c.log.Error(err, "failed to parse since", "since", indata.Since)
+			return nil, err
+		}
+		reply.Since = since
+	}
+
+	changeSets, err := c.store.KV.GetIRRChangeSets(ctx, reply.Since)
+	if err != nil {
+		c.log.Error(err, "failed to get IRR change sets")
+		return nil, err
+	}
+
+	if indata.Prefix != "" {
+		prefix, err := netip.ParsePrefix(indata.Prefix)
+		if err != nil {
+			c.log.Error(err, "failed to parse prefix", "prefix", indata.Prefix)
+			return nil, err
+		}
+		for _, changeSet := range changeSets {
+			for source, changes := range changeSet.Sources {
+				changeSet.Sources[source] = &rpsl.Changes{
+					Added:    filterOverlapping(changes.Added, prefix),
+					Removed:  filterOverlapping(changes.Removed, prefix),
+					Modified: filterOverlapping(changes.Modified, prefix),
+				}
+				if changeSet.Sources[source].IsEmpty() {
+					delete(changeSet.Sources, source)
+				}
+			}
+		}
+	}
+
+	reply.ChangeSets = changeSets
+
+	return reply, nil
+}
+
+func filterOverlapping(objects []*rpsl.Object, prefix netip.Prefix) []*rpsl.Object {
+	var reply []*rpsl.Object
+	for _, obj := range objects {
+		network, err := netip.ParsePrefix(obj.Network)
+		if err != nil {
+			continue
+		}
+		if network.Overlaps(prefix) {
+			reply = append(reply, obj)
+		}
+	}
+	return reply
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Atom returns the reply as an Atom feed, one entry per changed route object
+func (r *IRRChangesReply) Atom() ([]byte, error) {
+	feed := atomFeed{
+		ID:      "urn:ip_service:irr:changes",
+		Title:   "ip_service IRR changes",
+		Updated: r.Since.UTC().Format(time.RFC3339),
+	}
+
+	for _, changeSet := range r.ChangeSets {
+		updated := changeSet.Timestamp.UTC().Format(time.RFC3339)
+		feed.Updated = updated
+
+		for _, source := range slices.Sorted(maps.Keys(changeSet.Sources)) {
+			changes := changeSet.Sources[source]
+			for _, kind := range []struct {
+				name    string
+				objects []*rpsl.Object
+			}{
+				{name: "added", objects: changes.Added},
+				{name: "removed", objects: changes.Removed},
+				{name: "modified", objects: changes.Modified},
+			} {
+				for _, obj := range kind.objects {
+					body, err := json.Marshal(obj)
+					if err != nil {
+						return nil, err
+					}
+					feed.Entries = append(feed.Entries, atomEntry{
+						ID:      fmt.Sprintf("urn:ip_service:irr:%d:%s:%s:%s:%s", changeSet.Timestamp.UnixNano(), source, kind.name, obj.Network, obj.Origin),
+						Title:   fmt.Sprintf("%s %s %s (%s, serial %s)", kind.name, obj.Network, obj.Origin, source, changeSet.Serials[source]),
+						Updated: updated,
+						Content: atomContent{Type: "text", Body: string(body)},
+					})
+				}
+			}
+		}
+	}
+
+	b, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), b...), nil
+}
@@ -0,0 +1,144 @@
+package apiv1
+
+import (
+	"encoding/xml"
+	"ip_service/internal/store"
+	"ip_service/pkg/model"
+	"ip_service/pkg/rpsl"
+	"testing"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockIRRClient(t *testing.T, changeSets ...*model.IRRChangeSet) *Client {
+	t.Helper()
+
+	tracer, err := trace.NewForTesting(t.Context(), "test", logger.NewSimple("test"))
+	assert.NoError(t, err)
+
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			Store: model.Store{File: model.FileStorage{Path: t.TempDir()}},
+		},
+	}
+
+	st, err := store.New(t.Context(), cfg, tracer, logger.NewSimple("test-store"))
+	assert.NoError(t, err)
+
+	for _, changeSet := range changeSets {
+		assert.NoError(t, st.KV.AddIRRChangeSet(t.Context(), changeSet))
+	}
+
+	return &Client{
+		config: cfg,
+		log:    logger.NewSimple("testing"),
+		tp:     tracer,
+		store:  st,
+	}
+}
+
+func TestIRRChanges(t *testing.T) {
+	now := time.Now()
+	changeSet := &model.IRRChangeSet{
+		Timestamp: now.Add(-time.Hour),
+		Serials:   map[string]string{"ripe": "42", "radb": "7"},
+		Sources: map[string]*rpsl.Changes{
+			"ripe": {
+				Added:   []*rpsl.Object{{Network: "192.0.2.0/24", Origin: "AS64500", Source: "RIPE"}},
+				Removed: []*rpsl.Object{{Network: "198.51.100.0/24", Origin: "AS64501", Source: "RIPE"}},
+			},
+			"radb": {
+				Modified: []*rpsl.Object{{Network: "2001:db8::/32", Origin: "AS64502", Source: "RADB"}},
+			},
+		},
+	}
+	oldChangeSet := &model.IRRChangeSet{
+		Timestamp: now.Add(-72 * time.Hour),
+		Serials:   map[string]string{"ripe": "40"},
+		Sources:   map[string]*rpsl.Changes{},
+	}
+
+	tts := []struct {
+		name        string
+		request     *IRRChangesRequest
+		wantSets    int
+		wantSources []string
+		wantErr     bool
+	}{
+		{
+			name:        "default since",
+			request:     &IRRChangesRequest{},
+			wantSets:    1,
+			wantSources: []string{"ripe", "radb"},
+		},
+		{
+			name:        "explicit since",
+			request:     &IRRChangesRequest{Since: now.Add(-96 * time.Hour).Format(time.RFC3339)},
+			wantSets:    2,
+			wantSources: []string{"ripe", "radb"},
+		},
+		{
+			name:        "prefix filter",
+			request:     &IRRChangesRequest{Prefix: "192.0.0.0/16"},
+			wantSets:    1,
+			wantSources: []string{"ripe"},
+		},
+		{
+			name:    "invalid since",
+			request: &IRRChangesRequest{Since: "yesterday"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			client := mockIRRClient(t, changeSet, oldChangeSet)
+
+			got, err := client.IRRChanges(t.Context(), tt.request)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, got.ChangeSets, tt.wantSets)
+
+			latest := got.ChangeSets[len(got.ChangeSets)-1]
+			assert.Len(t, latest.Sources, len(tt.wantSources))
+			for _, source := range tt.wantSources {
+				assert.Contains(t, latest.Sources, source)
+			}
+		})
+	}
+}
+
+func TestIRRChangesAtom(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reply := &IRRChangesReply{
+		Since: ts.Add(-24 * time.Hour),
+		ChangeSets: []*model.IRRChangeSet{
+			{
+				Timestamp: ts,
+				Serials:   map[string]string{"ripe": "42"},
+				Sources: map[string]*rpsl.Changes{
+					"ripe": {
+						Added:   []*rpsl.Object{{Network: "192.0.2.0/24", Origin: "AS64500"}},
+						Removed: []*rpsl.Object{{Network: "198.51.100.0/24", Origin: "AS64501"}},
+					},
+				},
+			},
+		},
+	}
+
+	b, err := reply.Atom()
+	assert.NoError(t, err)
+
+	feed := atomFeed{}
+	assert.NoError(t, xml.Unmarshal(b, &feed))
+	assert.Equal(t, "2024-05-01T12:00:00Z", feed.Updated)
+	assert.Len(t, feed.Entries, 2)
+	assert.Equal(t, "added 192.0.2.0/24 AS64500 (ripe, serial 42)", feed.Entries[0].Title)
+	assert.Equal(t, "removed 198.51.100.0/24 AS64501 (ripe, serial 42)", feed.Entries[1].Title)
+}
@@ -0,0 +1,66 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+)
+
+// PrefixLabeler returns the operator defined labels of the prefixes holding an IP, e.g. prefixlabels.Service
+type PrefixLabeler interface {
+	LookupLabels(ctx context.Context, ip net.IP) *model.Labels
+}
+
+// lookupLabels returns the labels of ip and the geo and asn records with its overrides applied.
+// The records are copied before they are changed, since providers may share them between lookups.
+func (c *Client) lookupLabels(ctx context.Context, ip net.IP, geoRecord *model.GeoRecord, asnRecord *model.ASNRecord) (*model.Labels, *model.GeoRecord, *model.ASNRecord) {
+	if c.labels == nil {
+		return nil, geoRecord, asnRecord
+	}
+
+	labels := c.labels.LookupLabels(ctx, ip)
+	if labels == nil || labels.Override == nil {
+		return labels, geoRecord, asnRecord
+	}
+	o := labels.Override
+
+	geo := *geoRecord
+	if o.City != "" {
+		geo.City = map[string]string{"en": o.City}
+	}
+	if o.Country != "" {
+		geo.Country = map[string]string{"en": o.Country}
+	}
+	if o.CountryISO != "" {
+		geo.CountryISO = o.CountryISO
+		geo.IsEU = model.IsEUCountry(o.CountryISO)
+	}
+	if o.Region != "" {
+		geo.Region = map[string]string{"en": o.Region}
+	}
+	if o.RegionCode != "" {
+		geo.RegionCode = o.RegionCode
+	}
+	if o.PostalCode != "" {
+		geo.PostalCode = o.PostalCode
+	}
+	if o.Coordinates != nil {
+		geo.Coordinates = o.Coordinates
+	}
+	if o.Timezone != "" {
+		geo.Timezone = o.Timezone
+	}
+	if o.Continent != "" {
+		geo.Continent = map[string]string{"en": o.Continent}
+	}
+
+	asn := *asnRecord
+	if o.ASN != 0 {
+		asn.ASN = o.ASN
+	}
+	if o.ASNOrganization != "" {
+		asn.Organization = o.ASNOrganization
+	}
+
+	return labels, &geo, &asn
+}
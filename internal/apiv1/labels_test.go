@@ -0,0 +1,81 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/contexthandler"
+	"ip_service/pkg/model"
+	"net"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLabeler is an in-memory PrefixLabeler keyed by IP
+type fakeLabeler map[string]*model.Labels
+
+func (f fakeLabeler) LookupLabels(ctx context.Context, ip net.IP) *model.Labels {
+	return f[ip.String()]
+}
+
+func TestAllJSONLabels(t *testing.T) {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	geoRecord := &model.GeoRecord{
+		City:       map[string]string{"en": "Uppsala", "de": "Upsala"},
+		Country:    map[string]string{"en": "Sweden", "de": "Schweden"},
+		CountryISO: "SE",
+		IsEU:       true,
+	}
+	asnRecord := &model.ASNRecord{ASN: 1653, Organization: "SUNET"}
+
+	c := &Client{
+		config: &model.Cfg{},
+		log:    logger.NewSimple("test"),
+		tp:     tracer,
+		geo: &fakeGeo{
+			geo: map[string]*model.GeoRecord{"130.242.1.1": geoRecord, "130.242.1.2": geoRecord},
+			asn: map[string]*model.ASNRecord{"130.242.1.1": asnRecord, "130.242.1.2": asnRecord},
+		},
+		labels: fakeLabeler{
+			"130.242.1.1": {
+				Prefixes:     []string{"130.242.1.0/31"},
+				Organization: "Example University",
+				Labels:       map[string]string{"network": "eduroam"},
+				Override: &model.LabelOverride{
+					City:            "Stockholm",
+					CountryISO:      "NO",
+					ASNOrganization: "Example University",
+					Coordinates:     &model.Coordinates{Latitude: 59.33, Longitude: 18.07},
+				},
+			},
+		},
+	}
+
+	ctx := contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: "130.242.1.1", Language: "de"})
+	got, err := c.Index(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Stockholm", got.City)
+	assert.Equal(t, "Schweden", got.Country)
+	assert.Equal(t, "NO", got.CountryISO)
+	assert.False(t, got.IsEU)
+	assert.Equal(t, uint(1653), got.ASN)
+	assert.Equal(t, "Example University", got.ASNOrganization)
+	assert.Equal(t, &model.Coordinates{Latitude: 59.33, Longitude: 18.07}, got.Coordinates)
+	require.NotNil(t, got.Labels)
+	assert.Equal(t, map[string]string{"network": "eduroam"}, got.Labels.Labels)
+
+	// the provider records are not changed by the overrides
+	assert.Equal(t, "SE", geoRecord.CountryISO)
+	assert.Equal(t, "SUNET", asnRecord.Organization)
+
+	ctx = contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: "130.242.1.2", Language: "de"})
+	got, err = c.Index(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Upsala", got.City)
+	assert.Nil(t, got.Labels)
+}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"ip_service/pkg/contexthandler"
 	"ip_service/pkg/model"
+	"ip_service/pkg/specialpurpose"
 	"math/big"
 	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
 
 	ua "github.com/mileusna/useragent"
 	"inet.af/netaddr"
@@ -30,6 +35,44 @@ func (c *Client) getUserAgent(ctx context.Context) (string, error) {
 	return requestContext.UserAgent, nil
 }
 
+// languages are the locales of the maxmind name fields
+var languages = []string{"de", "en", "es", "fr", "ja", "pt-BR", "ru", "zh-CN"}
+
+// getLanguage returns the maxmind locale matching the requested language, defaults to en
+func (c *Client) getLanguage(ctx context.Context) string {
+	requestContext, err := contexthandler.Get(ctx, "request")
+	if err != nil {
+		return "en"
+	}
+	return matchLanguage(requestContext.Language)
+}
+
+func matchLanguage(tag string) string {
+	for _, language := range languages {
+		if strings.EqualFold(language, tag) {
+			return language
+		}
+	}
+
+	primary, _, _ := strings.Cut(tag, "-")
+	for _, language := range languages {
+		languagePrimary, _, _ := strings.Cut(language, "-")
+		if strings.EqualFold(languagePrimary, primary) {
+			return language
+		}
+	}
+
+	return "en"
+}
+
+// localizedName returns the name in language, falling back to English
+func localizedName(names map[string]string, language string) string {
+	if name, ok := names[language]; ok {
+		return name
+	}
+	return names["en"]
+}
+
 func (c *Client) ua(ctx context.Context) (ua.UserAgent, error) {
 	userAgent, err := c.getUserAgent(ctx)
 	if err != nil {
@@ -66,12 +109,12 @@ func (c *Client) asn(ctx context.Context) (uint, error) {
 		return 0, err
 	}
 
-	m, err := c.max.ASN(ctx, net.ParseIP(ip))
+	m, err := c.lookupASN(ctx, net.ParseIP(ip))
 	if err != nil {
 		c.log.Error(err, "failed to get ASN")
 		return 0, err
 	}
-	return m.AutonomousSystemNumber, nil
+	return m.ASN, nil
 }
 
 func (c *Client) asnOrganization(ctx context.Context) (string, error) {
@@ -79,11 +122,11 @@ func (c *Client) asnOrganization(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	m, err := c.max.ASN(ctx, net.ParseIP(ip))
+	m, err := c.lookupASN(ctx, net.ParseIP(ip))
 	if err != nil {
 		return "", nil
 	}
-	return m.AutonomousSystemOrganization, nil
+	return m.Organization, nil
 }
 
 func (c *Client) postal(ctx context.Context) (string, error) {
@@ -92,12 +135,12 @@ func (c *Client) postal(ctx context.Context) (string, error) {
 		c.log.Error(err, "failed to get IP")
 		return "", err
 	}
-	m, err := c.max.City(ctx, net.ParseIP(ip))
+	m, err := c.lookupGeo(ctx, net.ParseIP(ip))
 	if err != nil {
 		c.log.Error(err, "failed to get City")
 		return "", nil
 	}
-	return m.Postal.Code, nil
+	return m.PostalCode, nil
 }
 
 func (c *Client) city(ctx context.Context) (string, error) {
@@ -106,13 +149,13 @@ func (c *Client) city(ctx context.Context) (string, error) {
 		c.log.Error(err, "failed to get IP")
 		return "", err
 	}
-	m, err := c.max.City(ctx, net.ParseIP(ip))
+	m, err := c.lookupGeo(ctx, net.ParseIP(ip))
 	if err != nil {
 		c.log.Error(err, "failed to get City")
 		return "", err
 	}
 
-	reply, ok := m.City.Names["en"]
+	reply, ok := m.City["en"]
 	if !ok {
 		c.log.Debug("no City name in English available")
 		return "", nil
@@ -125,15 +168,20 @@ func (c *Client) coordinates(ctx context.Context) (*model.Coordinates, error) {
 	if err != nil {
 		return nil, err
 	}
-	m, err := c.max.City(ctx, net.ParseIP(ip))
+	m, err := c.lookupGeo(ctx, net.ParseIP(ip))
 	if err != nil {
 		return nil, err
 	}
-	reply := &model.Coordinates{
-		Latitude:  m.Location.Latitude,
-		Longitude: m.Location.Longitude,
+	return coordinatesOf(m), nil
+}
+
+// coordinatesOf returns the coordinates of m, 0,0 when the provider has none
+func coordinatesOf(m *model.GeoRecord) *model.Coordinates {
+	if m.Coordinates == nil {
+		return &model.Coordinates{}
 	}
-	return reply, nil
+	coordinates := *m.Coordinates
+	return &coordinates
 }
 
 func (c *Client) country(ctx context.Context) (string, error) {
@@ -141,12 +189,12 @@ func (c *Client) country(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	m, err := c.max.City(ctx, net.ParseIP(ip))
+	m, err := c.lookupGeo(ctx, net.ParseIP(ip))
 	if err != nil {
 		return "", err
 	}
 
-	return m.Country.Names["en"], nil
+	return m.Country["en"], nil
 }
 
 func (c *Client) countryISO(ctx context.Context) (string, error) {
@@ -155,11 +203,11 @@ func (c *Client) countryISO(ctx context.Context) (string, error) {
 		c.log.Error(err, "failed to get IP")
 		return "", err
 	}
-	m, err := c.max.City(ctx, net.ParseIP(ip))
+	m, err := c.lookupGeo(ctx, net.ParseIP(ip))
 	if err != nil {
 		return "", nil
 	}
-	return m.Country.IsoCode, nil
+	return m.CountryISO, nil
 }
 
 func (c *Client) isEU(ctx context.Context) (bool, error) {
@@ -167,11 +215,11 @@ func (c *Client) isEU(ctx context.Context) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	m, err := c.max.City(ctx, net.ParseIP(ip))
+	m, err := c.lookupGeo(ctx, net.ParseIP(ip))
 	if err != nil {
 		return false, nil
 	}
-	return m.Country.IsInEuropeanUnion, nil
+	return m.IsEU, nil
 }
 
 func (c *Client) is1918Network(ctx context.Context) (bool, error) {
@@ -179,7 +227,63 @@ func (c *Client) is1918Network(ctx context.Context) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return net.ParseIP(ip).IsPrivate(), nil
+	return is1918(specialPurpose(net.ParseIP(ip))), nil
+}
+
+// specialPurpose returns the IANA special-purpose entry holding ip, nil for an ordinary address
+func specialPurpose(ip net.IP) *model.SpecialPurpose {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil
+	}
+	return specialpurpose.Lookup(addr)
+}
+
+// is1918 reports whether special is one of the RFC 1918 private IPv4 blocks, IPv6 unique local addresses are not
+func is1918(special *model.SpecialPurpose) bool {
+	return special != nil && slices.Contains(special.RFC, "RFC1918")
+}
+
+// lookupGeo returns the geo record of ip, empty without a lookup when ip is never globally reachable
+func (c *Client) lookupGeo(ctx context.Context, ip net.IP) (*model.GeoRecord, error) {
+	if special := specialPurpose(ip); special.Unreachable() {
+		metrics.specialPurpose.WithLabelValues(special.Name).Inc()
+		return &model.GeoRecord{}, nil
+	}
+	return c.geo.LookupGeo(ctx, ip)
+}
+
+// lookupASN returns the asn record of ip, empty without a lookup when ip is never globally reachable
+func (c *Client) lookupASN(ctx context.Context, ip net.IP) (*model.ASNRecord, error) {
+	if special := specialPurpose(ip); special.Unreachable() {
+		metrics.specialPurpose.WithLabelValues(special.Name).Inc()
+		return &model.ASNRecord{}, nil
+	}
+	return c.geo.LookupASN(ctx, ip)
+}
+
+// lookupRecords returns the geo and asn records of ip, empty without lookups when special is never globally reachable
+func (c *Client) lookupRecords(ctx context.Context, ip net.IP, special *model.SpecialPurpose) (*model.GeoRecord, *model.ASNRecord, error) {
+	if special.Unreachable() {
+		metrics.specialPurpose.WithLabelValues(special.Name).Inc()
+		return &model.GeoRecord{}, &model.ASNRecord{}, nil
+	}
+
+	// Single ASN lookup instead of two separate calls
+	asnRecord, err := c.geo.LookupASN(ctx, ip)
+	if err != nil {
+		c.log.Error(err, "failed to get ASN")
+		return nil, nil, err
+	}
+
+	// Single City lookup instead of eight separate calls
+	geoRecord, err := c.geo.LookupGeo(ctx, ip)
+	if err != nil {
+		c.log.Error(err, "failed to get City")
+		return nil, nil, err
+	}
+
+	return geoRecord, asnRecord, nil
 }
 
 func (c *Client) timezone(ctx context.Context) (string, error) {
@@ -187,11 +291,11 @@ func (c *Client) timezone(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	m, err := c.max.City(ctx, net.ParseIP(ip))
+	m, err := c.lookupGeo(ctx, net.ParseIP(ip))
 	if err != nil {
 		return "", nil
 	}
-	return m.Location.TimeZone, nil
+	return m.Timezone, nil
 }
 
 func (c *Client) continent(ctx context.Context) (string, error) {
@@ -199,133 +303,176 @@ func (c *Client) continent(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	m, err := c.max.City(ctx, net.ParseIP(ip))
+	m, err := c.lookupGeo(ctx, net.ParseIP(ip))
 	if err != nil {
 		return "", nil
 	}
-	return m.Continent.Names["en"], nil
+	return m.Continent["en"], nil
+}
+
+// replyFields maps the geo and ASN records and the feeds of ip onto the fields /all and /lookup share
+func (c *Client) replyFields(ctx context.Context, ip net.IP, geoRecord *model.GeoRecord, asnRecord *model.ASNRecord, special *model.SpecialPurpose, language string) model.ReplyFields {
+	feeds := c.lookupFeeds(ctx, ip)
+
+	return model.ReplyFields{
+		ASN:             asnRecord.ASN,
+		ASNOrganization: asnRecord.Organization,
+		City:            localizedName(geoRecord.City, language),
+		Country:         localizedName(geoRecord.Country, language),
+		CountryISO:      geoRecord.CountryISO,
+		IsEU:            geoRecord.IsEU,
+		Is1918Network:   is1918(special),
+		Region:          localizedName(geoRecord.Region, language),
+		RegionCode:      geoRecord.RegionCode,
+		PostalCode:      geoRecord.PostalCode,
+		Coordinates:     coordinatesOf(geoRecord),
+		Timezone:        geoRecord.Timezone,
+		Continent:       localizedName(geoRecord.Continent, language),
+		IsTorExit:       feeds.IsTorExit,
+		IsVPN:           feeds.IsVPN,
+		IsRelay:         feeds.IsRelay,
+		Feeds:           feeds.Feeds,
+	}
 }
 
 func (c *Client) formatAllJSON(ctx context.Context) (*model.ReplyIPInformation, error) {
 	c.log.Debug("formatAllJSON start")
 
-	reply := &model.ReplyIPInformation{}
-
 	ip, err := c.getIP(ctx)
 	if err != nil {
 		c.log.Error(err, "failed to get IP")
 		return nil, err
 	}
-	reply.IP = ip
 
-	reply.IPDecimal, err = c.IPDecimal(ctx)
+	language := c.getLanguage(ctx)
+
+	cached, err := c.allCache.get(replyCacheKey{ip: ip, language: language}, func() (*model.ReplyIPInformation, error) {
+		return c.assembleAllJSON(ctx, ip, language)
+	})
 	if err != nil {
-		c.log.Error(err, "failed to get IPDecimal")
 		return nil, err
 	}
 
-	parsedIP := net.ParseIP(ip)
+	// the cached reply is shared, the user agent belongs to this request and reverse DNS is cached by the resolver for
+	// the TTL of its answers
+	reply := *cached
 
-	// Single ASN lookup instead of two separate calls
-	asnRecord, err := c.max.ASN(ctx, parsedIP)
+	reply.UserAgent, err = c.ua(ctx)
 	if err != nil {
-		c.log.Error(err, "failed to get ASN")
+		c.log.Error(err, "failed to get UserAgent")
 		return nil, err
 	}
-	reply.ASN = asnRecord.AutonomousSystemNumber
-	reply.ASNOrganization = asnRecord.AutonomousSystemOrganization
 
-	// Single City lookup instead of eight separate calls
-	cityRecord, err := c.max.City(ctx, parsedIP)
+	reply.ReverseDNS = c.reverseDNS(ctx, net.ParseIP(ip))
+	reply.Hostname = reply.ReverseDNS.Hostname()
+
+	return &reply, nil
+}
+
+func (c *Client) assembleAllJSON(ctx context.Context, ip, language string) (*model.ReplyIPInformation, error) {
+	reply := &model.ReplyIPInformation{}
+	reply.IP = ip
+
+	var err error
+	reply.IPDecimal, err = c.IPDecimal(ctx)
 	if err != nil {
-		c.log.Error(err, "failed to get City")
+		c.log.Error(err, "failed to get IPDecimal")
 		return nil, err
 	}
 
-	reply.City = cityRecord.City.Names["en"]
-	reply.Country = cityRecord.Country.Names["en"]
-	reply.CountryISO = cityRecord.Country.IsoCode
-	reply.IsEU = cityRecord.Country.IsInEuropeanUnion
-	reply.Is1918Network = parsedIP.IsPrivate()
-	reply.PostalCode = cityRecord.Postal.Code
-	reply.Coordinates = &model.Coordinates{
-		Latitude:  cityRecord.Location.Latitude,
-		Longitude: cityRecord.Location.Longitude,
-	}
-	reply.Timezone = cityRecord.Location.TimeZone
-	reply.Continent = cityRecord.Continent.Names["en"]
+	parsedIP := net.ParseIP(ip)
+	reply.SpecialPurpose = specialPurpose(parsedIP)
 
-	reply.UserAgent, err = c.ua(ctx)
+	geoRecord, asnRecord, err := c.lookupRecords(ctx, parsedIP, reply.SpecialPurpose)
 	if err != nil {
-		c.log.Error(err, "failed to get UserAgent")
 		return nil, err
 	}
 
+	reply.Labels, geoRecord, asnRecord = c.lookupLabels(ctx, parsedIP, geoRecord, asnRecord)
+
+	reply.ReplyFields = c.replyFields(ctx, parsedIP, geoRecord, asnRecord, reply.SpecialPurpose, language)
+
+	reply.Cloud = c.lookupCloud(ctx, parsedIP)
+
+	reply.IPv6Transition = c.lookupTransition(ctx, ip, language)
+
 	return reply, nil
 }
 
 func (c *Client) formatLookUpJSON(ctx context.Context) (*model.ReplyLookUp, error) {
-	reply := &model.ReplyLookUp{}
-
 	ip, err := c.getIP(ctx)
 	if err != nil {
 		c.log.Error(err, "failed to get IP")
 		return nil, err
 	}
-	reply.IP = ip
 
-	reply.IPDecimal, err = c.IPDecimal(ctx)
+	language := c.getLanguage(ctx)
+
+	cached, err := c.lookupCache.get(replyCacheKey{ip: ip, language: language}, func() (*model.ReplyLookUp, error) {
+		return c.assembleLookUpJSON(ctx, ip, language)
+	})
 	if err != nil {
-		c.log.Error(err, "failed to get IPDecimal")
 		return nil, err
 	}
 
-	parsedIP := net.ParseIP(ip)
+	// the cached reply is shared, reverse DNS is cached by the resolver for the TTL of its answers and the list ages
+	// are as of this request
+	reply := *cached
 
-	// Single ASN lookup instead of two separate calls
-	asnRecord, err := c.max.ASN(ctx, parsedIP)
-	if err != nil {
-		c.log.Error(err, "failed to get ASN")
-		return nil, err
+	reply.Blocklists = listAges(reply.Blocklists, time.Now())
+
+	reply.ReverseDNS = c.reverseDNS(ctx, net.ParseIP(ip))
+	reply.Hostname = reply.ReverseDNS.Hostname()
+	if reply.ReverseDNS != nil && len(reply.ReverseDNS.Names) > 0 {
+		reply.PTR = reply.ReverseDNS.Names[0].Name
 	}
-	reply.ASN = asnRecord.AutonomousSystemNumber
-	reply.ASNOrganization = asnRecord.AutonomousSystemOrganization
 
-	c.log.Debug("before whois")
+	return &reply, nil
+}
+
+func (c *Client) assembleLookUpJSON(ctx context.Context, ip, language string) (*model.ReplyLookUp, error) {
+	reply := &model.ReplyLookUp{}
+	reply.IP = ip
 
-	reply.Whois, err = c.whois.QueryIP(ctx, reply.IP)
+	var err error
+	reply.IPDecimal, err = c.IPDecimal(ctx)
 	if err != nil {
-		c.log.Error(err, "failed to get route info from radb", "ip", reply.IP)
+		c.log.Error(err, "failed to get IPDecimal")
 		return nil, err
 	}
 
-	c.log.Debug("after whois")
+	parsedIP := net.ParseIP(ip)
+	reply.SpecialPurpose = specialPurpose(parsedIP)
 
-	// Single City lookup instead of eight separate calls
-	cityRecord, err := c.max.City(ctx, parsedIP)
+	geoRecord, asnRecord, err := c.lookupRecords(ctx, parsedIP, reply.SpecialPurpose)
 	if err != nil {
-		c.log.Error(err, "failed to get City")
 		return nil, err
 	}
 
-	reply.City = cityRecord.City.Names["en"]
-	reply.Country = cityRecord.Country.Names["en"]
-	reply.CountryISO = cityRecord.Country.IsoCode
-	reply.IsEU = cityRecord.Country.IsInEuropeanUnion
-	reply.Is1918Network = parsedIP.IsPrivate()
-	reply.PostalCode = cityRecord.Postal.Code
-	reply.Coordinates = &model.Coordinates{
-		Latitude:  cityRecord.Location.Latitude,
-		Longitude: cityRecord.Location.Longitude,
-	}
-	reply.Timezone = cityRecord.Location.TimeZone
-	reply.Continent = cityRecord.Continent.Names["en"]
+	// no route object holds an address that is never globally reachable
+	if !reply.SpecialPurpose.Unreachable() {
+		c.log.Debug("before whois")
+
+		reply.Whois, err = c.whois.QueryIP(ctx, reply.IP)
+		if err != nil {
+			c.log.Error(err, "failed to get route info from radb", "ip", reply.IP)
+			return nil, err
+		}
 
-	// Reverse DNS lookup
-	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
-	if err == nil && len(names) > 0 {
-		reply.PTR = names[0]
+		c.log.Debug("after whois")
 	}
 
+	reply.Labels, geoRecord, asnRecord = c.lookupLabels(ctx, parsedIP, geoRecord, asnRecord)
+
+	reply.ReplyFields = c.replyFields(ctx, parsedIP, geoRecord, asnRecord, reply.SpecialPurpose, language)
+
+	reply.Cloud = c.lookupCloud(ctx, parsedIP)
+
+	reply.IPv6Transition = c.lookupTransition(ctx, ip, language)
+
+	reply.Blocklists = c.lookupBlocklists(ctx, parsedIP)
+
+	reply.BGP = c.lookupRoute(ctx, parsedIP, reply.Whois)
+
 	return reply, nil
 }
@@ -0,0 +1,126 @@
+package apiv1
+
+import (
+	"context"
+	"errors"
+	"ip_service/pkg/contexthandler"
+	"ip_service/pkg/model"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllJSONSpecialPurpose(t *testing.T) {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	c := &Client{
+		config: &model.Cfg{},
+		log:    logger.NewSimple("test"),
+		tp:     tracer,
+		// a geo lookup fails the reply, so only addresses that skip it succeed
+		geo: &fakeGeo{err: errors.New("not loaded")},
+		labels: fakeLabeler{
+			"10.1.2.3": {Prefixes: []string{"10.1.0.0/16"}, Labels: map[string]string{"network": "campus"}},
+		},
+	}
+
+	tts := []struct {
+		name        string
+		ip          string
+		wantName    string
+		want1918    bool
+		wantErr     bool
+		wantLabeled bool
+	}{
+		{name: "rfc1918", ip: "10.1.2.3", wantName: "Private-Use", want1918: true, wantLabeled: true},
+		{name: "cgnat", ip: "100.64.0.1", wantName: "Shared Address Space"},
+		{name: "ula is not rfc1918", ip: "fd00::1", wantName: "Unique-Local"},
+		{name: "loopback", ip: "127.0.0.1", wantName: "Loopback"},
+		{name: "6to4 is looked up", ip: "2002:c000:204::1", wantErr: true},
+		{name: "global is looked up", ip: "130.242.1.1", wantErr: true},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: tt.ip})
+			got, err := c.Index(ctx)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			require.NotNil(t, got.SpecialPurpose)
+			assert.Equal(t, tt.wantName, got.SpecialPurpose.Name)
+			assert.Equal(t, tt.want1918, got.Is1918Network)
+			assert.Empty(t, got.Country)
+			assert.Zero(t, got.ASN)
+			assert.Equal(t, tt.wantLabeled, got.Labels != nil)
+		})
+	}
+}
+
+func TestLookUpIPSpecialPurpose(t *testing.T) {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	// without geo and whois, the lookup only succeeds when both are skipped
+	c := &Client{
+		config: &model.Cfg{},
+		log:    logger.NewSimple("test"),
+		tp:     tracer,
+		geo:    &fakeGeo{err: errors.New("not loaded")},
+	}
+
+	ctx := contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{})
+	got, err := c.LookUpIP(ctx, &LookUpIPRequest{IP: "127.0.0.1"})
+	require.NoError(t, err)
+
+	require.NotNil(t, got.SpecialPurpose)
+	assert.Equal(t, "127.0.0.0/8", got.SpecialPurpose.Prefix)
+	assert.Equal(t, []string{"RFC1122"}, got.SpecialPurpose.RFC)
+	assert.False(t, got.Is1918Network)
+	assert.Nil(t, got.Whois)
+}
+
+func TestFieldsSpecialPurpose(t *testing.T) {
+	// a geo lookup fails the helpers, so only addresses that skip it succeed
+	c := &Client{
+		log: logger.NewSimple("test"),
+		geo: &fakeGeo{err: errors.New("not loaded")},
+	}
+
+	tts := []struct {
+		ip      string
+		wantErr bool
+	}{
+		{ip: "10.1.2.3"},
+		{ip: "127.0.0.1"},
+		{ip: "fd00::1"},
+		{ip: "130.242.1.1", wantErr: true},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.ip, func(t *testing.T) {
+			ctx := contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: tt.ip})
+
+			city, err := c.city(ctx)
+			assert.Equal(t, tt.wantErr, err != nil, "city: %v", err)
+			assert.Empty(t, city)
+
+			asn, err := c.asn(ctx)
+			assert.Equal(t, tt.wantErr, err != nil, "asn: %v", err)
+			assert.Zero(t, asn)
+
+			coordinates, err := c.coordinates(ctx)
+			assert.Equal(t, tt.wantErr, err != nil, "coordinates: %v", err)
+			if !tt.wantErr {
+				assert.Equal(t, &model.Coordinates{}, coordinates)
+			}
+		})
+	}
+}
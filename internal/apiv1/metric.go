@@ -0,0 +1,42 @@
+package apiv1
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the prometheus metrics for the lookup cache, labeled by cache name, for special-purpose lookups and
+// for reverse DNS errors
+var metrics = struct {
+	hits           *prometheus.CounterVec
+	misses         *prometheus.CounterVec
+	evictions      *prometheus.CounterVec
+	invalidations  prometheus.Counter
+	specialPurpose *prometheus.CounterVec
+	dnsErrors      *prometheus.CounterVec
+}{
+	hits: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ip_service_lookup_cache_hits_total",
+		Help: "The total number of lookup cache hits",
+	}, []string{"cache"}),
+	misses: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ip_service_lookup_cache_misses_total",
+		Help: "The total number of lookup cache misses",
+	}, []string{"cache"}),
+	evictions: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ip_service_lookup_cache_evictions_total",
+		Help: "The total number of lookup cache evictions, by reason",
+	}, []string{"cache", "reason"}),
+	invalidations: promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ip_service_lookup_cache_invalidations_total",
+		Help: "The total number of lookup cache flushes caused by dataset reloads",
+	}),
+	specialPurpose: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ip_service_lookup_special_purpose_total",
+		Help: "The total number of lookups of addresses that are never globally reachable, answered without geo and whois lookups, by registry name",
+	}, []string{"name"}),
+	dnsErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ip_service_reverse_dns_errors_total",
+		Help: "The total number of failed reverse DNS lookups, by query (ptr or forward) and reason (timeout, deadline or error)",
+	}, []string{"query", "reason"}),
+}
@@ -0,0 +1,136 @@
+package apiv1
+
+import (
+	"context"
+	"errors"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDNSTimeout = 2 * time.Second
+	// maxPTRNames bounds the forward lookups of an IP with many PTR records
+	maxPTRNames = 10
+)
+
+// Resolver resolves the PTR names of an address and the addresses of a name, e.g. resolver.Service. A lookup
+// still running at the deadline of ctx returns context.DeadlineExceeded.
+type Resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+func dnsTimeout(cfg model.DNS) time.Duration {
+	if cfg.Timeout > 0 {
+		return cfg.Timeout
+	}
+	return defaultDNSTimeout
+}
+
+// reverseDNS returns the PTR names of ip, each forward confirmed when it resolves back to ip. nil without a resolver.
+// The lookups share the deadline of dnsTimeout, a lookup past it is marked pending instead of holding the reply.
+func (c *Client) reverseDNS(ctx context.Context, ip net.IP) *model.ReverseDNS {
+	if c.resolver == nil {
+		return nil
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	timeout := c.dnsTimeout
+	if timeout <= 0 {
+		timeout = defaultDNSTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	names, err := c.resolver.LookupAddr(ctx, addr.String())
+
+	reply := &model.ReverseDNS{Names: []model.PTRName{}}
+	if reason := dnsErrorReason(err); reason != "" {
+		metrics.dnsErrors.WithLabelValues("ptr", reason).Inc()
+		if reason == "deadline" {
+			reply.Pending = true
+		} else {
+			reply.Error = dnsErrorMessage(err)
+		}
+		return reply
+	}
+	if len(names) > maxPTRNames {
+		names = names[:maxPTRNames]
+	}
+
+	reply.Names = make([]model.PTRName, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply.Names[i] = c.forwardConfirm(ctx, name, addr)
+		}()
+	}
+	wg.Wait()
+
+	return reply
+}
+
+// forwardConfirm resolves name and marks it forward confirmed when one of its addresses is addr
+func (c *Client) forwardConfirm(ctx context.Context, name string, addr netip.Addr) model.PTRName {
+	ptr := model.PTRName{Name: name}
+
+	addrs, err := c.resolver.LookupIPAddr(ctx, name)
+	if reason := dnsErrorReason(err); reason != "" {
+		metrics.dnsErrors.WithLabelValues("forward", reason).Inc()
+		if reason == "deadline" {
+			ptr.Pending = true
+		} else {
+			ptr.Error = dnsErrorMessage(err)
+		}
+		return ptr
+	}
+
+	for _, a := range addrs {
+		if forward, ok := netip.AddrFromSlice(a.IP); ok && forward.Unmap() == addr {
+			ptr.ForwardConfirmed = true
+			break
+		}
+	}
+
+	return ptr
+}
+
+// dnsErrorReason returns the metric reason of a failed lookup, timeout of a DNS server, deadline of the request or
+// error. A name that does not exist is not a failure, it is empty like no error.
+func dnsErrorReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		switch {
+		case dnsErr.IsNotFound:
+			return ""
+		case dnsErr.IsTimeout:
+			return "timeout"
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "deadline"
+	}
+	return "error"
+}
+
+// dnsErrorMessage returns the reason of a failed lookup without the name and the server address
+func dnsErrorMessage(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.Err
+	}
+	return err.Error()
+}
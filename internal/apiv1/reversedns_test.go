@@ -0,0 +1,171 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/contexthandler"
+	"ip_service/pkg/model"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver is an in-memory Resolver, names and addresses missing from it do not exist
+type fakeResolver struct {
+	ptr     map[string][]string
+	forward map[string][]string
+	// errs fails the lookups of an address or name
+	errs map[string]error
+}
+
+func (f *fakeResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	if err, ok := f.errs[addr]; ok {
+		return nil, err
+	}
+	names, ok := f.ptr[addr]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+	}
+	return names, nil
+}
+
+func (f *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if err, ok := f.errs[host]; ok {
+		return nil, err
+	}
+	addrs, ok := f.forward[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	reply := make([]net.IPAddr, 0, len(addrs))
+	for _, addr := range addrs {
+		reply = append(reply, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+	return reply, nil
+}
+
+func TestReverseDNS(t *testing.T) {
+	c := &Client{
+		dnsTimeout: time.Second,
+		resolver: &fakeResolver{
+			ptr: map[string][]string{
+				"130.242.1.1":   {"spoofed.example.", "www.sunet.se.", "dangling.sunet.se.", "broken.sunet.se."},
+				"2001:6b0:7::1": {"www.sunet.se."},
+				"130.242.1.5":   {"slow.sunet.se."},
+			},
+			forward: map[string][]string{
+				"spoofed.example.": {"192.0.2.1"},
+				"www.sunet.se.":    {"130.242.1.1", "2001:6b0:7::1"},
+			},
+			errs: map[string]error{
+				"broken.sunet.se.": &net.DNSError{Err: "server misbehaving", Name: "broken.sunet.se.", Server: "10.0.0.53:53"},
+				"130.242.1.3":      &net.DNSError{Err: "i/o timeout", Name: "3.1.242.130.in-addr.arpa.", IsTimeout: true},
+				"130.242.1.4":      context.DeadlineExceeded,
+				"slow.sunet.se.":   context.DeadlineExceeded,
+			},
+		},
+	}
+
+	tts := []struct {
+		name         string
+		ip           string
+		want         *model.ReverseDNS
+		wantHostname string
+	}{
+		{
+			name: "each name is forward resolved",
+			ip:   "130.242.1.1",
+			want: &model.ReverseDNS{Names: []model.PTRName{
+				{Name: "spoofed.example."},
+				{Name: "www.sunet.se.", ForwardConfirmed: true},
+				{Name: "dangling.sunet.se."},
+				{Name: "broken.sunet.se.", Error: "server misbehaving"},
+			}},
+			wantHostname: "www.sunet.se",
+		},
+		{
+			name:         "ipv6",
+			ip:           "2001:6b0:7::1",
+			want:         &model.ReverseDNS{Names: []model.PTRName{{Name: "www.sunet.se.", ForwardConfirmed: true}}},
+			wantHostname: "www.sunet.se",
+		},
+		{
+			name: "no ptr record",
+			ip:   "130.242.1.2",
+			want: &model.ReverseDNS{Names: []model.PTRName{}},
+		},
+		{
+			name: "ptr lookup timeout",
+			ip:   "130.242.1.3",
+			want: &model.ReverseDNS{Names: []model.PTRName{}, Error: "i/o timeout"},
+		},
+		{
+			name: "ptr lookup past the request deadline",
+			ip:   "130.242.1.4",
+			want: &model.ReverseDNS{Names: []model.PTRName{}, Pending: true},
+		},
+		{
+			name: "forward lookup past the request deadline",
+			ip:   "130.242.1.5",
+			want: &model.ReverseDNS{Names: []model.PTRName{{Name: "slow.sunet.se.", Pending: true}}},
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.reverseDNS(context.TODO(), net.ParseIP(tt.ip))
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantHostname, got.Hostname())
+		})
+	}
+
+	// without a resolver there is no reverse DNS
+	c.resolver = nil
+	assert.Nil(t, c.reverseDNS(context.TODO(), net.ParseIP("130.242.1.1")))
+}
+
+func TestDNSErrorReason(t *testing.T) {
+	assert.Empty(t, dnsErrorReason(nil))
+	assert.Empty(t, dnsErrorReason(&net.DNSError{Err: "no such host", IsNotFound: true}))
+	assert.Equal(t, "timeout", dnsErrorReason(&net.DNSError{Err: "i/o timeout", IsTimeout: true}))
+	assert.Equal(t, "deadline", dnsErrorReason(context.DeadlineExceeded))
+	assert.Equal(t, "error", dnsErrorReason(&net.DNSError{Err: "server misbehaving"}))
+}
+
+func TestReverseDNSNotCached(t *testing.T) {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	resolver := &fakeResolver{
+		ptr:     map[string][]string{"130.242.1.1": {"www.sunet.se."}},
+		forward: map[string][]string{"www.sunet.se.": {"130.242.1.1"}},
+	}
+	c := &Client{
+		config:     &model.Cfg{},
+		log:        logger.NewSimple("test"),
+		tp:         tracer,
+		geo:        &fakeGeo{},
+		resolver:   resolver,
+		dnsTimeout: time.Second,
+		allCache:   newReplyCache[*model.ReplyIPInformation]("test", model.LookupCache{}),
+	}
+	defer c.allCache.close()
+
+	ctx := contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: "130.242.1.1"})
+	got, err := c.Index(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "www.sunet.se", got.Hostname)
+
+	// the reply is served from the cache, its reverse DNS is resolved again
+	resolver.ptr["130.242.1.1"] = []string{"mail.sunet.se."}
+	resolver.forward["mail.sunet.se."] = []string{"130.242.1.1"}
+	got, err = c.Index(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "mail.sunet.se", got.Hostname)
+	assert.Equal(t, []model.PTRName{{Name: "mail.sunet.se.", ForwardConfirmed: true}}, got.ReverseDNS.Names)
+	assert.NotNil(t, c.allCache.cache.Get(replyCacheKey{ip: "130.242.1.1", language: "en"}))
+}
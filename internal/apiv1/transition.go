@@ -0,0 +1,69 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/ipv6transition"
+	"ip_service/pkg/model"
+	"ip_service/pkg/oui"
+	"net"
+	"net/netip"
+)
+
+// wellKnownTransition decodes with the well-known NAT64 prefix only, for clients created without a configuration
+var wellKnownTransition, _ = ipv6transition.NewDecoder(nil)
+
+// lookupTransition decodes the IPv6 transition mechanism and EUI-64 interface identifier of ip, with the geo and asn
+// of an embedded IPv4 address. nil for IPv4 addresses and IPv6 addresses that tell neither.
+func (c *Client) lookupTransition(ctx context.Context, ip, language string) *model.Transition {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+
+	decoder := c.transition
+	if decoder == nil {
+		decoder = wellKnownTransition
+	}
+	transition := decoder.Decode(addr)
+	if transition == nil {
+		return nil
+	}
+
+	if transition.EUI64 != nil && !transition.EUI64.LocallyAdministered {
+		registry := c.oui
+		if registry == nil {
+			registry = oui.Default()
+		}
+		if mac, err := net.ParseMAC(transition.EUI64.MAC); err == nil {
+			transition.EUI64.Vendor = registry.Vendor(mac)
+		}
+	}
+
+	if transition.EmbeddedIPv4 != "" {
+		transition.Embedded = c.lookupEmbedded(ctx, net.ParseIP(transition.EmbeddedIPv4), language)
+	}
+
+	return transition
+}
+
+// lookupEmbedded returns the geo and asn of an embedded IPv4 address, a failed lookup leaves them empty
+func (c *Client) lookupEmbedded(ctx context.Context, ip net.IP, language string) *model.EmbeddedIPv4Info {
+	info := &model.EmbeddedIPv4Info{
+		IP:             ip.String(),
+		SpecialPurpose: specialPurpose(ip),
+	}
+
+	geoRecord, asnRecord, err := c.lookupRecords(ctx, ip, info.SpecialPurpose)
+	if err != nil {
+		return info
+	}
+
+	info.ASN = asnRecord.ASN
+	info.ASNOrganization = asnRecord.Organization
+	info.City = localizedName(geoRecord.City, language)
+	info.Country = localizedName(geoRecord.Country, language)
+	info.CountryISO = geoRecord.CountryISO
+	info.Coordinates = coordinatesOf(geoRecord)
+
+	return info
+}
@@ -0,0 +1,77 @@
+package apiv1
+
+import (
+	"context"
+	"ip_service/pkg/contexthandler"
+	"ip_service/pkg/model"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllJSONTransition(t *testing.T) {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	c := &Client{
+		config: &model.Cfg{},
+		log:    logger.NewSimple("test"),
+		tp:     tracer,
+		geo: &fakeGeo{
+			geo: map[string]*model.GeoRecord{"130.242.1.1": {CountryISO: "SE", Country: map[string]string{"en": "Sweden"}}},
+			asn: map[string]*model.ASNRecord{"130.242.1.1": {ASN: 1653, Organization: "SUNET"}},
+		},
+	}
+
+	tts := []struct {
+		name string
+		ip   string
+		want *model.Transition
+	}{
+		{
+			name: "6to4 with the geo of its IPv4",
+			ip:   "2002:82f2:101::1",
+			want: &model.Transition{
+				Mechanism:    model.Transition6to4,
+				Prefix:       "2002::/16",
+				EmbeddedIPv4: "130.242.1.1",
+				Embedded:     &model.EmbeddedIPv4Info{IP: "130.242.1.1", ASN: 1653, ASNOrganization: "SUNET", Country: "Sweden", CountryISO: "SE", Coordinates: &model.Coordinates{}},
+			},
+		},
+		{
+			name: "teredo client behind a documentation address",
+			ip:   "2001:0:4136:e378:8000:63bf:3fff:fdd2",
+			want: &model.Transition{
+				Mechanism:    model.TransitionTeredo,
+				Prefix:       "2001::/32",
+				EmbeddedIPv4: "192.0.2.45",
+				Teredo:       &model.Teredo{Server: "65.54.227.120", Client: "192.0.2.45", ClientPort: 40000, Cone: true},
+				Embedded: &model.EmbeddedIPv4Info{
+					IP:          "192.0.2.45",
+					Coordinates: &model.Coordinates{},
+					SpecialPurpose: &model.SpecialPurpose{
+						Prefix: "192.0.2.0/24", Name: "Documentation (TEST-NET-1)", RFC: []string{"RFC5737"}, GloballyReachable: new(bool),
+					},
+				},
+			},
+		},
+		{
+			name: "eui-64 with the vendor of its OUI",
+			ip:   "2001:6b0:1:2:250:56ff:fe01:203",
+			want: &model.Transition{EUI64: &model.EUI64{MAC: "00:50:56:01:02:03", OUI: "00-50-56", Vendor: "VMware, Inc."}},
+		},
+		{name: "ipv4", ip: "130.242.1.1"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := contexthandler.Add(context.TODO(), "request", &contexthandler.RequestContext{ClientIP: tt.ip})
+			got, err := c.Index(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.IPv6Transition)
+		})
+	}
+}
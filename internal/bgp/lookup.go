@@ -0,0 +1,33 @@
+package bgp
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+)
+
+// LookupRoute returns the most specific announced prefix holding ip, nil if it is not routed or no dump is loaded
+func (s *Service) LookupRoute(ctx context.Context, ip net.IP) *model.BGPRoute {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	t := s.table.Load()
+	if t == nil {
+		return nil
+	}
+
+	record, ok := t.Lookup(addr)
+	if !ok {
+		return nil
+	}
+
+	// the record is shared by the lookups, the caller adds the IRR comparison to its copy
+	route := *record
+	route.Peers = t.peers
+	route.DumpTime = t.dumpTime
+	return &route
+}
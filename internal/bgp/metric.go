@@ -0,0 +1,42 @@
+package bgp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the prometheus metrics for the MRT RIB dump
+var metrics = struct {
+	prefixes     *prometheus.GaugeVec
+	peers        prometheus.Gauge
+	dumpTime     prometheus.Gauge
+	lastSuccess  prometheus.Gauge
+	lastFailure  prometheus.Gauge
+	loadDuration prometheus.Histogram
+}{
+	prefixes: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_bgp_prefixes",
+		Help: "Routed prefixes in the loaded MRT RIB dump",
+	}, []string{"family"}),
+	peers: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_bgp_peers",
+		Help: "Collector peers in the loaded MRT RIB dump",
+	}),
+	dumpTime: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_bgp_dump_timestamp_seconds",
+		Help: "Time the loaded MRT RIB dump was taken",
+	}),
+	lastSuccess: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_bgp_last_success_timestamp_seconds",
+		Help: "Time of the last successfully loaded MRT RIB dump",
+	}),
+	lastFailure: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_bgp_last_failure_timestamp_seconds",
+		Help: "Time of the last failed MRT RIB dump load",
+	}),
+	loadDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ip_service_bgp_load_duration_seconds",
+		Help:    "Time spent loading and indexing an MRT RIB dump",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	}),
+}
@@ -0,0 +1,226 @@
+package bgp
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"ip_service/internal/loader"
+	"ip_service/pkg/model"
+	"ip_service/pkg/mrt"
+	"os"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+)
+
+const defaultUpdatePeriodicity = time.Hour
+
+// Service loads the routed prefixes of an MRT RIB dump into patricia trees, the file is reloaded when it changes
+type Service struct {
+	*loader.Schedule
+	log      *logger.Log
+	filePath string
+	state    fileState
+	table    atomic.Pointer[table]
+}
+
+// table holds the routed prefixes of a dump
+type table struct {
+	*loader.Table[*model.BGPRoute]
+	peers    int
+	dumpTime time.Time
+}
+
+// fileState is the part of a file's stat that marks it as changed
+type fileState struct {
+	modTime int64
+	size    int64
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
+
+// New creates a new bgp service and loads the dump, a dump that fails to load is retried on the next update
+func New(ctx context.Context, cfg *model.Cfg, log *logger.Log) (*Service, error) {
+	s := &Service{
+		log:      log,
+		filePath: cfg.IPService.BGP.FilePath,
+	}
+
+	s.Schedule = loader.NewSchedule(ctx, updatePeriodicity(cfg), s.update, log)
+
+	s.log.Info("Started")
+
+	return s, nil
+}
+
+func updatePeriodicity(cfg *model.Cfg) time.Duration {
+	if cfg.IPService.BGP.UpdatePeriodicity > 0 {
+		return cfg.IPService.BGP.UpdatePeriodicity
+	}
+	return defaultUpdatePeriodicity
+}
+
+// update loads the dump when the file has changed since the last load, a dump that fails keeps the previous table.
+// It reports whether a new dump was loaded.
+func (s *Service) update(ctx context.Context) bool {
+	current := statFile(s.filePath)
+	if current == s.state {
+		return false
+	}
+
+	start := time.Now()
+
+	t, err := s.load(ctx, s.filePath)
+	if err != nil {
+		s.log.Error(err, "failed to load the MRT RIB dump, keeping the previous one", "path", s.filePath)
+		metrics.lastFailure.SetToCurrentTime()
+		return false
+	}
+
+	s.state = current
+	s.table.Store(t)
+
+	metrics.loadDuration.Observe(time.Since(start).Seconds())
+	metrics.lastSuccess.SetToCurrentTime()
+	metrics.peers.Set(float64(t.peers))
+	metrics.dumpTime.Set(float64(t.dumpTime.Unix()))
+
+	return true
+}
+
+// load reads the RIB records of the dump into a table
+func (s *Service) load(ctx context.Context, path string) (*table, error) {
+	r, modified, err := loader.Open(ctx, nil, loader.Source{Name: "rib", FilePath: path})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	t := &table{Table: loader.NewTable[*model.BGPRoute]("rib", modified)}
+
+	reader := mrt.NewReader(r)
+	for {
+		route, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if t.dumpTime.IsZero() {
+			t.dumpTime = reader.Time
+		}
+
+		// a default route would hold every address
+		if route.Prefix.Bits() == 0 {
+			continue
+		}
+		record := aggregate(route)
+		if record == nil {
+			continue
+		}
+		t.Set(route.Prefix, record)
+	}
+	v4Count, v6Count := t.Len()
+	if v4Count+v6Count == 0 {
+		return nil, fmt.Errorf("no routed prefix in %s", path)
+	}
+	t.peers = len(reader.Peers)
+	t.Loaded = time.Now()
+
+	metrics.prefixes.WithLabelValues("ipv4").Set(float64(v4Count))
+	metrics.prefixes.WithLabelValues("ipv6").Set(float64(v6Count))
+
+	s.log.Info("MRT RIB dump loaded", "path", path, "dump_time", t.dumpTime, "peers", t.peers, "v4_prefixes", v4Count, "v6_prefixes", v6Count)
+
+	return t, nil
+}
+
+// aggregate returns the prefix as seen by the collector's peers, nil when no peer has an origin for it
+func aggregate(route *mrt.Route) *model.BGPRoute {
+	peers := map[int]struct{}{}
+	originPeers := map[uint32]int{}
+	pathLength := -1
+	for _, entry := range route.Entries {
+		// with add-path a peer can have more than one entry
+		peers[entry.PeerIndex] = struct{}{}
+		if origin, ok := entry.Origin(); ok {
+			originPeers[origin]++
+		}
+		if length := entry.PathLength(); pathLength < 0 || length < pathLength {
+			pathLength = length
+		}
+	}
+	if len(originPeers) == 0 {
+		return nil
+	}
+
+	origins := make([]uint32, 0, len(originPeers))
+	for origin := range originPeers {
+		origins = append(origins, origin)
+	}
+	slices.SortFunc(origins, func(a, b uint32) int {
+		if c := cmp.Compare(originPeers[b], originPeers[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	return &model.BGPRoute{
+		Prefix:       route.Prefix.String(),
+		OriginASNs:   origins,
+		ASPathLength: pathLength,
+		PeersSeen:    len(peers),
+	}
+}
+
+// Reload applies the hot reloadable settings of cfg, update_periodicity
+func (s *Service) Reload(ctx context.Context, cfg *model.Cfg) {
+	s.Reset(updatePeriodicity(cfg))
+	s.log.Info("Reloaded", "update_periodicity", updatePeriodicity(cfg))
+}
+
+// State returns the load state of the dump, a dump that was never loaded has no build time
+func (s *Service) State(ctx context.Context) []*model.DatasetState {
+	state := &model.DatasetState{
+		Name:        model.DatasetKindBGP,
+		Kind:        model.DatasetKindBGP,
+		Parsing:     s.Updating(),
+		LastChecked: s.LastUpdate().UTC().Format(time.RFC3339),
+	}
+	if t := s.table.Load(); t != nil {
+		state.BuildTime = t.dumpTime
+	}
+	return []*model.DatasetState{state}
+}
+
+// Status fails when no dump has been loaded, a dump that fails to load keeps the previous one serving
+func (s *Service) Status(ctx context.Context) *model.StatusProbe {
+	probe := &model.StatusProbe{
+		Name:          model.DatasetKindBGP,
+		Healthy:       true,
+		Message:       map[string]any{},
+		LastCheckedTS: time.Now(),
+	}
+
+	t := s.table.Load()
+	if t == nil {
+		probe.Healthy = false
+		probe.Message["status"] = "no MRT RIB dump loaded"
+		return probe
+	}
+	probe.Message["loaded"] = t.Loaded.UTC().Format(time.RFC3339)
+	probe.Message["dump_time"] = t.dumpTime.UTC().Format(time.RFC3339)
+
+	return probe
+}
@@ -0,0 +1,166 @@
+package bgp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dumpTimestamp = 1704236103
+
+// mrtRecord returns a TABLE_DUMP_V2 record of subtype holding body
+func mrtRecord(subtype uint16, body []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, dumpTimestamp)
+	b = binary.BigEndian.AppendUint16(b, 13)
+	b = binary.BigEndian.AppendUint16(b, subtype)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(body)))
+	return append(b, body...)
+}
+
+// dump returns an MRT file of three IPv4 peers and a RIB record per prefix, holding an AS_SEQUENCE path per peer
+func dump(routes map[string][][]uint32) []byte {
+	peers := []byte{192, 0, 2, 1, 0, 0}
+	peers = binary.BigEndian.AppendUint16(peers, 3)
+	for i := range 3 {
+		peers = append(peers, 0x02, 192, 0, 2, byte(10+i), 192, 0, 2, byte(10+i))
+		peers = binary.BigEndian.AppendUint32(peers, uint32(64496+i))
+	}
+	file := mrtRecord(1, peers)
+
+	for p, paths := range routes {
+		prefix := netip.MustParsePrefix(p)
+		subtype := uint16(2)
+		if prefix.Addr().Is6() {
+			subtype = 4
+		}
+
+		b := binary.BigEndian.AppendUint32(nil, 0)
+		b = append(b, uint8(prefix.Bits()))
+		b = append(b, prefix.Addr().AsSlice()[:(prefix.Bits()+7)/8]...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(paths)))
+		for peer, path := range paths {
+			attribute := []byte{0x40, 2, uint8(2 + 4*len(path)), 2, uint8(len(path))}
+			for _, asn := range path {
+				attribute = binary.BigEndian.AppendUint32(attribute, asn)
+			}
+			b = binary.BigEndian.AppendUint16(b, uint16(peer))
+			b = binary.BigEndian.AppendUint32(b, dumpTimestamp)
+			b = binary.BigEndian.AppendUint16(b, uint16(len(attribute)))
+			b = append(b, attribute...)
+		}
+		file = append(file, mrtRecord(subtype, b)...)
+	}
+
+	return file
+}
+
+func TestLookupRoute(t *testing.T) {
+	gz := &bytes.Buffer{}
+	gzw := gzip.NewWriter(gz)
+	_, err := gzw.Write(dump(map[string][][]uint32{
+		"0.0.0.0/0":        {{64496, 64500}},
+		"130.242.0.0/16":   {{64496, 1653}, {64497, 64510, 1653}, {64498, 64511, 64512, 1653}},
+		"130.242.128.0/17": {{64496, 64500}, {64497, 1653}, {64498, 64500}},
+		"2001:6b0::/32":    {{64496, 1653}, {64497, 64510, 1653}},
+	}))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+
+	path := filepath.Join(t.TempDir(), "bview.20240102.2200.gz")
+	require.NoError(t, os.WriteFile(path, gz.Bytes(), 0600))
+
+	cfg := &model.Cfg{IPService: &model.IPService{BGP: model.BGP{FilePath: path}}}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	dumpTime := time.Unix(dumpTimestamp, 0).UTC()
+	tts := []struct {
+		ip   string
+		want *model.BGPRoute
+	}{
+		{
+			ip:   "130.242.1.1",
+			want: &model.BGPRoute{Prefix: "130.242.0.0/16", OriginASNs: []uint32{1653}, ASPathLength: 2, PeersSeen: 3, Peers: 3, DumpTime: dumpTime},
+		},
+		{
+			ip:   "::ffff:130.242.200.1",
+			want: &model.BGPRoute{Prefix: "130.242.128.0/17", OriginASNs: []uint32{64500, 1653}, ASPathLength: 2, PeersSeen: 3, Peers: 3, DumpTime: dumpTime},
+		},
+		{
+			ip:   "2001:6b0::1",
+			want: &model.BGPRoute{Prefix: "2001:6b0::/32", OriginASNs: []uint32{1653}, ASPathLength: 2, PeersSeen: 2, Peers: 3, DumpTime: dumpTime},
+		},
+		// the default route is not loaded
+		{ip: "198.51.100.1"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.LookupRoute(context.TODO(), net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestUpdateKeepsPreviousDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rib.mrt")
+	require.NoError(t, os.WriteFile(path, dump(map[string][][]uint32{"130.242.0.0/16": {{64496, 1653}}}), 0600))
+
+	cfg := &model.Cfg{IPService: &model.IPService{BGP: model.BGP{FilePath: path}}}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	reloads := 0
+	s.OnReload(func(ctx context.Context) { reloads++ })
+
+	// an unchanged file is not reloaded
+	s.Update(context.TODO())
+	assert.Equal(t, 0, reloads)
+
+	require.NoError(t, os.WriteFile(path, []byte("truncated"), 0600))
+	s.Update(context.TODO())
+	assert.Equal(t, 0, reloads)
+	assert.NotNil(t, s.LookupRoute(context.TODO(), net.ParseIP("130.242.1.1")))
+
+	require.NoError(t, os.WriteFile(path, dump(map[string][][]uint32{"130.243.0.0/16": {{64496, 1653}}}), 0600))
+	s.Update(context.TODO())
+	assert.Equal(t, 1, reloads)
+	assert.Nil(t, s.LookupRoute(context.TODO(), net.ParseIP("130.242.1.1")))
+	assert.NotNil(t, s.LookupRoute(context.TODO(), net.ParseIP("130.243.1.1")))
+}
+
+func TestStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rib.mrt")
+
+	cfg := &model.Cfg{IPService: &model.IPService{BGP: model.BGP{FilePath: path}}}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	assert.False(t, s.Status(context.TODO()).Healthy)
+	assert.True(t, s.State(context.TODO())[0].BuildTime.IsZero())
+
+	require.NoError(t, os.WriteFile(path, dump(map[string][][]uint32{"130.242.0.0/16": {{64496, 1653}}}), 0600))
+	s.Update(context.TODO())
+
+	assert.True(t, s.Status(context.TODO()).Healthy)
+	state := s.State(context.TODO())[0]
+	assert.Equal(t, model.DatasetKindBGP, state.Name)
+	assert.False(t, state.BuildTime.IsZero())
+}
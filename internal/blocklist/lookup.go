@@ -0,0 +1,47 @@
+package blocklist
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// LookupBlocklists returns the lists holding ip, with the most specific listed prefix of each, in configured order
+func (s *Service) LookupBlocklists(ctx context.Context, ip net.IP) []model.BlocklistMatch {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	now := time.Now()
+
+	var matches []model.BlocklistMatch
+	for _, t := range s.Tables() {
+		entry, ok := t.Lookup(addr)
+		if !ok {
+			continue
+		}
+		matches = append(matches, model.BlocklistMatch{
+			List:        t.Name,
+			Prefix:      entry.prefix.String(),
+			Reference:   entry.reference,
+			ListUpdated: t.Modified,
+			ListAge:     int64(now.Sub(t.Modified).Seconds()),
+		})
+	}
+
+	return matches
+}
+
+// Lists returns the names of the loaded lists, in configured order
+func (s *Service) Lists() []string {
+	tables := s.Tables()
+	names := make([]string, 0, len(tables))
+	for _, t := range tables {
+		names = append(names, t.Name)
+	}
+	return names
+}
@@ -0,0 +1,20 @@
+package blocklist
+
+import (
+	"ip_service/internal/loader"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the prometheus metrics for blocklists, labeled by list name
+var metrics = struct {
+	loader  *loader.Metrics
+	updated *prometheus.GaugeVec
+}{
+	loader: loader.NewMetrics("blocklist", "list", "blocklist"),
+	updated: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_blocklist_updated_timestamp_seconds",
+		Help: "Modification time of the loaded blocklist",
+	}, []string{"list"}),
+}
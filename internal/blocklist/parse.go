@@ -0,0 +1,120 @@
+package blocklist
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// listEntry is a listed prefix and its listing reference
+type listEntry struct {
+	prefix    netip.Prefix
+	reference string
+}
+
+// dropJSON is a line of Spamhaus' json DROP lists, a prefix or the metadata of the list
+type dropJSON struct {
+	CIDR      string `json:"cidr"`
+	SBLID     string `json:"sblid"`
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// parseList reads a blocklist in DROP or netset format, a prefix or address per line:
+//
+//	1.10.16.0/20 ; SBL256894           DROP and EDROP, ; starts the reference
+//	{"cidr":"1.10.16.0/20","sblid":"SBL256894","rir":"apnic"}
+//	1.10.16.0/20                       netset, # starts a comment
+//
+// It returns the entries and the modification time of the list when the list has one,
+// from the "; Last-Modified:" comment of DROP or the metadata line of json DROP.
+func parseList(r io.Reader) ([]listEntry, time.Time, int, error) {
+	var (
+		entries []listEntry
+		updated time.Time
+		skipped int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+
+		case strings.HasPrefix(line, ";"):
+			if value, ok := strings.CutPrefix(strings.TrimSpace(line[1:]), "Last-Modified:"); ok {
+				if t, err := time.Parse(time.RFC1123, strings.TrimSpace(value)); err == nil {
+					updated = t
+				}
+			}
+			continue
+
+		case strings.HasPrefix(line, "{"):
+			var drop dropJSON
+			if err := json.Unmarshal([]byte(line), &drop); err != nil {
+				skipped++
+				continue
+			}
+			if drop.Type == "metadata" {
+				updated = time.Unix(drop.Timestamp, 0)
+				continue
+			}
+			prefix, ok := parsePrefix(drop.CIDR)
+			if !ok {
+				skipped++
+				continue
+			}
+			entries = append(entries, listEntry{prefix: prefix, reference: drop.SBLID})
+			continue
+		}
+
+		line, _, _ = strings.Cut(line, "#")
+		value, reference, _ := strings.Cut(line, ";")
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			skipped++
+			continue
+		}
+
+		prefix, ok := parsePrefix(fields[0])
+		if !ok {
+			skipped++
+			continue
+		}
+		entries = append(entries, listEntry{prefix: prefix, reference: strings.TrimSpace(reference)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, time.Time{}, 0, err
+	}
+	if len(entries) == 0 && skipped > 0 {
+		return nil, time.Time{}, skipped, fmt.Errorf("no prefix in %d lines", skipped)
+	}
+
+	return entries, updated, skipped, nil
+}
+
+// parsePrefix parses a prefix or an address as its host prefix, IPv4-mapped addresses as IPv4
+func parsePrefix(s string) (netip.Prefix, bool) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, false
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), true
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
@@ -0,0 +1,72 @@
+package blocklist
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseList(t *testing.T) {
+	tts := []struct {
+		name        string
+		list        string
+		want        []listEntry
+		wantUpdated time.Time
+		wantSkipped int
+		wantErr     bool
+	}{
+		{
+			name: "drop",
+			list: "; Spamhaus DROP List 2024/01/02 - (c) 2024 The Spamhaus Project SLU\n; https://www.spamhaus.org/drop/drop.txt\n; Last-Modified: Tue, 02 Jan 2024 22:55:03 GMT\n; Expires: Wed, 03 Jan 2024 23:20:03 GMT\n1.10.16.0/20 ; SBL256894\n1.19.0.0/16 ; SBL434604\n",
+			want: []listEntry{
+				{prefix: netip.MustParsePrefix("1.10.16.0/20"), reference: "SBL256894"},
+				{prefix: netip.MustParsePrefix("1.19.0.0/16"), reference: "SBL434604"},
+			},
+			wantUpdated: time.Date(2024, 1, 2, 22, 55, 3, 0, time.UTC),
+		},
+		{
+			name: "drop json",
+			list: `{"cidr":"1.10.16.0/20","sblid":"SBL256894","rir":"apnic"}` + "\n" + `{"cidr":"2a06:e480::/29","sblid":"SBL301771","rir":"ripencc"}` + "\n" + `{"type":"metadata","timestamp":1704236103,"size":2,"records":2,"copyright":"(c) 2024 The Spamhaus Project SLU","terms":"https://www.spamhaus.org/drop/terms/"}` + "\n",
+			want: []listEntry{
+				{prefix: netip.MustParsePrefix("1.10.16.0/20"), reference: "SBL256894"},
+				{prefix: netip.MustParsePrefix("2a06:e480::/29"), reference: "SBL301771"},
+			},
+			wantUpdated: time.Unix(1704236103, 0),
+		},
+		{
+			name: "netset",
+			list: "#\n# firehol_level1\n#\n192.0.2.0/24\n198.51.100.7 # single address\n::ffff:203.0.113.0/120\n",
+			want: []listEntry{
+				{prefix: netip.MustParsePrefix("192.0.2.0/24")},
+				{prefix: netip.MustParsePrefix("198.51.100.7/32")},
+				{prefix: netip.MustParsePrefix("203.0.113.0/24")},
+			},
+		},
+		{
+			name:        "invalid lines are skipped",
+			list:        "192.0.2.0/24\nnot-a-prefix\n{broken\n; \n",
+			want:        []listEntry{{prefix: netip.MustParsePrefix("192.0.2.0/24")}},
+			wantSkipped: 2,
+		},
+		{name: "empty", list: "; nothing listed\n"},
+		{name: "no prefixes", list: "<html>\n</html>\n", wantErr: true},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got, updated, skipped, err := parseList(strings.NewReader(tt.list))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, tt.wantUpdated.Equal(updated), "updated %s", updated)
+			assert.Equal(t, tt.wantSkipped, skipped)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package blocklist
+
+import (
+	"context"
+	"io"
+	"ip_service/internal/loader"
+	"ip_service/pkg/model"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+)
+
+// Service loads blocklists into patricia trees, each list in its own table
+type Service struct {
+	*loader.Loader[model.BlocklistSource, *listEntry]
+}
+
+var dataset = loader.Dataset[model.BlocklistSource, *listEntry]{
+	Kind:                     model.DatasetKindBlocklist,
+	Metrics:                  metrics.loader,
+	DefaultUpdatePeriodicity: 12 * time.Hour,
+	Config: func(cfg *model.Cfg) (time.Duration, []model.BlocklistSource) {
+		return cfg.IPService.Blocklists.UpdatePeriodicity, cfg.IPService.Blocklists.Sources
+	},
+	Source: func(source model.BlocklistSource) loader.Source {
+		return loader.Source{Name: source.Name, URL: source.URL, FilePath: source.FilePath, Token: source.Token}
+	},
+	Parse: parse,
+}
+
+// New creates a new blocklist service and loads the sources, a source that fails to load is retried on the next update
+func New(ctx context.Context, cfg *model.Cfg, log *logger.Log) (*Service, error) {
+	return &Service{Loader: loader.New(ctx, cfg, dataset, log)}, nil
+}
+
+// parse reads the entries of a list into t
+func parse(source model.BlocklistSource, r io.Reader, t *loader.Table[*listEntry]) error {
+	entries, updated, skipped, err := parseList(r)
+	if err != nil {
+		return err
+	}
+	t.Skipped = skipped
+
+	// the date in the list wins over the date of the file or response
+	if !updated.IsZero() {
+		t.Modified = updated
+	}
+	if t.Modified.IsZero() {
+		t.Modified = time.Now()
+	}
+
+	for i := range entries {
+		t.Set(entries[i].prefix, &entries[i])
+	}
+
+	metrics.updated.WithLabelValues(source.Name).Set(float64(t.Modified.Unix()))
+
+	return nil
+}
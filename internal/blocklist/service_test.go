@@ -0,0 +1,106 @@
+package blocklist
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupBlocklists(t *testing.T) {
+	dir := t.TempDir()
+	internal := filepath.Join(dir, "internal.netset")
+	require.NoError(t, os.WriteFile(internal, []byte("192.0.2.0/24\n192.0.2.128/25\n2001:db8::/32\n"), 0600))
+	fileModified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(internal, fileModified, fileModified))
+
+	lastModified := time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/drop.txt":
+			w.Write([]byte("; Spamhaus DROP List\n; Last-Modified: Tue, 02 Jan 2024 22:55:03 GMT\n192.0.2.0/23 ; SBL1\n"))
+		case "/edrop.txt":
+			w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+			w.Write([]byte("198.51.100.0/24 ; SBL2\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			Blocklists: model.Blocklists{
+				Sources: []model.BlocklistSource{
+					{Name: "drop", URL: server.URL + "/drop.txt"},
+					{Name: "edrop", URL: server.URL + "/edrop.txt"},
+					{Name: "internal", FilePath: internal},
+					{Name: "missing", URL: server.URL + "/missing.netset"},
+				},
+			},
+		},
+	}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	assert.Equal(t, []string{"drop", "edrop", "internal"}, s.Lists())
+
+	got := s.LookupBlocklists(context.TODO(), net.ParseIP("192.0.2.200"))
+	require.Len(t, got, 2)
+	assert.Equal(t, "drop", got[0].List)
+	assert.Equal(t, "192.0.2.0/23", got[0].Prefix)
+	assert.Equal(t, "SBL1", got[0].Reference)
+	assert.True(t, time.Date(2024, 1, 2, 22, 55, 3, 0, time.UTC).Equal(got[0].ListUpdated))
+	assert.Greater(t, got[0].ListAge, int64(0))
+	assert.Equal(t, "internal", got[1].List)
+	assert.Equal(t, "192.0.2.128/25", got[1].Prefix)
+	assert.Empty(t, got[1].Reference)
+	assert.True(t, fileModified.Equal(got[1].ListUpdated))
+
+	got = s.LookupBlocklists(context.TODO(), net.ParseIP("::ffff:198.51.100.1"))
+	require.Len(t, got, 1)
+	assert.Equal(t, "edrop", got[0].List)
+	assert.True(t, lastModified.Equal(got[0].ListUpdated))
+
+	got = s.LookupBlocklists(context.TODO(), net.ParseIP("2001:db8::1"))
+	require.Len(t, got, 1)
+	assert.Equal(t, "2001:db8::/32", got[0].Prefix)
+
+	assert.Empty(t, s.LookupBlocklists(context.TODO(), net.ParseIP("203.0.113.1")))
+}
+
+func TestUpdateKeepsPreviousList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drop.txt")
+	require.NoError(t, os.WriteFile(path, []byte("192.0.2.0/24 ; SBL1\n"), 0600))
+
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			Blocklists: model.Blocklists{
+				Sources: []model.BlocklistSource{{Name: "drop", FilePath: path}},
+			},
+		},
+	}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	reloads := 0
+	s.OnReload(func(ctx context.Context) { reloads++ })
+
+	require.NoError(t, os.Remove(path))
+	s.Update(context.TODO())
+	assert.Equal(t, 0, reloads)
+	assert.Len(t, s.LookupBlocklists(context.TODO(), net.ParseIP("192.0.2.1")), 1)
+}
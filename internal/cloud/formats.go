@@ -0,0 +1,253 @@
+package cloud
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ip_service/pkg/model"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// rangeEntry is a published prefix and its service and region
+type rangeEntry struct {
+	prefix  netip.Prefix
+	service string
+	region  string
+}
+
+// parsers read the range files of each provider
+var parsers = map[string]func(io.Reader) ([]rangeEntry, error){
+	model.CloudProviderAWS:        parseAWS,
+	model.CloudProviderGCP:        parseGCP,
+	model.CloudProviderAzure:      parseAzure,
+	model.CloudProviderCloudflare: parseCloudflare,
+	model.CloudProviderFastly:     parseFastly,
+	model.CloudProviderOracle:     parseOracle,
+}
+
+// parse reads a range file in the format of provider, a prefix published more than once keeps its most detailed entry
+func parse(provider string, r io.Reader) ([]rangeEntry, error) {
+	parser, ok := parsers[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", provider)
+	}
+
+	entries, err := parser(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no prefix in the %s range file", provider)
+	}
+
+	index := map[netip.Prefix]int{}
+	deduplicated := make([]rangeEntry, 0, len(entries))
+	for _, entry := range entries {
+		i, ok := index[entry.prefix]
+		if !ok {
+			index[entry.prefix] = len(deduplicated)
+			deduplicated = append(deduplicated, entry)
+			continue
+		}
+		if entry.detail() > deduplicated[i].detail() {
+			deduplicated[i] = entry
+		}
+	}
+
+	return deduplicated, nil
+}
+
+// detail is the number of fields set, AWS' AMAZON and Azure's AzureCloud cover the prefixes of their services
+func (e rangeEntry) detail() int {
+	detail := 0
+	if e.service != "" && e.service != "AMAZON" && e.service != "AzureCloud" {
+		detail++
+	}
+	if e.region != "" && e.region != "GLOBAL" && e.region != "global" {
+		detail++
+	}
+	return detail
+}
+
+// newEntry returns the entry of a published prefix, an invalid prefix fails the whole file
+func newEntry(s, service, region string) (rangeEntry, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(s))
+	if err != nil {
+		return rangeEntry{}, err
+	}
+	return rangeEntry{prefix: prefix.Masked(), service: service, region: region}, nil
+}
+
+// parseAWS reads ip-ranges.json, https://ip-ranges.amazonaws.com/ip-ranges.json
+func parseAWS(r io.Reader) ([]rangeEntry, error) {
+	var file struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+			Region   string `json:"region"`
+			Service  string `json:"service"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6Prefix string `json:"ipv6_prefix"`
+			Region     string `json:"region"`
+			Service    string `json:"service"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	entries := make([]rangeEntry, 0, len(file.Prefixes)+len(file.IPv6Prefixes))
+	for _, p := range file.Prefixes {
+		entry, err := newEntry(p.IPPrefix, p.Service, p.Region)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	for _, p := range file.IPv6Prefixes {
+		entry, err := newEntry(p.IPv6Prefix, p.Service, p.Region)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// parseGCP reads cloud.json, https://www.gstatic.com/ipranges/cloud.json, where the scope is the region
+func parseGCP(r io.Reader) ([]rangeEntry, error) {
+	var file struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+			Service    string `json:"service"`
+			Scope      string `json:"scope"`
+		} `json:"prefixes"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	entries := make([]rangeEntry, 0, len(file.Prefixes))
+	for _, p := range file.Prefixes {
+		entry, err := newEntry(p.IPv4Prefix+p.IPv6Prefix, p.Service, p.Scope)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// parseAzure reads the weekly ServiceTags_Public json, the service is the system service or the tag name before its region
+func parseAzure(r io.Reader) ([]rangeEntry, error) {
+	var file struct {
+		Values []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				Region          string   `json:"region"`
+				SystemService   string   `json:"systemService"`
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	var entries []rangeEntry
+	for _, tag := range file.Values {
+		service := tag.Properties.SystemService
+		if service == "" {
+			service, _, _ = strings.Cut(tag.Name, ".")
+		}
+		for _, p := range tag.Properties.AddressPrefixes {
+			entry, err := newEntry(p, service, tag.Properties.Region)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// parseCloudflare reads https://www.cloudflare.com/ips-v4 and ips-v6, a prefix per line
+func parseCloudflare(r io.Reader) ([]rangeEntry, error) {
+	var entries []rangeEntry
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, err := newEntry(line, "", "")
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// parseFastly reads https://api.fastly.com/public-ip-list
+func parseFastly(r io.Reader) ([]rangeEntry, error) {
+	var file struct {
+		Addresses     []string `json:"addresses"`
+		IPv6Addresses []string `json:"ipv6_addresses"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	entries := make([]rangeEntry, 0, len(file.Addresses)+len(file.IPv6Addresses))
+	for _, p := range slices.Concat(file.Addresses, file.IPv6Addresses) {
+		entry, err := newEntry(p, "", "")
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// parseOracle reads https://docs.oracle.com/en-us/iaas/tools/public_ip_ranges.json, the tags of a prefix are its services
+func parseOracle(r io.Reader) ([]rangeEntry, error) {
+	var file struct {
+		Regions []struct {
+			Region string `json:"region"`
+			CIDRs  []struct {
+				CIDR string   `json:"cidr"`
+				Tags []string `json:"tags"`
+			} `json:"cidrs"`
+		} `json:"regions"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	var entries []rangeEntry
+	for _, region := range file.Regions {
+		for _, cidr := range region.CIDRs {
+			entry, err := newEntry(cidr.CIDR, strings.Join(cidr.Tags, ","), region.Region)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
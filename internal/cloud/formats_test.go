@@ -0,0 +1,134 @@
+package cloud
+
+import (
+	"ip_service/pkg/model"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	awsRanges = `{
+  "syncToken": "1704236103",
+  "createDate": "2024-01-02-22-55-03",
+  "prefixes": [
+    {"ip_prefix": "3.5.140.0/22", "region": "ap-northeast-2", "service": "AMAZON", "network_border_group": "ap-northeast-2"},
+    {"ip_prefix": "3.5.140.0/22", "region": "ap-northeast-2", "service": "S3", "network_border_group": "ap-northeast-2"},
+    {"ip_prefix": "13.34.37.64/27", "region": "ap-southeast-4", "service": "AMAZON", "network_border_group": "ap-southeast-4"}
+  ],
+  "ipv6_prefixes": [
+    {"ipv6_prefix": "2600:1f14::/35", "region": "us-west-2", "service": "EC2", "network_border_group": "us-west-2"},
+    {"ipv6_prefix": "2600:1f14::/35", "region": "us-west-2", "service": "AMAZON", "network_border_group": "us-west-2"}
+  ]
+}`
+
+	gcpRanges = `{
+  "syncToken": "1704236103",
+  "creationTime": "2024-01-02T22:55:03.000000",
+  "prefixes": [
+    {"ipv4Prefix": "34.1.208.0/20", "service": "Google Cloud", "scope": "africa-south1"},
+    {"ipv6Prefix": "2600:1900:8000::/44", "service": "Google Cloud", "scope": "us-central1"}
+  ]
+}`
+
+	azureRanges = `{
+  "changeNumber": 274,
+  "cloud": "Public",
+  "values": [
+    {"name": "AzureCloud", "id": "AzureCloud", "properties": {"changeNumber": 1, "region": "", "platform": "Azure", "systemService": "", "addressPrefixes": ["13.64.0.0/11", "20.38.0.0/22"]}},
+    {"name": "AzureCloud.eastus", "id": "AzureCloud.eastus", "properties": {"changeNumber": 1, "region": "eastus", "platform": "Azure", "systemService": "", "addressPrefixes": ["20.38.0.0/22"]}},
+    {"name": "Storage.EastUS", "id": "Storage.EastUS", "properties": {"changeNumber": 1, "region": "eastus", "platform": "Azure", "systemService": "AzureStorage", "addressPrefixes": ["20.38.0.0/22", "2603:1030:20e::/48"]}}
+  ]
+}`
+
+	oracleRanges = `{
+  "last_updated_timestamp": "2024-01-02T22:55:03.000000",
+  "regions": [
+    {"region": "us-phoenix-1", "cidrs": [{"cidr": "129.146.0.0/21", "tags": ["OCI"]}, {"cidr": "134.70.24.0/21", "tags": ["OBJECT_STORAGE", "OSN"]}]}
+  ]
+}`
+)
+
+func TestParse(t *testing.T) {
+	entry := func(prefix, service, region string) rangeEntry {
+		return rangeEntry{prefix: netip.MustParsePrefix(prefix), service: service, region: region}
+	}
+
+	tts := []struct {
+		name     string
+		provider string
+		file     string
+		want     []rangeEntry
+		wantErr  bool
+	}{
+		{
+			name:     "aws services win over AMAZON",
+			provider: model.CloudProviderAWS,
+			file:     awsRanges,
+			want: []rangeEntry{
+				entry("3.5.140.0/22", "S3", "ap-northeast-2"),
+				entry("13.34.37.64/27", "AMAZON", "ap-southeast-4"),
+				entry("2600:1f14::/35", "EC2", "us-west-2"),
+			},
+		},
+		{
+			name:     "gcp",
+			provider: model.CloudProviderGCP,
+			file:     gcpRanges,
+			want: []rangeEntry{
+				entry("34.1.208.0/20", "Google Cloud", "africa-south1"),
+				entry("2600:1900:8000::/44", "Google Cloud", "us-central1"),
+			},
+		},
+		{
+			name:     "azure regional service tags win",
+			provider: model.CloudProviderAzure,
+			file:     azureRanges,
+			want: []rangeEntry{
+				entry("13.64.0.0/11", "AzureCloud", ""),
+				entry("20.38.0.0/22", "AzureStorage", "eastus"),
+				entry("2603:1030:20e::/48", "AzureStorage", "eastus"),
+			},
+		},
+		{
+			name:     "cloudflare",
+			provider: model.CloudProviderCloudflare,
+			file:     "173.245.48.0/20\n2400:cb00::/32\n",
+			want:     []rangeEntry{entry("173.245.48.0/20", "", ""), entry("2400:cb00::/32", "", "")},
+		},
+		{
+			name:     "fastly",
+			provider: model.CloudProviderFastly,
+			file:     `{"addresses": ["23.235.32.0/20"], "ipv6_addresses": ["2a04:4e40::/32"]}`,
+			want:     []rangeEntry{entry("23.235.32.0/20", "", ""), entry("2a04:4e40::/32", "", "")},
+		},
+		{
+			name:     "oracle",
+			provider: model.CloudProviderOracle,
+			file:     oracleRanges,
+			want: []rangeEntry{
+				entry("129.146.0.0/21", "OCI", "us-phoenix-1"),
+				entry("134.70.24.0/21", "OBJECT_STORAGE,OSN", "us-phoenix-1"),
+			},
+		},
+		{name: "invalid prefix", provider: model.CloudProviderCloudflare, file: "173.245.48.0/33\n", wantErr: true},
+		{name: "invalid json", provider: model.CloudProviderAWS, file: "<html>", wantErr: true},
+		{name: "no prefixes", provider: model.CloudProviderFastly, file: `{"addresses": []}`, wantErr: true},
+		{name: "unknown provider", provider: "example", file: "173.245.48.0/20\n", wantErr: true},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(tt.provider, strings.NewReader(tt.file))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package cloud
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+)
+
+// LookupCloud returns the most specific range holding ip over all sources, the first source wins on equal prefixes
+func (s *Service) LookupCloud(ctx context.Context, ip net.IP) *model.CloudRange {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	var (
+		best     *model.CloudRange
+		bestBits = -1
+	)
+	for _, t := range s.Tables() {
+		r, ok := t.Lookup(addr)
+		if ok && r.bits > bestBits {
+			best, bestBits = r.record, r.bits
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,6 @@
+package cloud
+
+import "ip_service/internal/loader"
+
+// metrics holds the prometheus metrics for cloud ranges, labeled by source name
+var metrics = loader.NewMetrics("cloud", "source", "cloud range file")
@@ -0,0 +1,62 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"ip_service/internal/loader"
+	"ip_service/pkg/model"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+)
+
+// Service loads the range files of cloud and CDN providers into patricia trees, each source in its own table
+type Service struct {
+	*loader.Loader[model.CloudSource, *rangeRecord]
+}
+
+// rangeRecord is the reply section of a range, with its prefix length to compare the matches of sources
+type rangeRecord struct {
+	bits   int
+	record *model.CloudRange
+}
+
+var dataset = loader.Dataset[model.CloudSource, *rangeRecord]{
+	Kind:                     model.DatasetKindCloud,
+	Metrics:                  metrics,
+	DefaultUpdatePeriodicity: 24 * time.Hour,
+	Config: func(cfg *model.Cfg) (time.Duration, []model.CloudSource) {
+		return cfg.IPService.Cloud.UpdatePeriodicity, cfg.IPService.Cloud.Sources
+	},
+	Source: func(source model.CloudSource) loader.Source {
+		return loader.Source{Name: source.Name, URL: source.URL, FilePath: source.FilePath, Token: source.Token}
+	},
+	Parse: parseSource,
+}
+
+// New creates a new cloud service and loads the sources, a source that fails to load is retried on the next update
+func New(ctx context.Context, cfg *model.Cfg, log *logger.Log) (*Service, error) {
+	return &Service{Loader: loader.New(ctx, cfg, dataset, log)}, nil
+}
+
+// parseSource reads the range file of a source into t, in the format of its provider
+func parseSource(source model.CloudSource, r io.Reader, t *loader.Table[*rangeRecord]) error {
+	entries, err := parse(source.Provider, r)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		t.Set(entry.prefix, &rangeRecord{
+			bits: entry.prefix.Bits(),
+			record: &model.CloudRange{
+				Provider: source.Provider,
+				Service:  entry.service,
+				Region:   entry.region,
+				Prefix:   entry.prefix.String(),
+			},
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package cloud
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCloud(t *testing.T) {
+	dir := t.TempDir()
+	azure := filepath.Join(dir, "ServiceTags_Public_20240101.json")
+	require.NoError(t, os.WriteFile(azure, []byte(azureRanges), 0600))
+
+	gz := &bytes.Buffer{}
+	gzw := gzip.NewWriter(gz)
+	_, err := gzw.Write([]byte(awsRanges))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ip-ranges.json.gz":
+			w.Write(gz.Bytes())
+		case "/ips-v4":
+			// a CDN range holding a more specific aws range
+			w.Write([]byte("3.5.0.0/16\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			Cloud: model.Cloud{
+				Sources: []model.CloudSource{
+					{Name: "cloudflare-v4", Provider: model.CloudProviderCloudflare, URL: server.URL + "/ips-v4"},
+					{Name: "aws", Provider: model.CloudProviderAWS, URL: server.URL + "/ip-ranges.json.gz"},
+					{Name: "azure", Provider: model.CloudProviderAzure, FilePath: azure},
+					{Name: "gcp", Provider: model.CloudProviderGCP, URL: server.URL + "/missing.json"},
+				},
+			},
+		},
+	}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	tts := []struct {
+		ip   string
+		want *model.CloudRange
+	}{
+		{ip: "3.5.140.1", want: &model.CloudRange{Provider: "aws", Service: "S3", Region: "ap-northeast-2", Prefix: "3.5.140.0/22"}},
+		{ip: "3.5.1.1", want: &model.CloudRange{Provider: "cloudflare", Prefix: "3.5.0.0/16"}},
+		{ip: "::ffff:20.38.1.1", want: &model.CloudRange{Provider: "azure", Service: "AzureStorage", Region: "eastus", Prefix: "20.38.0.0/22"}},
+		{ip: "13.64.0.1", want: &model.CloudRange{Provider: "azure", Service: "AzureCloud", Prefix: "13.64.0.0/11"}},
+		{ip: "2600:1f14::1", want: &model.CloudRange{Provider: "aws", Service: "EC2", Region: "us-west-2", Prefix: "2600:1f14::/35"}},
+		{ip: "130.242.1.1"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.LookupCloud(context.TODO(), net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestUpdateKeepsPreviousTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ips-v4")
+	require.NoError(t, os.WriteFile(path, []byte("173.245.48.0/20\n"), 0600))
+
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			Cloud: model.Cloud{
+				Sources: []model.CloudSource{{Name: "cloudflare", Provider: model.CloudProviderCloudflare, FilePath: path}},
+			},
+		},
+	}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	reloads := 0
+	s.OnReload(func(ctx context.Context) { reloads++ })
+
+	require.NoError(t, os.WriteFile(path, []byte("not a prefix\n"), 0600))
+	s.Update(context.TODO())
+	assert.Equal(t, 0, reloads)
+	assert.NotNil(t, s.LookupCloud(context.TODO(), net.ParseIP("173.245.48.1")))
+}
@@ -0,0 +1,40 @@
+package feeds
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+)
+
+// LookupFeeds returns the flags of the feeds listing ip, nil when none does
+func (s *Service) LookupFeeds(ctx context.Context, ip net.IP) *model.FeedMatch {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	var match *model.FeedMatch
+	for _, t := range s.Tables() {
+		kind, ok := t.Lookup(addr)
+		if !ok {
+			continue
+		}
+		if match == nil {
+			match = &model.FeedMatch{}
+		}
+
+		switch kind {
+		case model.FeedKindTorExit:
+			match.IsTorExit = true
+		case model.FeedKindVPN:
+			match.IsVPN = true
+		case model.FeedKindRelay:
+			match.IsRelay = true
+		}
+		match.Feeds = append(match.Feeds, t.Name)
+	}
+
+	return match
+}
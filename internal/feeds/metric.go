@@ -0,0 +1,6 @@
+package feeds
+
+import "ip_service/internal/loader"
+
+// metrics holds the prometheus metrics for feeds, labeled by feed name
+var metrics = loader.NewMetrics("feed", "feed", "feed")
@@ -0,0 +1,75 @@
+package feeds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+)
+
+// parseList reads the addresses and prefixes of a feed, one per line as the first comma or space separated field.
+// Comments, empty lines and lines without an address, like the headers of csv files, are skipped,
+// but a feed without any address in its lines is rejected, e.g. an html error page.
+func parseList(r io.Reader) ([]netip.Prefix, int, error) {
+	var (
+		prefixes []netip.Prefix
+		skipped  int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) == 0 {
+			skipped++
+			continue
+		}
+		// Tor's exit-addresses has "ExitAddress <ip> <date> <time>" lines between the relay descriptors
+		if len(fields) > 1 && fields[0] == "ExitAddress" {
+			fields = fields[1:]
+		}
+
+		prefix, ok := parsePrefix(fields[0])
+		if !ok {
+			skipped++
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(prefixes) == 0 && skipped > 0 {
+		return nil, skipped, fmt.Errorf("no address in %d lines", skipped)
+	}
+
+	return prefixes, skipped, nil
+}
+
+// parsePrefix parses a prefix or an address as its host prefix, IPv4-mapped addresses as IPv4
+func parsePrefix(s string) (netip.Prefix, bool) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, false
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), true
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
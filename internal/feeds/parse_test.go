@@ -0,0 +1,82 @@
+package feeds
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseList(t *testing.T) {
+	tts := []struct {
+		name        string
+		feed        string
+		want        []string
+		wantSkipped int
+		wantErr     bool
+	}{
+		{
+			name: "tor bulk exit list",
+			feed: "192.0.2.1\n198.51.100.7\n2001:db8::1\n",
+			want: []string{"192.0.2.1/32", "198.51.100.7/32", "2001:db8::1/128"},
+		},
+		{
+			name: "tor exit-addresses",
+			feed: "ExitNode 0011BD2485AD45D984EC4159C88FC066E5E3300E\nPublished 2024-01-01 00:00:00\nLastStatus 2024-01-01 01:00:00\nExitAddress 192.0.2.1 2024-01-01 01:02:03\n",
+			want: []string{"192.0.2.1/32"},
+			// the ExitNode, Published and LastStatus lines
+			wantSkipped: 3,
+		},
+		{
+			name: "icloud private relay egress ranges",
+			feed: "172.224.224.0/27,GB,GB-EN,London,\n2a02:26f7:b3c0:4000::/64,SE,SE-AB,Stockholm,\n",
+			want: []string{"172.224.224.0/27", "2a02:26f7:b3c0:4000::/64"},
+		},
+		{
+			name:        "vpn ranges with comments and a header",
+			feed:        "# corporate vpn\nprefix description\n192.0.2.0/24 office\n::ffff:198.51.100.0/120\n\n203.0.113.5/24\n",
+			want:        []string{"192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24"},
+			wantSkipped: 1,
+		},
+		{
+			name:        "separator only rows",
+			feed:        "prefix,country\n,,,\n192.0.2.0/24,SE\n , \t,\n",
+			want:        []string{"192.0.2.0/24"},
+			wantSkipped: 3,
+		},
+		{name: "empty", feed: "# nothing yet\n"},
+		{name: "no addresses", feed: "<html>\n<body>error</body>\n</html>\n", wantErr: true},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped, err := parseList(strings.NewReader(tt.feed))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			prefixes := []string{}
+			for _, prefix := range got {
+				prefixes = append(prefixes, prefix.String())
+			}
+			if tt.want == nil {
+				tt.want = []string{}
+			}
+			assert.Equal(t, tt.want, prefixes)
+			assert.Equal(t, tt.wantSkipped, skipped)
+		})
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	got, ok := parsePrefix("::ffff:192.0.2.1")
+	require.True(t, ok)
+	assert.Equal(t, netip.MustParsePrefix("192.0.2.1/32"), got)
+
+	_, ok = parsePrefix("192.0.2.0/33")
+	assert.False(t, ok)
+}
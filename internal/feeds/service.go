@@ -0,0 +1,49 @@
+package feeds
+
+import (
+	"context"
+	"io"
+	"ip_service/internal/loader"
+	"ip_service/pkg/model"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+)
+
+// Service loads the anonymity and proxy feeds into prefix sets, each feed in its own table of its prefixes and kind
+type Service struct {
+	*loader.Loader[model.FeedSource, string]
+}
+
+var dataset = loader.Dataset[model.FeedSource, string]{
+	Kind:                     model.DatasetKindFeeds,
+	Metrics:                  metrics,
+	DefaultUpdatePeriodicity: time.Hour,
+	Config: func(cfg *model.Cfg) (time.Duration, []model.FeedSource) {
+		return cfg.IPService.Feeds.UpdatePeriodicity, cfg.IPService.Feeds.Sources
+	},
+	Source: func(source model.FeedSource) loader.Source {
+		return loader.Source{Name: source.Name, URL: source.URL, FilePath: source.FilePath, Token: source.Token}
+	},
+	Parse: parse,
+}
+
+// New creates a new feeds service and loads the sources, a source that fails to load is retried on the next update
+func New(ctx context.Context, cfg *model.Cfg, log *logger.Log) (*Service, error) {
+	return &Service{Loader: loader.New(ctx, cfg, dataset, log)}, nil
+}
+
+// parse reads the addresses and prefixes of a feed into t, tagged with the kind of the feed
+func parse(source model.FeedSource, r io.Reader, t *loader.Table[string]) error {
+	prefixes, skipped, err := parseList(r)
+	if err != nil {
+		return err
+	}
+	t.Skipped = skipped
+
+	for _, prefix := range prefixes {
+		t.Set(prefix, source.Kind)
+	}
+
+	return nil
+}
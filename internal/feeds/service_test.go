@@ -0,0 +1,112 @@
+package feeds
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupFeeds(t *testing.T) {
+	dir := t.TempDir()
+	vpn := filepath.Join(dir, "vpn.txt")
+	require.NoError(t, os.WriteFile(vpn, []byte("192.0.2.0/24\n2001:db8::/32\n"), 0600))
+
+	gz := &bytes.Buffer{}
+	gzw := gzip.NewWriter(gz)
+	_, err := gzw.Write([]byte("198.51.100.0/28,SE,SE-AB,Stockholm,\n192.0.2.128/25,GB,GB-EN,London,\n"))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/torbulkexitlist":
+			w.Write([]byte("198.51.100.7\n203.0.113.9\n"))
+		case "/egress-ip-ranges.csv.gz":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write(gz.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			Feeds: model.Feeds{
+				Sources: []model.FeedSource{
+					{Name: "tor", Kind: model.FeedKindTorExit, URL: server.URL + "/torbulkexitlist"},
+					{Name: "icloud", Kind: model.FeedKindRelay, URL: server.URL + "/egress-ip-ranges.csv.gz", Token: "secret"},
+					{Name: "corporate", Kind: model.FeedKindVPN, FilePath: vpn},
+					{Name: "missing", Kind: model.FeedKindVPN, URL: server.URL + "/missing"},
+				},
+			},
+		},
+	}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	tts := []struct {
+		ip   string
+		want *model.FeedMatch
+	}{
+		{ip: "192.0.2.1", want: &model.FeedMatch{IsVPN: true, Feeds: []string{"corporate"}}},
+		{ip: "192.0.2.200", want: &model.FeedMatch{IsVPN: true, IsRelay: true, Feeds: []string{"icloud", "corporate"}}},
+		{ip: "198.51.100.7", want: &model.FeedMatch{IsTorExit: true, IsRelay: true, Feeds: []string{"tor", "icloud"}}},
+		{ip: "::ffff:203.0.113.9", want: &model.FeedMatch{IsTorExit: true, Feeds: []string{"tor"}}},
+		{ip: "2001:db8::1", want: &model.FeedMatch{IsVPN: true, Feeds: []string{"corporate"}}},
+		{ip: "203.0.113.10"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.LookupFeeds(context.TODO(), net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestUpdateKeepsPreviousFeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tor.txt")
+	require.NoError(t, os.WriteFile(path, []byte("192.0.2.1\n"), 0600))
+
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			Feeds: model.Feeds{
+				Sources: []model.FeedSource{{Name: "tor", Kind: model.FeedKindTorExit, FilePath: path}},
+			},
+		},
+	}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	reloads := 0
+	s.OnReload(func(ctx context.Context) { reloads++ })
+
+	require.NoError(t, os.WriteFile(path, []byte("<html>rate limited</html>\n"), 0600))
+	s.Update(context.TODO())
+	assert.Equal(t, 0, reloads)
+	assert.True(t, s.LookupFeeds(context.TODO(), net.ParseIP("192.0.2.1")).IsTorExit)
+
+	// a successful update replaces the previous addresses
+	require.NoError(t, os.WriteFile(path, []byte("192.0.2.2\n"), 0600))
+	s.Update(context.TODO())
+	assert.Nil(t, s.LookupFeeds(context.TODO(), net.ParseIP("192.0.2.1")))
+	assert.True(t, s.LookupFeeds(context.TODO(), net.ParseIP("192.0.2.2")).IsTorExit)
+}
@@ -0,0 +1,190 @@
+package georange
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"ip_service/pkg/model"
+	"math/big"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// geoRange is a row of a range table, the record is shared by the prefixes of the range
+type geoRange struct {
+	start  netip.Addr
+	end    netip.Addr
+	record *model.GeoRecord
+}
+
+// columns of a range table row
+const (
+	colStartIP = iota
+	colEndIP
+	colCountry
+	colRegion
+	colCity
+	colLatitude
+	colLongitude
+	numColumns
+)
+
+// parseCSV reads the range rows of r, a header row and invalid rows are skipped.
+// It returns the number of skipped rows, and an error if there are no valid rows.
+func parseCSV(r io.Reader, provider string) ([]geoRange, int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	reader.Comment = '#'
+
+	var (
+		ranges  []geoRange
+		skipped int
+	)
+
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, skipped, err
+		}
+
+		geoRange, err := parseRow(row, provider)
+		if err != nil {
+			// the first row is usually a header
+			if line > 1 {
+				skipped++
+			}
+			continue
+		}
+		ranges = append(ranges, geoRange)
+	}
+
+	if len(ranges) == 0 {
+		return nil, skipped, fmt.Errorf("%s has no valid ranges", provider)
+	}
+
+	return ranges, skipped, nil
+}
+
+func parseRow(row []string, provider string) (geoRange, error) {
+	if len(row) < numColumns {
+		return geoRange{}, fmt.Errorf("%d columns, want %d", len(row), numColumns)
+	}
+
+	bits := decimalBits(row[colStartIP], row[colEndIP])
+	start, err := parseAddr(row[colStartIP], bits)
+	if err != nil {
+		return geoRange{}, err
+	}
+	end, err := parseAddr(row[colEndIP], bits)
+	if err != nil {
+		return geoRange{}, err
+	}
+	if start.Is4() != end.Is4() || end.Less(start) {
+		return geoRange{}, fmt.Errorf("invalid range %s-%s", start, end)
+	}
+
+	record := &model.GeoRecord{
+		City:     englishName(row[colCity]),
+		Region:   englishName(row[colRegion]),
+		Provider: provider,
+	}
+
+	// the country column holds an ISO code in most feeds, and a name in some
+	if country := strings.TrimSpace(row[colCountry]); len(country) == 2 {
+		record.CountryISO = strings.ToUpper(country)
+		record.IsEU = model.IsEUCountry(country)
+	} else {
+		record.Country = englishName(country)
+	}
+
+	latitude, latErr := strconv.ParseFloat(strings.TrimSpace(row[colLatitude]), 64)
+	longitude, lonErr := strconv.ParseFloat(strings.TrimSpace(row[colLongitude]), 64)
+	if latErr == nil && lonErr == nil {
+		record.Coordinates = &model.Coordinates{Latitude: latitude, Longitude: longitude}
+	}
+
+	return geoRange{start: start, end: end, record: record}, nil
+}
+
+// decimalBits returns the address length of a range given as decimal integers, 128 when either end needs more than
+// 32 bits. The ends of a range are one family, so the low rows of IP2Location's IPv6 csv files, starting at 0 for
+// ::/96, are not read as IPv4.
+func decimalBits(start, end string) int {
+	for _, s := range []string{start, end} {
+		if n, ok := new(big.Int).SetString(strings.TrimSpace(s), 10); ok && n.BitLen() > 32 {
+			return 128
+		}
+	}
+	return 32
+}
+
+// parseAddr parses an IP address, or an address as a decimal integer like IP2Location's csv files, of bits 32 or 128
+func parseAddr(s string, bits int) (netip.Addr, error) {
+	s = strings.TrimSpace(s)
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap(), nil
+	}
+
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 || n.BitLen() > bits {
+		return netip.Addr{}, fmt.Errorf("invalid address %q", s)
+	}
+
+	if bits == 32 {
+		var b [4]byte
+		n.FillBytes(b[:])
+		return netip.AddrFrom4(b), nil
+	}
+	var b [16]byte
+	n.FillBytes(b[:])
+	return netip.AddrFrom16(b).Unmap(), nil
+}
+
+func englishName(name string) map[string]string {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "-" {
+		return nil
+	}
+	return map[string]string{"en": name}
+}
+
+// rangeToPrefixes returns the smallest set of prefixes covering start to end, both included
+func rangeToPrefixes(start, end netip.Addr) []netip.Prefix {
+	var prefixes []netip.Prefix
+
+	for start.IsValid() && !end.Less(start) {
+		// the largest prefix that starts at start and ends before end
+		bits := start.BitLen()
+		for bits > 0 {
+			prefix := netip.PrefixFrom(start, bits-1).Masked()
+			if prefix.Addr() != start || end.Less(lastAddr(prefix)) {
+				break
+			}
+			bits--
+		}
+
+		prefix := netip.PrefixFrom(start, bits)
+		prefixes = append(prefixes, prefix)
+
+		// Next is invalid after the last address, which ends the loop
+		start = lastAddr(prefix).Next()
+	}
+
+	return prefixes
+}
+
+// lastAddr returns the last address of prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
@@ -0,0 +1,142 @@
+package georange
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeToPrefixes(t *testing.T) {
+	tts := []struct {
+		name  string
+		start string
+		end   string
+		want  []string
+	}{
+		{
+			name:  "single address",
+			start: "192.0.2.1",
+			end:   "192.0.2.1",
+			want:  []string{"192.0.2.1/32"},
+		},
+		{
+			name:  "aligned prefix",
+			start: "192.0.2.0",
+			end:   "192.0.2.255",
+			want:  []string{"192.0.2.0/24"},
+		},
+		{
+			name:  "unaligned range",
+			start: "192.0.2.1",
+			end:   "192.0.2.6",
+			want:  []string{"192.0.2.1/32", "192.0.2.2/31", "192.0.2.4/31", "192.0.2.6/32"},
+		},
+		{
+			name:  "whole ipv4 space",
+			start: "0.0.0.0",
+			end:   "255.255.255.255",
+			want:  []string{"0.0.0.0/0"},
+		},
+		{
+			name:  "ends at the last address",
+			start: "255.255.255.254",
+			end:   "255.255.255.255",
+			want:  []string{"255.255.255.254/31"},
+		},
+		{
+			name:  "ipv6",
+			start: "2001:db8::",
+			end:   "2001:db8:0:1:ffff:ffff:ffff:ffff",
+			want:  []string{"2001:db8::/63"},
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, prefix := range rangeToPrefixes(netip.MustParseAddr(tt.start), netip.MustParseAddr(tt.end)) {
+				got = append(got, prefix.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	input := strings.Join([]string{
+		"start_ip,end_ip,country,region,city,latitude,longitude",
+		"192.0.2.0,192.0.2.255,se,Uppsala County,Uppsala,59.8586,17.6389",
+		"3325256704,3325256959,Norway,,Oslo,,",
+		"# comment",
+		"198.51.100.9,198.51.100.1,SE,,,,",
+		"not an ip,192.0.2.1,SE,,,,",
+		"2001:db8::,2001:db8::ffff,FI,-,-,x,y",
+		// the first row of an IP2Location IPv6 csv, ::/96 and the rest up to ::ffff:0:0
+		"0,281470681743359,-,-,-,0,0",
+		"192.0.2.1,192.0.2.2",
+	}, "\n")
+
+	got, skipped, err := parseCSV(strings.NewReader(input), "test")
+	require.NoError(t, err)
+	assert.Equal(t, 3, skipped)
+	require.Len(t, got, 4)
+
+	assert.Equal(t, netip.MustParseAddr("192.0.2.0"), got[0].start)
+	assert.Equal(t, "SE", got[0].record.CountryISO)
+	assert.True(t, got[0].record.IsEU)
+	assert.Equal(t, map[string]string{"en": "Uppsala County"}, got[0].record.Region)
+	assert.Equal(t, 17.6389, got[0].record.Coordinates.Longitude)
+	assert.Equal(t, "test", got[0].record.Provider)
+
+	assert.Equal(t, netip.MustParseAddr("198.51.100.0"), got[1].start)
+	assert.Equal(t, netip.MustParseAddr("198.51.100.255"), got[1].end)
+	assert.Equal(t, map[string]string{"en": "Norway"}, got[1].record.Country)
+	assert.Empty(t, got[1].record.CountryISO)
+	assert.Nil(t, got[1].record.Coordinates)
+
+	assert.Nil(t, got[2].record.City)
+	assert.Nil(t, got[2].record.Coordinates)
+
+	assert.Equal(t, netip.MustParseAddr("::"), got[3].start)
+	assert.Equal(t, netip.MustParseAddr("::fffe:ffff:ffff"), got[3].end)
+
+	_, _, err = parseCSV(strings.NewReader("start_ip,end_ip\n"), "test")
+	assert.Error(t, err)
+}
+
+func TestParseAddr(t *testing.T) {
+	tts := []struct {
+		in      string
+		bits    int
+		want    string
+		wantErr bool
+	}{
+		{in: "192.0.2.1", bits: 32, want: "192.0.2.1"},
+		{in: "::ffff:192.0.2.1", bits: 32, want: "192.0.2.1"},
+		{in: "0", bits: 32, want: "0.0.0.0"},
+		{in: "0", bits: 128, want: "::"},
+		{in: "3221225985", bits: 32, want: "192.0.2.1"},
+		{in: "3221225985", bits: 128, want: "::c000:201"},
+		{in: "281473902969345", bits: 128, want: "192.0.2.1"},
+		{in: "281473902969345", bits: 32, wantErr: true},
+		{in: "42540766411282592856903984951653826560", bits: 128, want: "2001:db8::"},
+		{in: "-1", bits: 32, wantErr: true},
+		{in: "340282366920938463463374607431768211456", bits: 128, wantErr: true},
+	}
+
+	for _, tt := range tts {
+		t.Run(fmt.Sprintf("%s/%d", tt.in, tt.bits), func(t *testing.T) {
+			got, err := parseAddr(tt.in, tt.bits)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package georange
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+)
+
+// LookupGeo returns the record of the most specific range holding ip, sources are asked in configured order
+func (s *Service) LookupGeo(ctx context.Context, ip net.IP) (*model.GeoRecord, error) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return &model.GeoRecord{}, nil
+	}
+	addr = addr.Unmap()
+
+	for _, t := range s.Tables() {
+		if record, ok := t.Lookup(addr); ok {
+			return record, nil
+		}
+	}
+
+	return &model.GeoRecord{}, nil
+}
+
+// LookupASN returns an empty record, range tables have no origin AS
+func (s *Service) LookupASN(ctx context.Context, ip net.IP) (*model.ASNRecord, error) {
+	return &model.ASNRecord{}, nil
+}
@@ -0,0 +1,20 @@
+package georange
+
+import (
+	"ip_service/internal/loader"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the prometheus metrics for georange, labeled by source name
+var metrics = struct {
+	loader *loader.Metrics
+	ranges *prometheus.GaugeVec
+}{
+	loader: loader.NewMetrics("georange", "source", "range table"),
+	ranges: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_georange_ranges",
+		Help: "Valid ranges in the loaded range table",
+	}, []string{"source"}),
+}
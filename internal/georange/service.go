@@ -0,0 +1,54 @@
+package georange
+
+import (
+	"context"
+	"io"
+	"ip_service/internal/loader"
+	"ip_service/pkg/model"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+)
+
+// Service serves csv geolocation range tables as a geo provider, each source in its own table
+type Service struct {
+	*loader.Loader[model.GeoRangeSource, *model.GeoRecord]
+}
+
+var dataset = loader.Dataset[model.GeoRangeSource, *model.GeoRecord]{
+	Kind:                     model.DatasetKindGeoRange,
+	Metrics:                  metrics.loader,
+	DefaultUpdatePeriodicity: 24 * time.Hour,
+	Config: func(cfg *model.Cfg) (time.Duration, []model.GeoRangeSource) {
+		return cfg.IPService.GeoRange.UpdatePeriodicity, cfg.IPService.GeoRange.Sources
+	},
+	Source: func(source model.GeoRangeSource) loader.Source {
+		return loader.Source{Name: source.Name, URL: source.URL, FilePath: source.FilePath, Token: source.Token}
+	},
+	Parse: parse,
+}
+
+// New creates a new georange service and loads the sources, a source that fails to load is retried on the next update
+func New(ctx context.Context, cfg *model.Cfg, log *logger.Log) (*Service, error) {
+	return &Service{Loader: loader.New(ctx, cfg, dataset, log)}, nil
+}
+
+// parse reads the ranges of a source into t, split into prefixes
+func parse(source model.GeoRangeSource, r io.Reader, t *loader.Table[*model.GeoRecord]) error {
+	ranges, skipped, err := parseCSV(r, source.Name)
+	if err != nil {
+		return err
+	}
+	t.Skipped = skipped
+
+	for _, geoRange := range ranges {
+		// a later row replaces an earlier one for the same prefix
+		for _, prefix := range rangeToPrefixes(geoRange.start, geoRange.end) {
+			t.Set(prefix, geoRange.record)
+		}
+	}
+
+	metrics.ranges.WithLabelValues(source.Name).Set(float64(len(ranges)))
+
+	return nil
+}
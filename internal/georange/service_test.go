@@ -0,0 +1,109 @@
+package georange
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupGeo(t *testing.T) {
+	dir := t.TempDir()
+	wide := filepath.Join(dir, "wide.csv")
+	require.NoError(t, os.WriteFile(wide, []byte("192.0.2.0,192.0.2.255,SE,,Uppsala,,\n192.0.2.16,192.0.2.31,NO,,Oslo,,\n2001:db8::,2001:db8::ffff,FI,,,,\n"), 0600))
+
+	gz := &bytes.Buffer{}
+	gzw := gzip.NewWriter(gz)
+	_, err := gzw.Write([]byte("198.51.100.0,198.51.100.255,DK,,,,\n192.0.2.0,192.0.2.255,DE,,,,\n"))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(gz.Bytes())
+	}))
+	defer server.Close()
+
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			GeoRange: model.GeoRange{
+				Sources: []model.GeoRangeSource{
+					{Name: "wide", FilePath: wide},
+					{Name: "remote", URL: server.URL + "/ranges.csv.gz", Token: "secret"},
+					{Name: "missing", FilePath: filepath.Join(dir, "missing.csv")},
+				},
+			},
+		},
+	}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	tts := []struct {
+		ip           string
+		wantCountry  string
+		wantProvider string
+	}{
+		{ip: "192.0.2.1", wantCountry: "SE", wantProvider: "wide"},
+		{ip: "192.0.2.17", wantCountry: "NO", wantProvider: "wide"},
+		{ip: "198.51.100.7", wantCountry: "DK", wantProvider: "remote"},
+		{ip: "2001:db8::1", wantCountry: "FI", wantProvider: "wide"},
+		{ip: "::ffff:192.0.2.1", wantCountry: "SE", wantProvider: "wide"},
+		{ip: "203.0.113.1"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := s.LookupGeo(context.TODO(), net.ParseIP(tt.ip))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCountry, got.CountryISO)
+			assert.Equal(t, tt.wantProvider, got.Provider)
+		})
+	}
+
+	got, err := s.LookupASN(context.TODO(), net.ParseIP("192.0.2.1"))
+	require.NoError(t, err)
+	assert.True(t, got.Empty())
+}
+
+func TestUpdateKeepsPreviousTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranges.csv")
+	require.NoError(t, os.WriteFile(path, []byte("192.0.2.0,192.0.2.255,SE,,,,\n"), 0600))
+
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			GeoRange: model.GeoRange{
+				Sources: []model.GeoRangeSource{{Name: "local", FilePath: path}},
+			},
+		},
+	}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	reloads := 0
+	s.OnReload(func(ctx context.Context) { reloads++ })
+
+	require.NoError(t, os.WriteFile(path, []byte("not,a,range\n"), 0600))
+	s.Update(context.TODO())
+	assert.Equal(t, 0, reloads)
+
+	got, err := s.LookupGeo(context.TODO(), net.ParseIP("192.0.2.1"))
+	require.NoError(t, err)
+	assert.Equal(t, "SE", got.CountryISO)
+}
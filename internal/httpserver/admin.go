@@ -0,0 +1,117 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// checkAdminConfig checks that an enabled admin api has a way to authenticate
+func checkAdminConfig(cfg model.APIServer) error {
+	if !cfg.Admin.Enable {
+		return nil
+	}
+	if len(cfg.Admin.Tokens) == 0 && len(cfg.Admin.ClientCertNames) == 0 {
+		return errors.New("admin api enabled without tokens or client_cert_names")
+	}
+	if len(cfg.Admin.ClientCertNames) > 0 && (!cfg.TLS.Enable || cfg.TLS.ClientCAFile == "") {
+		return errors.New("admin client_cert_names requires tls with client_ca_file")
+	}
+	return nil
+}
+
+// tlsListener listens on addr with the server certificate, client certificates are verified when given
+func tlsListener(cfg model.APIServer) (net.Listener, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.TLS.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLS.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", cfg.TLS.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tls.Listen("tcp", cfg.Addr, tlsConfig)
+}
+
+// adminIdentity returns the name of the admin of the request, or the status to deny it with
+func (s *Service) adminIdentity(c *fiber.Ctx) (string, int) {
+	cfg := s.config.IPService.APIServer.Admin
+
+	if token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok {
+		token = strings.TrimSpace(token)
+		for _, adminToken := range cfg.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken.Token)) == 1 {
+				return adminToken.Name, fiber.StatusOK
+			}
+		}
+		return "", fiber.StatusUnauthorized
+	}
+
+	// only verified chains count, the listener accepts connections without a client certificate
+	if state := c.Context().TLSConnectionState(); state != nil && len(state.VerifiedChains) > 0 {
+		name := state.VerifiedChains[0][0].Subject.CommonName
+		if slices.Contains(cfg.ClientCertNames, name) {
+			return name, fiber.StatusOK
+		}
+		return "", fiber.StatusForbidden
+	}
+
+	return "", fiber.StatusUnauthorized
+}
+
+// middlewareAdminAuth authenticates admins and writes every admin request to the audit log
+func (s *Service) middlewareAdminAuth(ctx context.Context) fiber.Handler {
+	audit := s.logger.New("audit")
+
+	return func(c *fiber.Ctx) error {
+		name, status := s.adminIdentity(c)
+		if status != fiber.StatusOK {
+			audit.Info("admin request denied", "method", c.Method(), "path", c.OriginalURL(), "status", status, "clientip", s.clientIP(c))
+			if status == fiber.StatusUnauthorized {
+				c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+				return c.Status(status).JSON(fiber.Map{"data": nil, "error": helpers.NewError("admin_authentication_required")})
+			}
+			return c.Status(status).JSON(fiber.Map{"data": nil, "error": helpers.NewError("admin_not_allowed")})
+		}
+
+		// the admin api only replies JSON
+		c.Request().Header.Set(fiber.HeaderAccept, MIMEJSON)
+
+		err := c.Next()
+
+		audit.Info("admin request", "admin", name, "method", c.Method(), "path", c.OriginalURL(), "status", c.Response().StatusCode(), "clientip", s.clientIP(c))
+
+		return err
+	}
+}
+
+func (s *Service) regAdminEndpoint(ctx context.Context, method, path string, handler func(context.Context, *fiber.Ctx) (any, error)) {
+	s.app.Add(method, path, s.middlewareAdminAuth(ctx), s.handle(ctx, handler))
+}
@@ -0,0 +1,97 @@
+package httpserver
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"ip_service/pkg/model"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareAdminAuth(t *testing.T) {
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			APIServer: model.APIServer{
+				Admin: model.Admin{
+					Enable: true,
+					Tokens: []model.AdminToken{{Name: "ops", Token: "admin-secret"}},
+				},
+			},
+		},
+	}
+
+	s := &Service{config: cfg, logger: logger.NewSimple("test")}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/admin/datasets", s.middlewareAdminAuth(t.Context()), func(c *fiber.Ctx) error {
+		return c.SendString(c.Get(fiber.HeaderAccept))
+	})
+
+	tts := []struct {
+		name       string
+		headers    map[string]string
+		wantCode   int
+		wantAccept string
+	}{
+		{name: "no token", wantCode: fiber.StatusUnauthorized},
+		{name: "invalid token", headers: map[string]string{fiber.HeaderAuthorization: "Bearer nope"}, wantCode: fiber.StatusUnauthorized},
+		{name: "api key header is not an admin token", headers: map[string]string{headerAPIKey: "admin-secret"}, wantCode: fiber.StatusUnauthorized},
+		{name: "valid token", headers: map[string]string{fiber.HeaderAuthorization: "Bearer admin-secret", fiber.HeaderAccept: MIMEHTML}, wantCode: fiber.StatusOK, wantAccept: MIMEJSON},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/datasets", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantCode, resp.StatusCode)
+			if tt.wantAccept != "" {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantAccept, string(body))
+			}
+		})
+	}
+}
+
+func TestCheckAdminConfig(t *testing.T) {
+	tts := []struct {
+		name    string
+		cfg     model.APIServer
+		wantErr bool
+	}{
+		{name: "disabled", cfg: model.APIServer{}},
+		{name: "token", cfg: model.APIServer{Admin: model.Admin{Enable: true, Tokens: []model.AdminToken{{Name: "ops", Token: "secret"}}}}},
+		{name: "no authentication", cfg: model.APIServer{Admin: model.Admin{Enable: true}}, wantErr: true},
+		{name: "client cert without tls", cfg: model.APIServer{Admin: model.Admin{Enable: true, ClientCertNames: []string{"ops"}}}, wantErr: true},
+		{
+			name: "client cert",
+			cfg: model.APIServer{
+				TLS:   model.TLS{Enable: true, ClientCAFile: "/ca.pem"},
+				Admin: model.Admin{Enable: true, ClientCertNames: []string{"ops"}},
+			},
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAdminConfig(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -37,5 +37,21 @@ type Apiv1 interface {
 
 	Whois(ctx context.Context, indata *apiv1.WhoisRequest) ([]rpsl.ASN, error)
 
+	Blocklist(ctx context.Context, indata *apiv1.BlocklistRequest) (*model.ReplyBlocklist, error)
+
+	IRRChanges(ctx context.Context, indata *apiv1.IRRChangesRequest) (*apiv1.IRRChangesReply, error)
+
 	Status(ctx context.Context) (*model.StatusReply, error)
+	Live(ctx context.Context) (*model.StatusReply, error)
+
+	AdminDatasets(ctx context.Context) (*apiv1.AdminDatasetsReply, error)
+	AdminUpdateDataset(ctx context.Context, indata *apiv1.AdminUpdateDatasetRequest) (*apiv1.AdminDatasetsReply, error)
+	AdminRollbackDataset(ctx context.Context, indata *apiv1.AdminRollbackDatasetRequest) (*apiv1.AdminDatasetsReply, error)
+	AdminGetStoreKey(ctx context.Context, indata *apiv1.AdminStoreKeyRequest) (*apiv1.AdminStoreKeyReply, error)
+	AdminSetStoreKey(ctx context.Context, indata *apiv1.AdminStoreKeyRequest) (*apiv1.AdminStoreKeyReply, error)
+	AdminListAPIKeys(ctx context.Context) (*apiv1.AdminAPIKeysReply, error)
+	AdminAddAPIKey(ctx context.Context, indata *apiv1.AdminAddAPIKeyRequest) (*model.APIKey, error)
+	AdminDelAPIKey(ctx context.Context, indata *apiv1.AdminDelAPIKeyRequest) (*apiv1.AdminAPIKeysReply, error)
+	AdminLogLevel(ctx context.Context, indata *apiv1.AdminLogLevelRequest) (*apiv1.AdminLogLevelReply, error)
+	AdminExportMMDB(ctx context.Context) (*apiv1.FileReply, error)
 }
@@ -0,0 +1,192 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jellydator/ttlcache/v3"
+	"golang.org/x/time/rate"
+)
+
+const (
+	headerAPIKey = "X-API-Key"
+
+	// localsAPIKey holds the *apiKeyIdentity of an authenticated request
+	localsAPIKey = "api-key"
+
+	// defaultInvalidKeyRate and defaultInvalidKeyBurst limit the invalid keys a client may send, per second
+	defaultInvalidKeyRate  = 1
+	defaultInvalidKeyBurst = 10
+)
+
+// apiKeyStore is the part of store.KV used for api keys
+type apiKeyStore interface {
+	GetAPIKey(ctx context.Context, key string) (*model.APIKey, error)
+}
+
+// apiKeyIdentity is the authenticated api key of a request
+type apiKeyIdentity struct {
+	name string
+	tier model.APITier
+}
+
+// apiKeys resolves api keys from config, falling back to the store. The invalid keys of a client are limited per
+// prefix, so a client guessing keys does not read the store on every request.
+type apiKeys struct {
+	cfg     *model.APIKeys
+	keys    map[string]*model.APIKey
+	store   apiKeyStore
+	invalid *ttlcache.Cache[netip.Prefix, *rate.Limiter]
+}
+
+func newAPIKeys(cfg *model.APIKeys, store apiKeyStore) (*apiKeys, error) {
+	a := &apiKeys{
+		cfg:     cfg,
+		keys:    map[string]*model.APIKey{},
+		store:   store,
+		invalid: ttlcache.New(ttlcache.WithTTL[netip.Prefix, *rate.Limiter](defaultRateLimitIdleTimeout)),
+	}
+
+	for _, apiKey := range cfg.Keys {
+		if _, ok := cfg.Tiers[apiKey.Tier]; !ok {
+			return nil, fmt.Errorf("api key %q has unknown tier %q", apiKey.Name, apiKey.Tier)
+		}
+		a.keys[model.HashAPIKey(apiKey.Key)] = &apiKey
+	}
+
+	go a.invalid.Start()
+
+	return a, nil
+}
+
+// invalidKeyLimiter returns the limiter of the invalid keys sent by the client prefix of clientIP, IPv4 addresses
+// by /32 and IPv6 by /64. nil for an unparsable address.
+func (a *apiKeys) invalidKeyLimiter(clientIP string) *rate.Limiter {
+	addr, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	bits := defaultRateLimitIPv6PrefixLength
+	if addr.Is4() {
+		bits = defaultRateLimitIPv4PrefixLength
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return nil
+	}
+
+	bucket := a.cfg.InvalidKeyLimit
+	if bucket.Rate <= 0 {
+		bucket.Rate = defaultInvalidKeyRate
+	}
+	if bucket.Burst < 1 {
+		bucket.Burst = defaultInvalidKeyBurst
+	}
+
+	item, _ := a.invalid.GetOrSetFunc(prefix, func() *rate.Limiter {
+		return rate.NewLimiter(rate.Limit(bucket.Rate), bucket.Burst)
+	})
+	return item.Value()
+}
+
+// Close stops the expiry of the invalid key limiters
+func (a *apiKeys) Close() {
+	a.invalid.Stop()
+}
+
+// lookup returns the identity of key, or helpers.ErrAPIKeyNotFound
+func (a *apiKeys) lookup(ctx context.Context, key string) (*apiKeyIdentity, error) {
+	apiKey, ok := a.keys[model.HashAPIKey(key)]
+	if !ok {
+		if a.store == nil {
+			return nil, helpers.ErrAPIKeyNotFound
+		}
+		var err error
+		apiKey, err = a.store.GetAPIKey(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tier, ok := a.cfg.Tiers[apiKey.Tier]
+	if !ok {
+		return nil, helpers.ErrAPIKeyNotFound
+	}
+
+	return &apiKeyIdentity{name: apiKey.Name, tier: tier}, nil
+}
+
+// apiKeyFromRequest returns the api key from the X-API-Key header or a bearer token
+func apiKeyFromRequest(c *fiber.Ctx) string {
+	if key := c.Get(headerAPIKey); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// middlewareAPIKey authenticates the api key of the request, protected routes require one
+func (s *Service) middlewareAPIKey(ctx context.Context, route string) fiber.Handler {
+	protected := slices.Contains(s.config.IPService.APIServer.APIKeys.Protected, route)
+
+	return func(c *fiber.Ctx) error {
+		key := apiKeyFromRequest(c)
+		if key == "" {
+			if protected {
+				c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"data": nil, "error": helpers.NewError("api_key_required")})
+			}
+			return c.Next()
+		}
+
+		// a client past its invalid keys is refused before the store is read, the token taken is given back when the
+		// key is valid
+		var reservation *rate.Reservation
+		now := time.Now()
+		if limiter := s.apiKeys.invalidKeyLimiter(s.clientIP(c)); limiter != nil {
+			reservation = limiter.ReserveN(now, 1)
+			if delay := reservation.DelayFrom(now); delay > 0 {
+				reservation.CancelAt(now)
+				s.metrics.rateLimited.WithLabelValues(route).Inc()
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"data": nil, "error": helpers.NewError("too_many_invalid_api_keys")})
+			}
+		}
+
+		identity, err := s.apiKeys.lookup(ctx, key)
+		if reservation != nil && !errors.Is(err, helpers.ErrAPIKeyNotFound) {
+			reservation.CancelAt(now)
+		}
+		if err != nil {
+			if errors.Is(err, helpers.ErrAPIKeyNotFound) {
+				c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"data": nil, "error": helpers.NewError("invalid_api_key")})
+			}
+			s.logger.Error(err, "failed to look up api key")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"data": nil, "error": helpers.NewError("internal_server_error")})
+		}
+
+		if protected && len(identity.tier.Routes) > 0 && !slices.Contains(identity.tier.Routes, route) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"data": nil, "error": helpers.NewError("route_not_in_tier")})
+		}
+
+		c.Locals(localsAPIKey, identity)
+
+		return c.Next()
+	}
+}
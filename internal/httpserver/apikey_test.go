@@ -0,0 +1,157 @@
+package httpserver
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAPIKeyStore map[string]*model.APIKey
+
+func (m mockAPIKeyStore) GetAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+	apiKey, ok := m[key]
+	if !ok {
+		return nil, helpers.ErrAPIKeyNotFound
+	}
+	return apiKey, nil
+}
+
+func TestMiddlewareAPIKey(t *testing.T) {
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			APIServer: model.APIServer{
+				APIKeys: model.APIKeys{
+					Protected: []string{"/lookup/:ip", "/collision"},
+					Tiers: map[string]model.APITier{
+						"basic": {Routes: []string{"/lookup/:ip"}, RateLimit: model.RateLimitBucket{Rate: 0.1, Burst: 1}},
+						"bulk":  {},
+					},
+					Keys: []model.APIKey{
+						{Name: "config-basic", Key: "basic-secret", Tier: "basic"},
+					},
+				},
+			},
+		},
+	}
+
+	keys, err := newAPIKeys(&cfg.IPService.APIServer.APIKeys, mockAPIKeyStore{
+		"bulk-secret": {Name: "store-bulk", Tier: "bulk"},
+	})
+	require.NoError(t, err)
+	defer keys.Close()
+
+	limiter, err := newRateLimiter(&cfg.IPService.APIServer.RateLimit)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	s := &Service{config: cfg, logger: logger.NewSimple("test"), metrics: serviceMetrics, apiKeys: keys, rateLimiter: limiter}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	for _, route := range []string{"/lookup/:ip", "/collision", "/city"} {
+		app.Get(route, s.middlewareAPIKey(t.Context(), route), s.middlewareRateLimit(route), func(c *fiber.Ctx) error {
+			name := "anonymous"
+			if identity, ok := c.Locals(localsAPIKey).(*apiKeyIdentity); ok {
+				name = identity.name
+			}
+			return c.SendString(name)
+		})
+	}
+
+	tts := []struct {
+		name     string
+		path     string
+		headers  map[string]string
+		wantCode int
+	}{
+		{name: "anonymous unprotected", path: "/city", wantCode: fiber.StatusOK},
+		{name: "anonymous protected", path: "/lookup/192.0.2.1", wantCode: fiber.StatusUnauthorized},
+		{name: "invalid key", path: "/city", headers: map[string]string{headerAPIKey: "nope"}, wantCode: fiber.StatusUnauthorized},
+		{name: "config key header", path: "/lookup/192.0.2.1", headers: map[string]string{headerAPIKey: "basic-secret"}, wantCode: fiber.StatusOK},
+		{name: "config key quota", path: "/lookup/192.0.2.1", headers: map[string]string{headerAPIKey: "basic-secret"}, wantCode: fiber.StatusTooManyRequests},
+		{name: "route not in tier", path: "/collision", headers: map[string]string{headerAPIKey: "basic-secret"}, wantCode: fiber.StatusForbidden},
+		{name: "store key bearer", path: "/collision", headers: map[string]string{fiber.HeaderAuthorization: "Bearer bulk-secret"}, wantCode: fiber.StatusOK},
+		{name: "unlimited tier", path: "/collision", headers: map[string]string{fiber.HeaderAuthorization: "Bearer bulk-secret"}, wantCode: fiber.StatusOK},
+	}
+
+	// cases run in order, the quota case depends on the previous one
+	for _, tt := range tts {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, tt.wantCode, resp.StatusCode, tt.name)
+	}
+}
+
+// countingAPIKeyStore counts the store reads
+type countingAPIKeyStore struct {
+	mockAPIKeyStore
+	reads int
+}
+
+func (m *countingAPIKeyStore) GetAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+	m.reads++
+	return m.mockAPIKeyStore.GetAPIKey(ctx, key)
+}
+
+func TestMiddlewareAPIKeyInvalidKeyLimit(t *testing.T) {
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			APIServer: model.APIServer{
+				APIKeys: model.APIKeys{
+					Tiers:           map[string]model.APITier{"bulk": {}},
+					InvalidKeyLimit: model.RateLimitBucket{Rate: 0.01, Burst: 2},
+				},
+			},
+		},
+	}
+
+	store := &countingAPIKeyStore{mockAPIKeyStore: mockAPIKeyStore{"bulk-secret": {Name: "store-bulk", Tier: "bulk"}}}
+	keys, err := newAPIKeys(&cfg.IPService.APIServer.APIKeys, store)
+	require.NoError(t, err)
+	defer keys.Close()
+
+	s := &Service{config: cfg, logger: logger.NewSimple("test"), metrics: serviceMetrics, apiKeys: keys}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/city", s.middlewareAPIKey(t.Context(), "/city"), func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	get := func(key string) int {
+		req := httptest.NewRequest("GET", "/city", nil)
+		req.Header.Set(headerAPIKey, key)
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	// valid keys do not count
+	for range 3 {
+		assert.Equal(t, fiber.StatusOK, get("bulk-secret"))
+	}
+
+	assert.Equal(t, fiber.StatusUnauthorized, get("guess-1"))
+	assert.Equal(t, fiber.StatusUnauthorized, get("guess-2"))
+	assert.Equal(t, 5, store.reads)
+
+	// past the burst the store is not read
+	assert.Equal(t, fiber.StatusTooManyRequests, get("guess-3"))
+	assert.Equal(t, fiber.StatusTooManyRequests, get("bulk-secret"))
+	assert.Equal(t, 5, store.reads)
+}
+
+func TestNewAPIKeysUnknownTier(t *testing.T) {
+	_, err := newAPIKeys(&model.APIKeys{
+		Keys: []model.APIKey{{Name: "a", Key: "a", Tier: "missing"}},
+	}, nil)
+	assert.Error(t, err)
+}
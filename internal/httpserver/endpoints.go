@@ -29,7 +29,6 @@ func (s *Service) endpointIndex(ctx context.Context, c *fiber.Ctx) (any, error)
 		if err != nil {
 			return nil, err
 		}
-		s.metrics.EndpointIndexPLAINCounter.Inc()
 		return reply, nil
 
 	case MIMEJSON:
@@ -38,7 +37,6 @@ func (s *Service) endpointIndex(ctx context.Context, c *fiber.Ctx) (any, error)
 		if err != nil {
 			return nil, err
 		}
-		s.metrics.EndpointIndexJSONCounter.Inc()
 		return reply, nil
 
 	case MIMEHTML:
@@ -47,7 +45,6 @@ func (s *Service) endpointIndex(ctx context.Context, c *fiber.Ctx) (any, error)
 		if err != nil {
 			return nil, err
 		}
-		s.metrics.EndpointIndexHTMLCounter.Inc()
 		return reply, nil
 	}
 
@@ -63,8 +60,6 @@ func (s *Service) endpointCity(ctx context.Context, c *fiber.Ctx) (any, error) {
 		return nil, err
 	}
 
-	s.metrics.EndpointCityCounter.Inc()
-
 	switch contextRequest.Accept {
 	case MIMEJSON, MIMEHTML:
 		reply, err := s.apiv1.CityJSON(ctx)
@@ -90,8 +85,6 @@ func (s *Service) endpointCountry(ctx context.Context, c *fiber.Ctx) (any, error
 		return nil, err
 	}
 
-	s.metrics.EndpointCountryCounter.Inc()
-
 	switch contextRequest.Accept {
 	case MIMEJSON, MIMEHTML:
 		reply, err := s.apiv1.CountryJSON(ctx)
@@ -117,8 +110,6 @@ func (s *Service) endpointCountryISO(ctx context.Context, c *fiber.Ctx) (any, er
 		return nil, err
 	}
 
-	s.metrics.EndpointCountryISOCounter.Inc()
-
 	switch contextRequest.Accept {
 	case MIMEJSON, MIMEHTML:
 		reply, err := s.apiv1.CountryISOJSON(ctx)
@@ -144,8 +135,6 @@ func (s *Service) endpointASN(ctx context.Context, c *fiber.Ctx) (any, error) {
 		return nil, err
 	}
 
-	s.metrics.EndpointASNCounter.Inc()
-
 	switch contextRequest.Accept {
 	case MIMEJSON, MIMEHTML:
 		reply, err := s.apiv1.ASNJSON(ctx)
@@ -171,8 +160,6 @@ func (s *Service) endpointCoordinates(ctx context.Context, c *fiber.Ctx) (any, e
 		return nil, err
 	}
 
-	s.metrics.EndpointCoordinatesCounter.Inc()
-
 	switch contextRequest.Accept {
 	case MIMEJSON, MIMEHTML:
 		reply, err := s.apiv1.CoordinatesJSON(ctx)
@@ -198,7 +185,6 @@ func (s *Service) endpointAll(ctx context.Context, c *fiber.Ctx) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	s.metrics.EndpointAllCounter.Inc()
 	return reply, nil
 }
 
@@ -215,7 +201,6 @@ func (s *Service) endpointLookUpIP(ctx context.Context, c *fiber.Ctx) (any, erro
 	if err != nil {
 		return nil, err
 	}
-	s.metrics.EndpointLookUpIPCounter.Inc()
 	return reply, nil
 }
 
@@ -227,7 +212,20 @@ func (s *Service) endpointHealth(ctx context.Context, c *fiber.Ctx) (any, error)
 	if err != nil {
 		return nil, err
 	}
-	s.metrics.HealthCounter.Inc()
+	if !reply.Healthy() {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointHealthLive(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointHealthLive")
+	defer span.End()
+
+	reply, err := s.apiv1.Live(ctx)
+	if err != nil {
+		return nil, err
+	}
 	return reply, nil
 }
 
@@ -264,3 +262,189 @@ func (s *Service) endpointWhois(ctx context.Context, c *fiber.Ctx) (any, error)
 	}
 	return reply, nil
 }
+
+func (s *Service) endpointBlocklist(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointBlocklist")
+	defer span.End()
+
+	request := &apiv1.BlocklistRequest{}
+	if err := s.bindRequest(ctx, c, request); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	reply, err := s.apiv1.Blocklist(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointIRRChanges(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointIRRChanges")
+	defer span.End()
+
+	request := &apiv1.IRRChangesRequest{}
+	if err := s.bindRequest(ctx, c, request); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	reply, err := s.apiv1.IRRChanges(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointAdminDatasets(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointAdminDatasets")
+	defer span.End()
+
+	reply, err := s.apiv1.AdminDatasets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointAdminUpdateDataset(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointAdminUpdateDataset")
+	defer span.End()
+
+	request := &apiv1.AdminUpdateDatasetRequest{}
+	if err := s.bindRequest(ctx, c, request); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	reply, err := s.apiv1.AdminUpdateDataset(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointAdminRollbackDataset(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointAdminRollbackDataset")
+	defer span.End()
+
+	request := &apiv1.AdminRollbackDatasetRequest{}
+	if err := s.bindRequest(ctx, c, request); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	reply, err := s.apiv1.AdminRollbackDataset(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointAdminGetStoreKey(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointAdminGetStoreKey")
+	defer span.End()
+
+	request := &apiv1.AdminStoreKeyRequest{}
+	if err := s.bindRequest(ctx, c, request); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	reply, err := s.apiv1.AdminGetStoreKey(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointAdminSetStoreKey(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointAdminSetStoreKey")
+	defer span.End()
+
+	request := &apiv1.AdminStoreKeyRequest{}
+	if err := s.bindRequest(ctx, c, request); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	reply, err := s.apiv1.AdminSetStoreKey(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointAdminListAPIKeys(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointAdminListAPIKeys")
+	defer span.End()
+
+	reply, err := s.apiv1.AdminListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointAdminAddAPIKey(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointAdminAddAPIKey")
+	defer span.End()
+
+	request := &apiv1.AdminAddAPIKeyRequest{}
+	if err := s.bindRequest(ctx, c, request); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	reply, err := s.apiv1.AdminAddAPIKey(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointAdminDelAPIKey(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointAdminDelAPIKey")
+	defer span.End()
+
+	request := &apiv1.AdminDelAPIKeyRequest{}
+	if err := s.bindRequest(ctx, c, request); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	reply, err := s.apiv1.AdminDelAPIKey(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointAdminLogLevel(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointAdminLogLevel")
+	defer span.End()
+
+	request := &apiv1.AdminLogLevelRequest{}
+	if err := s.bindRequest(ctx, c, request); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	reply, err := s.apiv1.AdminLogLevel(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *Service) endpointAdminExportMMDB(ctx context.Context, c *fiber.Ctx) (any, error) {
+	ctx, span := s.TP.Start(ctx, "httpserver:endpointAdminExportMMDB")
+	defer span.End()
+
+	reply, err := s.apiv1.AdminExportMMDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
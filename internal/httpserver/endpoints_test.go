@@ -9,11 +9,10 @@ import (
 	"io"
 	"net/http/httptest"
 	"path/filepath"
-	"sync"
 	"testing"
 
 	"ip_service/internal/apiv1"
-	"ip_service/internal/maxmind"
+	"ip_service/internal/maxmind/maxmindtest"
 	"ip_service/internal/store"
 	"ip_service/internal/whois"
 	"ip_service/pkg/contexthandler"
@@ -24,7 +23,6 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/go-cmp/cmp"
 	ua "github.com/mileusna/useragent"
-	"github.com/oschwald/geoip2-golang"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,24 +35,26 @@ const (
 var (
 	mockIPWithPort         = fmt.Sprintf("%s:8000", mockIP)
 	mockReplyIPInformation = &model.ReplyIPInformation{
-		IP:              mockIP,
-		IPDecimal:       "1503663216",
-		ASN:             29518,
-		ASNOrganization: "Bredband2 AB",
-		City:            "Linköping",
-		Country:         "Sweden",
-		CountryISO:      "SE",
-		IsEU:            true,
-		Region:          "",
-		RegionCode:      "",
-		PostalCode:      "",
-		Coordinates: &model.Coordinates{
-			Latitude:  58.4167,
-			Longitude: 15.6167,
+		IP:        mockIP,
+		IPDecimal: "1503663216",
+		ReplyFields: model.ReplyFields{
+			ASN:             29518,
+			ASNOrganization: "Bredband2 AB",
+			City:            "Linköping",
+			Country:         "Sweden",
+			CountryISO:      "SE",
+			IsEU:            true,
+			Region:          "Östergötland County",
+			RegionCode:      "E",
+			PostalCode:      "",
+			Coordinates: &model.Coordinates{
+				Latitude:  58.4167,
+				Longitude: 15.6167,
+			},
+			Continent: "Europe",
+			Timezone:  "Europe/Stockholm",
 		},
-		Continent: "Europe",
-		Timezone:  "Europe/Stockholm",
-		Hostname:  "",
+		Hostname: "",
 		UserAgent: ua.UserAgent{
 			Name:      "test-application",
 			Version:   "3.14.15",
@@ -83,48 +83,32 @@ func mockHTMLTemplate(t *testing.T) string {
 
 func mockService(t *testing.T) *Service {
 	ctx := context.TODO()
-	dbCity, err := geoip2.Open(filepath.Join("..", "..", "testdata", "GeoLite2-city-Test.mmdb"))
-	assert.NoError(t, err)
-
-	dbASN, err := geoip2.Open(filepath.Join("..", "..", "testdata", "GeoLite2-asn-Test.mmdb"))
-	assert.NoError(t, err)
 
 	tracer, err := trace.NewForTesting(ctx, "test", logger.NewSimple("test"))
 	assert.NoError(t, err)
 
-	maxmind := &maxmind.Service{
-		DBCity: dbCity,
-		DBASN:  dbASN,
-		TP:     tracer,
-		Log:    logger.NewSimple("test-maxmind"),
-		DBMeta: map[string]*maxmind.DBObject{
-			model.MaxmindDBTypeASN: {
-				MU: sync.RWMutex{},
-			},
-			model.MaxmindDBTypeCity: {
-				MU: sync.RWMutex{},
-			},
-		},
-	}
+	maxmind, err := maxmindtest.New(t, tracer, logger.NewSimple("test-maxmind"), map[string]string{
+		model.MaxmindDBTypeASN:  filepath.Join("..", "..", "testdata", "GeoLite2-asn-Test.mmdb"),
+		model.MaxmindDBTypeCity: filepath.Join("..", "..", "testdata", "GeoLite2-city-Test.mmdb"),
+	})
+	assert.NoError(t, err)
 	whois := &whois.Service{}
 
 	store := &store.Service{}
 
 	cfg := &model.Cfg{}
 
-	apiv1, err := apiv1.New(ctx, maxmind, whois, store, cfg, tracer, logger.NewSimple("test-api"))
+	apiv1, err := apiv1.New(ctx, maxmind, whois, store, apiv1.Options{}, cfg, tracer, logger.NewSimple("test-api"))
 	assert.NoError(t, err)
 
 	s := &Service{
 		config:  &model.Cfg{},
 		logger:  logger.NewSimple("test-httpserver"),
 		TP:      tracer,
-		metrics: &metrics{},
+		metrics: serviceMetrics,
 		apiv1:   apiv1,
 	}
 
-	s.metrics.init()
-
 	return s
 }
 
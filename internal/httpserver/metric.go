@@ -1,68 +1,108 @@
 package httpserver
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// serviceMetrics is shared between all instances of Service, since the collectors are registered globally
+var serviceMetrics = newMetrics()
+
+// legacyCounterKey identifies a deprecated per-endpoint counter by route template and negotiated content type,
+// an empty content type matches any.
+type legacyCounterKey struct {
+	route       string
+	contentType string
+}
+
 // metrics is the metrics object for httpserver
 type metrics struct {
-	EndpointIndexHTMLCounter   prometheus.Counter
-	EndpointIndexJSONCounter   prometheus.Counter
-	EndpointIndexPLAINCounter  prometheus.Counter
-	EndpointCityCounter        prometheus.Counter
-	EndpointCountryCounter     prometheus.Counter
-	EndpointCountryISOCounter  prometheus.Counter
-	EndpointASNCounter         prometheus.Counter
-	EndpointCoordinatesCounter prometheus.Counter
-	EndpointAllCounter         prometheus.Counter
-	EndpointLookUpIPCounter    prometheus.Counter
-	HealthCounter              prometheus.Counter
+	requests     *prometheus.CounterVec
+	inFlight     prometheus.Gauge
+	responseSize *prometheus.HistogramVec
+	duration     *prometheus.HistogramVec
+	rateLimited  *prometheus.CounterVec
+
+	// legacy holds the per-endpoint counters, kept during the deprecation period so that existing dashboards keep working.
+	legacy map[legacyCounterKey]prometheus.Counter
+}
+
+func newMetrics() *metrics {
+	labels := []string{"route", "method", "code", "content_type"}
+
+	m := &metrics{
+		requests: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "ip_service_http_requests_total",
+			Help: "The total number of http requests",
+		}, labels),
+		inFlight: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "ip_service_http_requests_in_flight",
+			Help: "The number of http requests currently being served",
+		}),
+		responseSize: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ip_service_http_response_size_bytes",
+			Help:    "The size of http responses",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		}, labels),
+		duration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ip_service_http_request_duration_seconds",
+			Help:    "The latency of http requests",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+		rateLimited: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "ip_service_http_rate_limited_total",
+			Help: "The total number of http requests rejected by the rate limiter",
+		}, []string{"route"}),
+		legacy: map[legacyCounterKey]prometheus.Counter{},
+	}
+
+	for _, c := range []struct {
+		key  legacyCounterKey
+		name string
+		help string
+	}{
+		{key: legacyCounterKey{"/", MIMEHTML}, name: "ip_service_http_endpoint_index_html_total", help: "The total number of request to endpoint / with Accept: text/html"},
+		{key: legacyCounterKey{"/", MIMEJSON}, name: "ip_service_http_endpoint_index_json_total", help: "The total number of request to endpoint / with Accept: application/json"},
+		{key: legacyCounterKey{"/", MIMEPlain}, name: "ip_service_http_endpoint_index_plain_total", help: "The total number of request to endpoint / with Accept: text/plain"},
+		{key: legacyCounterKey{route: "/city"}, name: "ip_service_http_endpoint_city_total", help: "The total number of request to endpoint /city"},
+		{key: legacyCounterKey{route: "/country"}, name: "ip_service_http_endpoint_country_total", help: "The total number of request to endpoint /country"},
+		{key: legacyCounterKey{route: "/country-iso"}, name: "ip_service_http_endpoint_country_iso_total", help: "The total number of request to endpoint /country-iso"},
+		{key: legacyCounterKey{route: "/asn"}, name: "ip_service_http_endpoint_asn_total", help: "The total number of request to endpoint /asn"},
+		{key: legacyCounterKey{route: "/coordinates"}, name: "ip_service_http_endpoint_coordinates_total", help: "The total number of request to endpoint /coordinates"},
+		{key: legacyCounterKey{route: "/all"}, name: "ip_service_http_endpoint_all_total", help: "The total number of request to endpoint /all"},
+		{key: legacyCounterKey{route: "/lookup/:ip"}, name: "ip_service_http_endpoint_lookup_ip_total", help: "The total number of request to endpoint /lookup"},
+		{key: legacyCounterKey{route: "/health"}, name: "ip_service_http_health_total", help: "The total number of request to endpoint /health"},
+	} {
+		m.legacy[c.key] = promauto.NewCounter(prometheus.CounterOpts{
+			Name: c.name,
+			Help: "Deprecated, use ip_service_http_requests_total. " + c.help,
+		})
+	}
+
+	return m
 }
 
-func (m *metrics) init() {
-	m.EndpointIndexHTMLCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_endpoint_index_html_total",
-		Help: "The total number of request to endpoint / with Accept: text/html",
-	})
-	m.EndpointIndexJSONCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_endpoint_index_json_total",
-		Help: "The total number of request to endpoint / with Accept: application/json",
-	})
-	m.EndpointIndexPLAINCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_endpoint_index_plain_total",
-		Help: "The total number of request to endpoint / with Accept: text/plain",
-	})
-	m.EndpointCityCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_endpoint_city_total",
-		Help: "The total number of request to endpoint /city",
-	})
-	m.EndpointCountryCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_endpoint_country_total",
-		Help: "The total number of request to endpoint /country",
-	})
-	m.EndpointCountryISOCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_endpoint_country_iso_total",
-		Help: "The total number of request to endpoint /country-iso",
-	})
-	m.EndpointASNCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_endpoint_asn_total",
-		Help: "The total number of request to endpoint /asn",
-	})
-	m.EndpointCoordinatesCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_endpoint_coordinates_total",
-		Help: "The total number of request to endpoint /coordinates",
-	})
-	m.EndpointAllCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_endpoint_all_total",
-		Help: "The total number of request to endpoint /all",
-	})
-	m.EndpointLookUpIPCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_endpoint_lookup_ip_total",
-		Help: "The total number of request to endpoint /lookup",
-	})
-	m.HealthCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "ip_service_http_health_total",
-		Help: "The total number of request to endpoint /health",
-	})
+// observe records a finished request
+func (m *metrics) observe(route, method string, code int, contentType string, size int, seconds float64) {
+	labels := prometheus.Labels{
+		"route":        route,
+		"method":       method,
+		"code":         strconv.Itoa(code),
+		"content_type": contentType,
+	}
+
+	m.requests.With(labels).Inc()
+	m.responseSize.With(labels).Observe(float64(size))
+	m.duration.With(labels).Observe(seconds)
+
+	// the legacy counters count every response, as before the status code was a label
+	if counter, ok := m.legacy[legacyCounterKey{route, contentType}]; ok {
+		counter.Inc()
+		return
+	}
+	if counter, ok := m.legacy[legacyCounterKey{route: route}]; ok {
+		counter.Inc()
+	}
 }
@@ -0,0 +1,95 @@
+package httpserver
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	assert.NoError(t, c.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	s := &Service{metrics: serviceMetrics}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(s.middlewareMetrics(t.Context()))
+	app.Get("/lookup/:ip", func(c *fiber.Ctx) error {
+		if c.Params("ip") == "invalid" {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+		return c.JSON(fiber.Map{"ip": c.Params("ip")})
+	})
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("192.0.2.1")
+	})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(localsUnmatchedRoute, true)
+		return c.SendStatus(fiber.StatusNotFound)
+	})
+
+	tts := []struct {
+		name       string
+		path       string
+		accept     string
+		wantLabels prometheus.Labels
+		wantLegacy prometheus.Counter
+	}{
+		{
+			name:       "route template",
+			path:       "/lookup/192.0.2.1",
+			accept:     MIMEJSON,
+			wantLabels: prometheus.Labels{"route": "/lookup/:ip", "method": "GET", "code": "200", "content_type": MIMEJSON},
+			wantLegacy: serviceMetrics.legacy[legacyCounterKey{route: "/lookup/:ip"}],
+		},
+		{
+			name:       "error responses are counted by the legacy counter",
+			path:       "/lookup/invalid",
+			accept:     MIMEJSON,
+			wantLabels: prometheus.Labels{"route": "/lookup/:ip", "method": "GET", "code": "400", "content_type": MIMEJSON},
+			wantLegacy: serviceMetrics.legacy[legacyCounterKey{route: "/lookup/:ip"}],
+		},
+		{
+			name:       "index plain",
+			path:       "/",
+			accept:     "*/*",
+			wantLabels: prometheus.Labels{"route": "/", "method": "GET", "code": "200", "content_type": MIMEPlain},
+			wantLegacy: serviceMetrics.legacy[legacyCounterKey{"/", MIMEPlain}],
+		},
+		{
+			name:       "unmatched",
+			path:       "/does/not/exist",
+			accept:     MIMEJSON,
+			wantLabels: prometheus.Labels{"route": "unmatched", "method": "GET", "code": "404", "content_type": MIMEJSON},
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := serviceMetrics.requests.With(tt.wantLabels)
+			before := counterValue(t, requests)
+			var legacyBefore float64
+			if tt.wantLegacy != nil {
+				legacyBefore = counterValue(t, tt.wantLegacy)
+			}
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Accept", tt.accept)
+			_, err := app.Test(req, -1)
+			assert.NoError(t, err)
+
+			assert.Equal(t, before+1, counterValue(t, requests))
+			if tt.wantLegacy != nil {
+				assert.Equal(t, legacyBefore+1, counterValue(t, tt.wantLegacy))
+			}
+		})
+	}
+}
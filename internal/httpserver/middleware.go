@@ -2,6 +2,7 @@ package httpserver
 
 import (
 	"context"
+	"errors"
 	"ip_service/pkg/helpers"
 	"time"
 
@@ -19,6 +20,36 @@ func (s *Service) middlewareDuration(ctx context.Context) fiber.Handler {
 	}
 }
 
+// localsUnmatchedRoute is set by the 404 handler, since it is registered with Use and shares the route template "/" with index
+const localsUnmatchedRoute = "unmatched-route"
+
+func (s *Service) middlewareMetrics(ctx context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		s.metrics.inFlight.Inc()
+		defer s.metrics.inFlight.Dec()
+
+		t := time.Now()
+		err := c.Next()
+
+		code := c.Response().StatusCode()
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			code = fiberErr.Code
+		} else if err != nil {
+			code = fiber.StatusInternalServerError
+		}
+
+		route := c.Route().Path
+		if unmatched, _ := c.Locals(localsUnmatchedRoute).(bool); unmatched {
+			route = "unmatched"
+		}
+
+		s.metrics.observe(route, c.Method(), code, s.getAccept(c), len(c.Response().Body()), time.Since(t).Seconds())
+
+		return err
+	}
+}
+
 func (s *Service) middlewareTraceID(ctx context.Context) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		reqID := shortuuid.New()
@@ -33,7 +64,11 @@ func (s *Service) middlewareLogger(ctx context.Context) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		err := c.Next()
 		reqID, _ := c.Locals("req-id").(string)
-		log.Info("request", "status", c.Response().StatusCode(), "url", c.OriginalURL(), "method", c.Method(), "req-id", reqID)
+		apiKey := "anonymous"
+		if identity, ok := c.Locals(localsAPIKey).(*apiKeyIdentity); ok {
+			apiKey = identity.name
+		}
+		log.Info("request", "status", c.Response().StatusCode(), "url", c.OriginalURL(), "method", c.Method(), "req-id", reqID, "api-key", apiKey)
 		return err
 	}
 }
@@ -0,0 +1,186 @@
+package httpserver
+
+import (
+	"math"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jellydator/ttlcache/v3"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRateLimitIPv4PrefixLength = 32
+	defaultRateLimitIPv6PrefixLength = 64
+	defaultRateLimitIdleTimeout      = 10 * time.Minute
+)
+
+// rateLimitKey identifies a token bucket, anonymous clients are grouped by prefix, others by api key name
+type rateLimitKey struct {
+	route  string
+	prefix netip.Prefix
+	apiKey string
+}
+
+// rateLimitResult is the outcome of taking a token from a bucket
+type rateLimitResult struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// rateLimiter holds the per client token buckets, unused buckets expire after the idle timeout
+type rateLimiter struct {
+	cfg       *model.RateLimit
+	allowlist []netip.Prefix
+	buckets   *ttlcache.Cache[rateLimitKey, *rate.Limiter]
+}
+
+func newRateLimiter(cfg *model.RateLimit) (*rateLimiter, error) {
+	idleTimeout := cfg.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultRateLimitIdleTimeout
+	}
+
+	r := &rateLimiter{
+		cfg:     cfg,
+		buckets: ttlcache.New(ttlcache.WithTTL[rateLimitKey, *rate.Limiter](idleTimeout)),
+	}
+
+	for _, cidr := range cfg.Allowlist {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		r.allowlist = append(r.allowlist, prefix.Masked())
+	}
+
+	go r.buckets.Start()
+
+	return r, nil
+}
+
+// clientPrefix returns the bucket prefix for the client ip, false if the client is allowlisted or unparsable
+func (r *rateLimiter) clientPrefix(clientIP string) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range r.allowlist {
+		if prefix.Contains(addr) {
+			return netip.Prefix{}, false
+		}
+	}
+
+	bits := r.cfg.IPv6PrefixLength
+	if bits == 0 {
+		bits = defaultRateLimitIPv6PrefixLength
+	}
+	if addr.Is4() {
+		bits = r.cfg.IPv4PrefixLength
+		if bits == 0 {
+			bits = defaultRateLimitIPv4PrefixLength
+		}
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
+
+// take takes one token from the bucket identified by key
+func (r *rateLimiter) take(key rateLimitKey, bucket model.RateLimitBucket, now time.Time) rateLimitResult {
+	burst := bucket.Burst
+	if burst < 1 {
+		burst = int(math.Ceil(bucket.Rate))
+	}
+
+	item, _ := r.buckets.GetOrSetFunc(key, func() *rate.Limiter {
+		return rate.NewLimiter(rate.Limit(bucket.Rate), burst)
+	})
+	limiter := item.Value()
+
+	result := rateLimitResult{limit: limiter.Burst()}
+
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		result.retryAfter = delay
+	} else {
+		result.allowed = true
+	}
+
+	tokens := limiter.TokensAt(now)
+	result.remaining = max(0, int(tokens))
+	result.reset = time.Duration((float64(result.limit) - tokens) / bucket.Rate * float64(time.Second))
+
+	return result
+}
+
+// Close stops the bucket expiry
+func (r *rateLimiter) Close() {
+	r.buckets.Stop()
+}
+
+// middlewareRateLimit limits requests to the route per api key, or per client prefix for anonymous requests.
+// It is added per route so that the route template is known.
+func (s *Service) middlewareRateLimit(route string) fiber.Handler {
+	if s.rateLimiter == nil {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+
+	anonymousBucket := s.rateLimiter.cfg.Bucket(route)
+
+	return func(c *fiber.Ctx) error {
+		key := rateLimitKey{route: route}
+		var bucket model.RateLimitBucket
+
+		if identity, ok := c.Locals(localsAPIKey).(*apiKeyIdentity); ok {
+			key.apiKey = identity.name
+			bucket = identity.tier.RateLimit
+		} else {
+			if !s.rateLimiter.cfg.Enabled {
+				return c.Next()
+			}
+			prefix, ok := s.rateLimiter.clientPrefix(s.clientIP(c))
+			if !ok {
+				return c.Next()
+			}
+			key.prefix = prefix
+			bucket = anonymousBucket
+		}
+
+		if bucket.Rate <= 0 {
+			return c.Next()
+		}
+
+		result := s.rateLimiter.take(key, bucket, time.Now())
+
+		c.Set("RateLimit-Limit", strconv.Itoa(result.limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.reset)))
+
+		if !result.allowed {
+			s.metrics.rateLimited.WithLabelValues(route).Inc()
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.retryAfter)))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"data": nil, "error": helpers.NewError("too_many_requests")})
+		}
+
+		return c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
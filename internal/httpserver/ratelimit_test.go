@@ -0,0 +1,122 @@
+package httpserver
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"ip_service/pkg/model"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterClientPrefix(t *testing.T) {
+	limiter, err := newRateLimiter(&model.RateLimit{
+		Allowlist:        []string{"192.0.2.0/24"},
+		IPv6PrefixLength: 48,
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	tts := []struct {
+		name     string
+		clientIP string
+		want     string
+		wantOK   bool
+	}{
+		{name: "ipv4", clientIP: "198.51.100.7", want: "198.51.100.7/32", wantOK: true},
+		{name: "ipv6", clientIP: "2001:db8:1:2::1", want: "2001:db8:1::/48", wantOK: true},
+		{name: "mapped ipv4", clientIP: "::ffff:198.51.100.7", want: "198.51.100.7/32", wantOK: true},
+		{name: "forwarded for list", clientIP: "198.51.100.7, 10.0.0.1"},
+		{name: "allowlisted", clientIP: "192.0.2.10"},
+		{name: "invalid", clientIP: "unknown"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := limiter.clientPrefix(tt.clientIP)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, got.String())
+			}
+		})
+	}
+}
+
+func TestMiddlewareRateLimit(t *testing.T) {
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			APIServer: model.APIServer{
+				RateLimit: model.RateLimit{
+					Enabled: true,
+					Routes: map[string]model.RateLimitBucket{
+						"/lookup/:ip": {Rate: 0.1, Burst: 2},
+					},
+					Allowlist: []string{"192.0.2.0/24"},
+				},
+			},
+		},
+	}
+
+	limiter, err := newRateLimiter(&cfg.IPService.APIServer.RateLimit)
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	s := &Service{config: cfg, metrics: serviceMetrics, rateLimiter: limiter}
+	s.behindProxy.Store(true)
+
+	// app.Test does not use the request remote address
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	for _, route := range []string{"/lookup/:ip", "/city"} {
+		app.Get(route, s.middlewareRateLimit(route), func(c *fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+	}
+
+	do := func(path, clientIP string) (int, map[string]string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Forwarded-For", clientIP)
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp.StatusCode, map[string]string{
+			"RateLimit-Limit":     resp.Header.Get("RateLimit-Limit"),
+			"RateLimit-Remaining": resp.Header.Get("RateLimit-Remaining"),
+			"Retry-After":         resp.Header.Get("Retry-After"),
+		}
+	}
+
+	code, headers := do("/lookup/198.51.100.1", "198.51.100.7")
+	assert.Equal(t, fiber.StatusOK, code)
+	assert.Equal(t, "2", headers["RateLimit-Limit"])
+	assert.Equal(t, "1", headers["RateLimit-Remaining"])
+
+	code, _ = do("/lookup/198.51.100.2", "198.51.100.7")
+	assert.Equal(t, fiber.StatusOK, code)
+
+	code, headers = do("/lookup/198.51.100.3", "198.51.100.7")
+	assert.Equal(t, fiber.StatusTooManyRequests, code)
+	assert.Equal(t, "0", headers["RateLimit-Remaining"])
+	assert.NotEmpty(t, headers["Retry-After"])
+
+	// other clients have their own bucket
+	code, _ = do("/lookup/198.51.100.3", "198.51.100.8")
+	assert.Equal(t, fiber.StatusOK, code)
+
+	// entries written by the client do not get it a new bucket
+	code, _ = do("/lookup/198.51.100.3", "203.0.113.1, 198.51.100.7")
+	assert.Equal(t, fiber.StatusTooManyRequests, code)
+
+	// routes without a configured bucket are not limited
+	for range 5 {
+		code, headers = do("/city", "198.51.100.7")
+		assert.Equal(t, fiber.StatusOK, code)
+		assert.Empty(t, headers["RateLimit-Limit"])
+	}
+
+	// allowlisted clients are not limited
+	for range 5 {
+		code, _ = do("/lookup/198.51.100.3", "192.0.2.10")
+		assert.Equal(t, fiber.StatusOK, code)
+	}
+}
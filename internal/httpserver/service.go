@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"io/fs"
 	"mime"
+	"net"
 	"net/http"
 	"net/http/pprof"
+	"net/netip"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 
 	"ip_service/internal/apiv1"
+	"ip_service/internal/store"
 	"ip_service/pkg/contexthandler"
 	"ip_service/pkg/helpers"
 	"ip_service/pkg/model"
@@ -32,20 +36,26 @@ const (
 	MIMEPlain = "text/plain"
 	MIMEJSON  = "application/json"
 	MIMEHTML  = "text/html"
+	MIMEAtom  = "application/atom+xml"
 )
 
 // Service is the service object for httpserver
 type Service struct {
-	config  *model.Cfg
-	logger  *logger.Log
-	TP      *trace.Tracer
-	metrics *metrics
-	apiv1   Apiv1
-	app     *fiber.App
+	config      *model.Cfg
+	logger      *logger.Log
+	TP          *trace.Tracer
+	metrics     *metrics
+	apiv1       Apiv1
+	app         *fiber.App
+	rateLimiter *rateLimiter
+	apiKeys     *apiKeys
+	// behindProxy and trustedProxyHops are hot reloadable
+	behindProxy      atomic.Bool
+	trustedProxyHops atomic.Int32
 }
 
 // New creates a new httpserver service
-func New(ctx context.Context, cfg *model.Cfg, api *apiv1.Client, tp *trace.Tracer, logger *logger.Log) (*Service, error) {
+func New(ctx context.Context, cfg *model.Cfg, api *apiv1.Client, store *store.Service, tp *trace.Tracer, logger *logger.Log) (*Service, error) {
 	tmplFS, _ := fs.Sub(templatesFS, "templates")
 	engine := html.NewFileSystem(http.FS(tmplFS), ".html")
 
@@ -53,11 +63,30 @@ func New(ctx context.Context, cfg *model.Cfg, api *apiv1.Client, tp *trace.Trace
 		config:  cfg,
 		logger:  logger,
 		TP:      tp,
-		metrics: &metrics{},
+		metrics: serviceMetrics,
 		apiv1:   api,
 	}
 
-	s.metrics.init()
+	s.behindProxy.Store(cfg.IPService.APIServer.BehindProxy)
+	s.trustedProxyHops.Store(int32(cfg.IPService.APIServer.TrustedProxyHops))
+
+	var err error
+	s.apiKeys, err = newAPIKeys(&cfg.IPService.APIServer.APIKeys, store.KV)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAdminConfig(cfg.IPService.APIServer); err != nil {
+		return nil, err
+	}
+
+	// api key tiers have their own quota, even without anonymous rate limiting
+	if cfg.IPService.APIServer.RateLimit.Enabled || len(cfg.IPService.APIServer.APIKeys.Tiers) > 0 {
+		s.rateLimiter, err = newRateLimiter(&cfg.IPService.APIServer.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	s.app = fiber.New(fiber.Config{
 		Views:                 engine,
@@ -83,6 +112,7 @@ func New(ctx context.Context, cfg *model.Cfg, api *apiv1.Client, tp *trace.Trace
 
 	// Middlewares
 	s.app.Use(s.middlewareDuration(ctx))
+	s.app.Use(s.middlewareMetrics(ctx))
 	s.app.Use(s.middlewareTraceID(ctx))
 	s.app.Use(s.middlewareLogger(ctx))
 	s.app.Use(s.middlewareCrash(ctx))
@@ -104,7 +134,27 @@ func New(ctx context.Context, cfg *model.Cfg, api *apiv1.Client, tp *trace.Trace
 
 	s.regEndpoint(ctx, "GET", "/whois/:ip", s.endpointWhois)
 
+	s.regEndpoint(ctx, "GET", "/blocklist/:ip", s.endpointBlocklist)
+
+	s.regEndpoint(ctx, "GET", "/irr/changes", s.endpointIRRChanges)
+
 	s.regEndpoint(ctx, "GET", "/health", s.endpointHealth)
+	s.regEndpoint(ctx, "GET", "/health/live", s.endpointHealthLive)
+	s.regEndpoint(ctx, "GET", "/health/ready", s.endpointHealth)
+
+	// Admin
+	if cfg.IPService.APIServer.Admin.Enable {
+		s.regAdminEndpoint(ctx, "GET", "/admin/datasets", s.endpointAdminDatasets)
+		s.regAdminEndpoint(ctx, "POST", "/admin/datasets/:name/update", s.endpointAdminUpdateDataset)
+		s.regAdminEndpoint(ctx, "POST", "/admin/datasets/:name/rollback", s.endpointAdminRollbackDataset)
+		s.regAdminEndpoint(ctx, "GET", "/admin/store/*", s.endpointAdminGetStoreKey)
+		s.regAdminEndpoint(ctx, "PUT", "/admin/store/*", s.endpointAdminSetStoreKey)
+		s.regAdminEndpoint(ctx, "GET", "/admin/api_keys", s.endpointAdminListAPIKeys)
+		s.regAdminEndpoint(ctx, "POST", "/admin/api_keys", s.endpointAdminAddAPIKey)
+		s.regAdminEndpoint(ctx, "DELETE", "/admin/api_keys/:hash", s.endpointAdminDelAPIKey)
+		s.regAdminEndpoint(ctx, "PUT", "/admin/log/level", s.endpointAdminLogLevel)
+		s.regAdminEndpoint(ctx, "GET", "/admin/export/mmdb", s.endpointAdminExportMMDB)
+	}
 
 	// Metrics
 	s.app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
@@ -117,12 +167,26 @@ func New(ctx context.Context, cfg *model.Cfg, api *apiv1.Client, tp *trace.Trace
 
 	// 404 handler
 	s.app.Use(func(c *fiber.Ctx) error {
+		c.Locals(localsUnmatchedRoute, true)
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": helpers.Problem404(), "data": nil})
 	})
 
+	var ln net.Listener
+	if cfg.IPService.APIServer.TLS.Enable {
+		ln, err = tlsListener(cfg.IPService.APIServer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Run http server
 	go func() {
-		err := s.app.Listen(cfg.IPService.APIServer.Addr)
+		var err error
+		if ln != nil {
+			err = s.app.Listener(ln)
+		} else {
+			err = s.app.Listen(cfg.IPService.APIServer.Addr)
+		}
 		if err != nil {
 			s.logger.New("http").Error(err, "listen_error")
 		}
@@ -138,6 +202,8 @@ func New(ctx context.Context, cfg *model.Cfg, api *apiv1.Client, tp *trace.Trace
 func (s *Service) getAccept(c *fiber.Ctx) string {
 	accept := c.Get("Accept")
 	switch {
+	case strings.Contains(accept, MIMEAtom):
+		return MIMEAtom
 	case strings.Contains(accept, MIMEJSON):
 		return MIMEJSON
 	case strings.Contains(accept, MIMEPlain):
@@ -151,32 +217,71 @@ func (s *Service) getAccept(c *fiber.Ctx) string {
 	}
 }
 
+// getLanguage returns the first language of the Accept-Language header
+func getLanguage(c *fiber.Ctx) string {
+	first, _, _ := strings.Cut(c.Get(fiber.HeaderAcceptLanguage), ",")
+	language, _, _ := strings.Cut(first, ";")
+	return strings.TrimSpace(language)
+}
+
 // clientIP extracts the real client IP from the request.
-// If proxy_header is configured, it reads the IP from that header.
-// Otherwise, it uses the direct remote address.
+// Behind a proxy, it reads the IP from the X-Forwarded-For entry appended by the trusted proxies.
+// Otherwise, or when that entry is not an IP, it uses the direct remote address.
 func (s *Service) clientIP(c *fiber.Ctx) string {
-	if s.config.IPService.APIServer.BehindProxy {
-		if ip := c.Get("X-Forwarded-For"); ip != "" {
+	if s.behindProxy.Load() {
+		if ip, ok := forwardedFor(c.Get(fiber.HeaderXForwardedFor), int(s.trustedProxyHops.Load())); ok {
 			return ip
 		}
 	}
 	return c.Context().RemoteIP().String()
 }
 
+// forwardedFor returns the client IP of an X-Forwarded-For header passed through hops trusted proxies, each
+// appending the address it received the request from. The entries to the left of the ones they appended are written
+// by the client, so they are not used.
+func forwardedFor(header string, hops int) (string, bool) {
+	if header == "" {
+		return "", false
+	}
+	if hops < 1 {
+		hops = 1
+	}
+
+	entries := strings.Split(header, ",")
+	addr, err := netip.ParseAddr(strings.TrimSpace(entries[max(len(entries)-hops, 0)]))
+	if err != nil {
+		return "", false
+	}
+
+	return addr.Unmap().String(), true
+}
+
 func (s *Service) regEndpoint(ctx context.Context, method, path string, handler func(context.Context, *fiber.Ctx) (any, error)) {
-	s.app.Add(method, path, func(c *fiber.Ctx) error {
+	s.app.Add(method, path, s.middlewareAPIKey(ctx, path), s.middlewareRateLimit(path), s.handle(ctx, handler))
+}
+
+// handle adds the request context, calls handler and writes its reply in the accepted content type
+func (s *Service) handle(ctx context.Context, handler func(context.Context, *fiber.Ctx) (any, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
 		clientIP := s.clientIP(c)
-		s.logger.Debug("register endpoint", "method", method, "path", path, "clientip", clientIP)
-		ctx = contexthandler.Add(ctx, "request", &contexthandler.RequestContext{
+		s.logger.Debug("handle request", "method", c.Method(), "path", c.Route().Path, "clientip", clientIP)
+		ctx := contexthandler.Add(ctx, "request", &contexthandler.RequestContext{
 			ClientIP:  clientIP,
 			UserAgent: string(c.Request().Header.UserAgent()),
 			Accept:    s.getAccept(c),
+			Language:  getLanguage(c),
 		})
 		res, err := handler(ctx, c)
 		if err != nil {
 			return c.Status(400).JSON(fiber.Map{"data": nil, "error": helpers.NewErrorFromError(err)})
 		}
 
+		if file, ok := res.(*apiv1.FileReply); ok {
+			c.Set(fiber.HeaderContentType, file.ContentType)
+			c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", file.Name))
+			return c.Send(file.Data)
+		}
+
 		requestValues, err := contexthandler.Get(ctx, "request")
 		if err != nil {
 			return c.Status(400).JSON(fiber.Map{"data": nil, "error": helpers.NewErrorFromError(err)})
@@ -188,17 +293,39 @@ func (s *Service) regEndpoint(ctx context.Context, method, path string, handler
 			case *model.ReplyIPInformation:
 				return c.Render("index", r)
 			}
+		case MIMEAtom:
+			switch r := res.(type) {
+			case *apiv1.IRRChangesReply:
+				feed, err := r.Atom()
+				if err != nil {
+					return c.Status(400).JSON(fiber.Map{"data": nil, "error": helpers.NewErrorFromError(err)})
+				}
+				c.Set(fiber.HeaderContentType, MIMEAtom)
+				return c.Send(feed)
+			}
+			return c.JSON(res)
 		case MIMEJSON:
 			return c.JSON(res)
 		case MIMEPlain:
 			return c.SendString(fmt.Sprintf("%v\n", res))
 		}
 		return nil
-	})
+	}
+}
+
+// Reload applies the hot reloadable settings of cfg, behind_proxy and trusted_proxy_hops
+func (s *Service) Reload(ctx context.Context, cfg *model.Cfg) {
+	s.behindProxy.Store(cfg.IPService.APIServer.BehindProxy)
+	s.trustedProxyHops.Store(int32(cfg.IPService.APIServer.TrustedProxyHops))
+	s.logger.Info("Reloaded", "behind_proxy", cfg.IPService.APIServer.BehindProxy, "trusted_proxy_hops", cfg.IPService.APIServer.TrustedProxyHops)
 }
 
 // Close closing httpserver
 func (s *Service) Close(ctx context.Context) error {
 	s.logger.Info("Quit")
+	if s.rateLimiter != nil {
+		s.rateLimiter.Close()
+	}
+	s.apiKeys.Close()
 	return s.app.Shutdown()
 }
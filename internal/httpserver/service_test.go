@@ -64,3 +64,31 @@ func TestAccept(t *testing.T) {
 		})
 	}
 }
+
+func TestForwardedFor(t *testing.T) {
+	tts := []struct {
+		name   string
+		header string
+		hops   int
+		want   string
+		wantOK bool
+	}{
+		{name: "single", header: "198.51.100.7", hops: 1, want: "198.51.100.7", wantOK: true},
+		{name: "rightmost of one proxy", header: "203.0.113.1, 198.51.100.7", hops: 1, want: "198.51.100.7", wantOK: true},
+		{name: "default hops", header: "203.0.113.1,198.51.100.7", want: "198.51.100.7", wantOK: true},
+		{name: "two proxies", header: "203.0.113.1, 198.51.100.7, 10.0.0.2", hops: 2, want: "198.51.100.7", wantOK: true},
+		{name: "fewer entries than hops", header: "198.51.100.7", hops: 3, want: "198.51.100.7", wantOK: true},
+		{name: "mapped ipv4", header: "::ffff:198.51.100.7", hops: 1, want: "198.51.100.7", wantOK: true},
+		{name: "ipv6", header: "2001:db8::1", hops: 1, want: "2001:db8::1", wantOK: true},
+		{name: "not an ip", header: "198.51.100.7, unknown", hops: 1},
+		{name: "empty", header: ""},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := forwardedFor(tt.header, tt.hops)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
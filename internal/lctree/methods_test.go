@@ -184,3 +184,31 @@ func BenchmarkFindTags_IPv6(b *testing.B) {
 		s.FindTags(ip)
 	}
 }
+
+func TestOnReload(t *testing.T) {
+	s := newTestService(t)
+
+	reloads := 0
+	s.OnReload(func(ctx context.Context) {
+		reloads++
+	})
+
+	assert.NoError(t, s.Build(context.Background(), rpsl.RouterClass{"10.0.0.0/8": nil}))
+	assert.NoError(t, s.Build(context.Background(), rpsl.RouterClass{"10.0.0.0/8": nil}))
+	assert.Equal(t, 2, reloads)
+}
+
+func TestStatus(t *testing.T) {
+	s := newTestService(t)
+
+	probe := s.Status(context.Background())
+	assert.False(t, probe.Healthy)
+	assert.Equal(t, "tree is empty", probe.Message["status"])
+
+	assert.NoError(t, s.Build(context.Background(), rpsl.RouterClass{"10.0.0.0/8": nil, "2001:db8::/32": nil}))
+
+	probe = s.Status(context.Background())
+	assert.True(t, probe.Healthy)
+	assert.Equal(t, 1, probe.Message["v4_prefixes"])
+	assert.Equal(t, 1, probe.Message["v6_prefixes"])
+}
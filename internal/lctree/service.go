@@ -14,10 +14,12 @@ import (
 // Service wraps two patricia trees (IPv4 + IPv6) for fast longest-prefix-match lookups.
 // Tags are network prefix strings (e.g. "2001:db8::/32") matching rpsl.RouterClass keys.
 type Service struct {
-	v4  *tree.TreeV4[string]
-	v6  *tree.TreeV6[string]
-	mu  sync.RWMutex
-	log *logger.Log
+	v4          *tree.TreeV4[string]
+	v6          *tree.TreeV6[string]
+	mu          sync.RWMutex
+	log         *logger.Log
+	hooksMu     sync.Mutex
+	reloadHooks []func(ctx context.Context)
 }
 
 func New(log *logger.Log) *Service {
@@ -68,9 +70,23 @@ func (s *Service) Build(ctx context.Context, routerClass rpsl.RouterClass) error
 	s.mu.Unlock()
 
 	s.log.Info("Patricia tree built", "v4_prefixes", v4Count, "v6_prefixes", v6Count)
+
+	s.hooksMu.Lock()
+	for _, fn := range s.reloadHooks {
+		fn(ctx)
+	}
+	s.hooksMu.Unlock()
+
 	return nil
 }
 
+// OnReload registers fn to be called each time new trees have been swapped in
+func (s *Service) OnReload(fn func(ctx context.Context)) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.reloadHooks = append(s.reloadHooks, fn)
+}
+
 // FindTags returns all network prefixes that contain the given IP (from least to most specific).
 func (s *Service) FindTags(ip netip.Addr) []string {
 	s.mu.RLock()
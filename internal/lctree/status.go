@@ -0,0 +1,28 @@
+package lctree
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"time"
+)
+
+// Status returns the prefix count of the trees, empty trees are unhealthy
+func (s *Service) Status(ctx context.Context) *model.StatusProbe {
+	v4, v6 := s.CountTags()
+
+	probe := &model.StatusProbe{
+		Name:    "lctree",
+		Healthy: v4+v6 > 0,
+		Message: map[string]any{
+			"v4_prefixes": v4,
+			"v6_prefixes": v6,
+		},
+		LastCheckedTS: time.Now(),
+	}
+
+	if !probe.Healthy {
+		probe.Message["status"] = "tree is empty"
+	}
+
+	return probe
+}
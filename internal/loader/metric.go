@@ -0,0 +1,38 @@
+package loader
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics holds the prometheus metrics the loader keeps for a dataset, labeled by source name
+type Metrics struct {
+	prefixes     *prometheus.GaugeVec
+	lastSuccess  *prometheus.GaugeVec
+	lastFailure  *prometheus.GaugeVec
+	loadDuration *prometheus.HistogramVec
+}
+
+// NewMetrics registers ip_service_<name>_prefixes, _last_success_timestamp_seconds, _last_failure_timestamp_seconds
+// and _load_duration_seconds, with the source name in label. source names a source in the help texts.
+func NewMetrics(name, label, source string) *Metrics {
+	return &Metrics{
+		prefixes: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ip_service_" + name + "_prefixes",
+			Help: "Prefixes in the loaded " + source,
+		}, []string{label, "family"}),
+		lastSuccess: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ip_service_" + name + "_last_success_timestamp_seconds",
+			Help: "Time of the last successfully loaded " + source,
+		}, []string{label}),
+		lastFailure: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ip_service_" + name + "_last_failure_timestamp_seconds",
+			Help: "Time of the last failed " + source + " load",
+		}, []string{label}),
+		loadDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ip_service_" + name + "_load_duration_seconds",
+			Help:    "Time spent loading and indexing a " + source,
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+		}, []string{label}),
+	}
+}
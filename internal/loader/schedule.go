@@ -0,0 +1,114 @@
+package loader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+)
+
+// Schedule runs the update of a dataset on a ticker and on demand, and calls the reload hooks after each update
+// that reports a reload
+type Schedule struct {
+	log          *logger.Log
+	update       func(ctx context.Context) bool
+	updateMu     sync.Mutex
+	updating     atomic.Bool
+	lastUpdate   atomic.Int64
+	updateTicker *time.Ticker
+	updateChan   chan struct{}
+	quitChan     chan struct{}
+	hooksMu      sync.Mutex
+	reloadHooks  []func(ctx context.Context)
+}
+
+// NewSchedule runs update once, then every updatePeriodicity and when triggered until Close. update reports whether
+// the dataset was reloaded.
+func NewSchedule(ctx context.Context, updatePeriodicity time.Duration, update func(ctx context.Context) bool, log *logger.Log) *Schedule {
+	s := &Schedule{
+		log:          log,
+		update:       update,
+		updateTicker: time.NewTicker(updatePeriodicity),
+		updateChan:   make(chan struct{}, 1),
+		quitChan:     make(chan struct{}),
+	}
+
+	s.Update(ctx)
+
+	go func() {
+		for {
+			select {
+			case <-s.updateTicker.C:
+				s.Update(ctx)
+			case <-s.updateChan:
+				s.Update(ctx)
+			case <-s.quitChan:
+				s.log.Info("Stopping update")
+				return
+			}
+		}
+	}()
+
+	return s
+}
+
+// Update runs the update now and waits for it
+func (s *Schedule) Update(ctx context.Context) {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	s.updating.Store(true)
+	reloaded := s.update(ctx)
+	s.updating.Store(false)
+	s.lastUpdate.Store(time.Now().UnixNano())
+
+	if !reloaded {
+		return
+	}
+
+	s.hooksMu.Lock()
+	for _, fn := range s.reloadHooks {
+		fn(ctx)
+	}
+	s.hooksMu.Unlock()
+}
+
+// Updating reports whether an update is running
+func (s *Schedule) Updating() bool {
+	return s.updating.Load()
+}
+
+// LastUpdate returns when the last update finished
+func (s *Schedule) LastUpdate() time.Time {
+	return time.Unix(0, s.lastUpdate.Load())
+}
+
+// OnReload registers fn to be called each time the dataset has been reloaded
+func (s *Schedule) OnReload(fn func(ctx context.Context)) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.reloadHooks = append(s.reloadHooks, fn)
+}
+
+// TriggerUpdate queues an update
+func (s *Schedule) TriggerUpdate(ctx context.Context) {
+	select {
+	case s.updateChan <- struct{}{}:
+	default:
+	}
+}
+
+// Reset changes the update periodicity
+func (s *Schedule) Reset(updatePeriodicity time.Duration) {
+	s.updateTicker.Reset(updatePeriodicity)
+}
+
+// Close stops the update loop
+func (s *Schedule) Close(ctx context.Context) error {
+	s.updateTicker.Stop()
+	close(s.quitChan)
+	s.log.Info("Quit")
+	return nil
+}
@@ -0,0 +1,193 @@
+// Package loader loads the range, feed and list files of a dataset into prefix tables on a schedule, from a url or a
+// local file, and keeps the previous table of a source that fails to load. The dataset packages only parse.
+package loader
+
+import (
+	"context"
+	"io"
+	"ip_service/pkg/model"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+)
+
+// Dataset describes a dataset to the loader, S is its source configuration and V the value of its prefixes
+type Dataset[S, V any] struct {
+	// Kind is the dataset kind in the admin datasets reply and the name of its readiness probe, e.g. georange
+	Kind    string
+	Metrics *Metrics
+	// DefaultUpdatePeriodicity is used when the configured update_periodicity is 0
+	DefaultUpdatePeriodicity time.Duration
+	// Config returns the configured update_periodicity and sources of the dataset
+	Config func(cfg *model.Cfg) (time.Duration, []S)
+	// Source returns where source is read from
+	Source func(source S) Source
+	// Parse reads the content of source into t
+	Parse func(source S, r io.Reader, t *Table[V]) error
+}
+
+// Loader loads the sources of a dataset into tables, in configured order
+type Loader[S, V any] struct {
+	*Schedule
+	dataset    Dataset[S, V]
+	cfg        *model.Cfg
+	log        *logger.Log
+	httpClient *http.Client
+	tables     atomic.Pointer[[]*Table[V]]
+}
+
+// New creates a loader and loads the sources, a source that fails to load is retried on the next update
+func New[S, V any](ctx context.Context, cfg *model.Cfg, dataset Dataset[S, V], log *logger.Log) *Loader[S, V] {
+	l := &Loader[S, V]{
+		dataset: dataset,
+		cfg:     cfg,
+		log:     log,
+		httpClient: &http.Client{
+			Timeout: 120 * time.Second,
+		},
+	}
+	l.tables.Store(&[]*Table[V]{})
+
+	l.Schedule = NewSchedule(ctx, l.updatePeriodicity(cfg), l.update, log)
+
+	l.log.Info("Started")
+
+	return l
+}
+
+func (l *Loader[S, V]) updatePeriodicity(cfg *model.Cfg) time.Duration {
+	if updatePeriodicity, _ := l.dataset.Config(cfg); updatePeriodicity > 0 {
+		return updatePeriodicity
+	}
+	return l.dataset.DefaultUpdatePeriodicity
+}
+
+// Tables returns the loaded tables, in configured order
+func (l *Loader[S, V]) Tables() []*Table[V] {
+	return *l.tables.Load()
+}
+
+// update loads every source and swaps in the new tables, a source that fails keeps its previous table. It reports
+// whether a source was loaded, so the reload hooks are not called when every source kept its previous table.
+func (l *Loader[S, V]) update(ctx context.Context) bool {
+	previous := l.loaded()
+	reloaded := false
+
+	_, sources := l.dataset.Config(l.cfg)
+	tables := make([]*Table[V], 0, len(sources))
+	for _, source := range sources {
+		name := l.dataset.Source(source).Name
+		start := time.Now()
+
+		t, err := l.load(ctx, source)
+		if err != nil {
+			l.log.Error(err, "failed to load, keeping the previous table", "source", name)
+			l.dataset.Metrics.lastFailure.WithLabelValues(name).SetToCurrentTime()
+			if t, ok := previous[name]; ok {
+				tables = append(tables, t)
+			}
+			continue
+		}
+
+		l.dataset.Metrics.loadDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+		l.dataset.Metrics.lastSuccess.WithLabelValues(name).SetToCurrentTime()
+		tables = append(tables, t)
+		reloaded = true
+	}
+
+	l.tables.Store(&tables)
+
+	return reloaded
+}
+
+// load reads and parses a source into a table
+func (l *Loader[S, V]) load(ctx context.Context, source S) (*Table[V], error) {
+	location := l.dataset.Source(source)
+
+	rc, modified, err := Open(ctx, l.httpClient, location)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	t := NewTable[V](location.Name, modified)
+	if err := l.dataset.Parse(source, rc, t); err != nil {
+		return nil, err
+	}
+
+	t.Loaded = time.Now()
+
+	v4Count, v6Count := t.Len()
+	l.dataset.Metrics.prefixes.WithLabelValues(location.Name, "ipv4").Set(float64(v4Count))
+	l.dataset.Metrics.prefixes.WithLabelValues(location.Name, "ipv6").Set(float64(v6Count))
+
+	l.log.Info("Loaded", "source", location.Name, "skipped", t.Skipped, "v4_prefixes", v4Count, "v6_prefixes", v6Count)
+
+	return t, nil
+}
+
+// Reload applies the hot reloadable settings of cfg, update_periodicity
+func (l *Loader[S, V]) Reload(ctx context.Context, cfg *model.Cfg) {
+	l.Reset(l.updatePeriodicity(cfg))
+	l.log.Info("Reloaded", "update_periodicity", l.updatePeriodicity(cfg))
+}
+
+// State returns the load state of each source, a source that was never loaded has no build time
+func (l *Loader[S, V]) State(ctx context.Context) []*model.DatasetState {
+	loaded := l.loaded()
+
+	_, sources := l.dataset.Config(l.cfg)
+	reply := make([]*model.DatasetState, 0, len(sources))
+	for _, source := range sources {
+		name := l.dataset.Source(source).Name
+		state := &model.DatasetState{
+			Name:        name,
+			Kind:        l.dataset.Kind,
+			Parsing:     l.Updating(),
+			LastChecked: l.LastUpdate().UTC().Format(time.RFC3339),
+		}
+		if t, ok := loaded[name]; ok {
+			state.BuildTime = t.Modified
+		}
+		reply = append(reply, state)
+	}
+
+	return reply
+}
+
+// Status fails when a source has never been loaded, a source that fails to reload keeps serving its previous table
+func (l *Loader[S, V]) Status(ctx context.Context) *model.StatusProbe {
+	probe := &model.StatusProbe{
+		Name:          l.dataset.Kind,
+		Healthy:       true,
+		Message:       map[string]any{},
+		LastCheckedTS: time.Now(),
+	}
+
+	loaded := l.loaded()
+
+	_, sources := l.dataset.Config(l.cfg)
+	for _, source := range sources {
+		name := l.dataset.Source(source).Name
+		t, ok := loaded[name]
+		if !ok {
+			probe.Healthy = false
+			probe.Message[name+"_status"] = "not loaded"
+			continue
+		}
+		probe.Message[name+"_loaded"] = t.Loaded.UTC().Format(time.RFC3339)
+	}
+
+	return probe
+}
+
+// loaded returns the loaded tables by source name
+func (l *Loader[S, V]) loaded() map[string]*Table[V] {
+	loaded := map[string]*Table[V]{}
+	for _, t := range l.Tables() {
+		loaded[t.Name] = t
+	}
+	return loaded
+}
@@ -0,0 +1,209 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"ip_service/pkg/model"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMetrics = NewMetrics("loader_test", "source", "test table")
+
+// testDataset reads one prefix per line, tagged with the source name
+func testDataset(sources ...model.GeoRangeSource) Dataset[model.GeoRangeSource, string] {
+	return Dataset[model.GeoRangeSource, string]{
+		Metrics:                  testMetrics,
+		DefaultUpdatePeriodicity: time.Hour,
+		Config: func(cfg *model.Cfg) (time.Duration, []model.GeoRangeSource) {
+			return 0, sources
+		},
+		Source: func(source model.GeoRangeSource) Source {
+			return Source{Name: source.Name, URL: source.URL, FilePath: source.FilePath, Token: source.Token}
+		},
+		Parse: func(source model.GeoRangeSource, r io.Reader, t *Table[string]) error {
+			scanner := bufio.NewScanner(r)
+			for scanner.Scan() {
+				prefix, err := netip.ParsePrefix(scanner.Text())
+				if err != nil {
+					return err
+				}
+				t.Set(prefix, source.Name)
+			}
+			return scanner.Err()
+		},
+	}
+}
+
+func lookup(l *Loader[model.GeoRangeSource, string], addr string) []string {
+	var names []string
+	for _, t := range l.Tables() {
+		if name, ok := t.Lookup(netip.MustParseAddr(addr)); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "local.txt")
+	require.NoError(t, os.WriteFile(local, []byte("192.0.2.0/24\n2001:db8::/32\n"), 0600))
+
+	gz := &bytes.Buffer{}
+	gzw := gzip.NewWriter(gz)
+	_, err := gzw.Write([]byte("192.0.2.0/25\n"))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(gz.Bytes())
+	}))
+	defer server.Close()
+
+	l := New(context.TODO(), &model.Cfg{}, testDataset(
+		model.GeoRangeSource{Name: "local", FilePath: local},
+		model.GeoRangeSource{Name: "remote", URL: server.URL + "/list.txt.gz", Token: "secret"},
+		model.GeoRangeSource{Name: "missing", FilePath: filepath.Join(dir, "missing.txt")},
+	), logger.NewSimple("test"))
+	defer l.Close(context.TODO())
+
+	require.Len(t, l.Tables(), 2)
+	assert.Equal(t, []string{"local", "remote"}, lookup(l, "192.0.2.1"))
+	assert.Equal(t, []string{"local"}, lookup(l, "192.0.2.129"))
+	assert.Equal(t, []string{"local"}, lookup(l, "2001:db8::1"))
+	assert.Nil(t, lookup(l, "198.51.100.1"))
+
+	v4, v6 := l.Tables()[0].Len()
+	assert.Equal(t, 1, v4)
+	assert.Equal(t, 1, v6)
+	assert.False(t, l.Tables()[0].Modified.IsZero())
+}
+
+func TestTableDuplicatePrefixes(t *testing.T) {
+	table := NewTable[string]("test", time.Time{})
+	table.Set(netip.MustParsePrefix("192.0.2.0/24"), "first")
+	table.Set(netip.MustParsePrefix("192.0.2.0/24"), "second")
+	table.Set(netip.MustParsePrefix("2001:db8::/32"), "first")
+	table.Set(netip.MustParsePrefix("2001:db8::/32"), "second")
+
+	v4, v6 := table.Len()
+	assert.Equal(t, 1, v4)
+	assert.Equal(t, 1, v6)
+
+	value, ok := table.Lookup(netip.MustParseAddr("192.0.2.1"))
+	assert.True(t, ok)
+	assert.Equal(t, "second", value)
+}
+
+func TestUpdateKeepsPreviousTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	require.NoError(t, os.WriteFile(path, []byte("192.0.2.0/24\n"), 0600))
+
+	l := New(context.TODO(), &model.Cfg{}, testDataset(model.GeoRangeSource{Name: "local", FilePath: path}), logger.NewSimple("test"))
+	defer l.Close(context.TODO())
+
+	reloads := 0
+	l.OnReload(func(ctx context.Context) { reloads++ })
+
+	// the previous table is kept, nothing was reloaded
+	require.NoError(t, os.WriteFile(path, []byte("<html>rate limited</html>\n"), 0600))
+	l.Update(context.TODO())
+	assert.Equal(t, 0, reloads)
+	assert.Equal(t, []string{"local"}, lookup(l, "192.0.2.1"))
+
+	require.NoError(t, os.WriteFile(path, []byte("198.51.100.0/24\n"), 0600))
+	l.Update(context.TODO())
+	assert.Equal(t, 1, reloads)
+	assert.Nil(t, lookup(l, "192.0.2.1"))
+	assert.Equal(t, []string{"local"}, lookup(l, "198.51.100.1"))
+}
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "local.txt")
+	require.NoError(t, os.WriteFile(local, []byte("192.0.2.0/24\n"), 0600))
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(local, modified, modified))
+
+	dataset := testDataset(
+		model.GeoRangeSource{Name: "local", FilePath: local},
+		model.GeoRangeSource{Name: "missing", FilePath: filepath.Join(dir, "missing.txt")},
+	)
+	dataset.Kind = model.DatasetKindGeoRange
+	l := New(context.TODO(), &model.Cfg{}, dataset, logger.NewSimple("test"))
+	defer l.Close(context.TODO())
+
+	states := l.State(context.TODO())
+	require.Len(t, states, 2)
+	assert.Equal(t, "local", states[0].Name)
+	assert.Equal(t, model.DatasetKindGeoRange, states[0].Kind)
+	assert.True(t, modified.Equal(states[0].BuildTime))
+	assert.NotEmpty(t, states[0].LastChecked)
+	assert.Equal(t, "missing", states[1].Name)
+	assert.True(t, states[1].BuildTime.IsZero())
+
+	probe := l.Status(context.TODO())
+	assert.Equal(t, model.DatasetKindGeoRange, probe.Name)
+	assert.False(t, probe.Healthy)
+	assert.Equal(t, "not loaded", probe.Message["missing_status"])
+	assert.Contains(t, probe.Message, "local_loaded")
+}
+
+func TestSchedule(t *testing.T) {
+	updates := make(chan struct{}, 10)
+	reloaded := false
+	s := NewSchedule(context.TODO(), time.Hour, func(ctx context.Context) bool {
+		updates <- struct{}{}
+		return reloaded
+	}, logger.NewSimple("test"))
+	defer s.Close(context.TODO())
+	<-updates
+
+	reloads := 0
+	s.OnReload(func(ctx context.Context) { reloads++ })
+
+	// an update without a reload does not call the hooks
+	s.Update(context.TODO())
+	<-updates
+	assert.Equal(t, 0, reloads)
+
+	reloaded = true
+	s.Update(context.TODO())
+	<-updates
+	assert.Equal(t, 1, reloads)
+
+	s.TriggerUpdate(context.TODO())
+	select {
+	case <-updates:
+	case <-time.After(time.Second):
+		require.Fail(t, "triggered update did not run")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	_, _, err := Open(context.TODO(), http.DefaultClient, Source{Name: "missing", FilePath: filepath.Join(t.TempDir(), "missing.gz")})
+	assert.True(t, errors.Is(err, os.ErrNotExist), "got %v", err)
+
+	path := filepath.Join(t.TempDir(), "list.txt.gz")
+	require.NoError(t, os.WriteFile(path, []byte("not gzip"), 0600))
+	_, _, err = Open(context.TODO(), http.DefaultClient, Source{Name: "broken", FilePath: path})
+	assert.Error(t, err)
+}
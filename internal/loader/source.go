@@ -0,0 +1,109 @@
+package loader
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Source is where a table is read from, a url fetched with an optional bearer token or a local file
+type Source struct {
+	Name     string
+	URL      string
+	FilePath string
+	Token    string
+}
+
+// path returns the path of url or file_path, for its suffix
+func (source Source) path() string {
+	if source.URL == "" {
+		return source.FilePath
+	}
+	u, err := url.Parse(source.URL)
+	if err != nil {
+		return source.URL
+	}
+	return u.Path
+}
+
+// Open returns the content of source and its modification time when known, content ending in .gz or .bz2 is
+// decompressed
+func Open(ctx context.Context, httpClient *http.Client, source Source) (io.ReadCloser, time.Time, error) {
+	rc, modified, err := open(ctx, httpClient, source)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	switch path := source.path(); {
+	case strings.HasSuffix(path, ".gz"):
+		gzr, err := gzip.NewReader(rc)
+		if err != nil {
+			rc.Close()
+			return nil, time.Time{}, err
+		}
+		return &readCloser{Reader: gzr, closers: []io.Closer{gzr, rc}}, modified, nil
+	case strings.HasSuffix(path, ".bz2"):
+		return &readCloser{Reader: bzip2.NewReader(rc), closers: []io.Closer{rc}}, modified, nil
+	}
+
+	return rc, modified, nil
+}
+
+func open(ctx context.Context, httpClient *http.Client, source Source) (io.ReadCloser, time.Time, error) {
+	if source.URL == "" {
+		f, err := os.Open(filepath.Clean(source.FilePath))
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, time.Time{}, err
+		}
+		return f, info.ModTime(), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if source.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+source.Token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, time.Time{}, fmt.Errorf("errors http status code: %d", resp.StatusCode)
+	}
+
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return resp.Body, modified, nil
+}
+
+// readCloser reads the decompressed content and closes the decompressor and the content
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
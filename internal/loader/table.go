@@ -0,0 +1,77 @@
+package loader
+
+import (
+	"net/netip"
+	"time"
+
+	patricia "github.com/kentik/patricia"
+	tree "github.com/kentik/patricia/generics_tree"
+)
+
+// Table holds the prefixes of a source in a pair of patricia trees
+type Table[V any] struct {
+	Name string
+	// Modified is when the content was last changed, the modification time of the file or the Last-Modified of the
+	// response when known. A parser can replace it with a date found in the content.
+	Modified time.Time
+	// Loaded is when the table was loaded
+	Loaded time.Time
+	// Skipped counts the entries the parser could not use
+	Skipped int
+	v4      *tree.TreeV4[V]
+	v6      *tree.TreeV6[V]
+	v4Count int
+	v6Count int
+}
+
+// NewTable returns an empty table of the source name, modified is the time of its content when known
+func NewTable[V any](name string, modified time.Time) *Table[V] {
+	return &Table[V]{
+		Name:     name,
+		Modified: modified,
+		v4:       tree.NewTreeV4[V](),
+		v6:       tree.NewTreeV6[V](),
+	}
+}
+
+// Set adds prefix with value, a later value replaces an earlier one for the same prefix
+func (t *Table[V]) Set(prefix netip.Prefix, value V) {
+	v4Addr, v6Addr, err := patricia.ParseFromNetIPPrefix(prefix)
+	if err != nil {
+		t.Skipped++
+		return
+	}
+	// a prefix set again replaces its value and is counted once
+	if prefix.Addr().Is4() {
+		if added, _ := t.v4.Set(*v4Addr, value); added {
+			t.v4Count++
+		}
+	} else {
+		if added, _ := t.v6.Set(*v6Addr, value); added {
+			t.v6Count++
+		}
+	}
+}
+
+// Lookup returns the value of the most specific prefix holding addr
+func (t *Table[V]) Lookup(addr netip.Addr) (V, bool) {
+	var value V
+
+	v4Addr, v6Addr, err := patricia.ParseFromNetIPAddr(addr)
+	if err != nil {
+		return value, false
+	}
+
+	var found bool
+	if addr.Is4() {
+		found, value = t.v4.FindDeepestTag(*v4Addr)
+	} else {
+		found, value = t.v6.FindDeepestTag(*v6Addr)
+	}
+	return value, found
+}
+
+// Len returns the number of distinct IPv4 and IPv6 prefixes set
+func (t *Table[V]) Len() (v4, v6 int) {
+	return t.v4Count, t.v6Count
+}
@@ -0,0 +1,71 @@
+package maxmind
+
+import (
+	"context"
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+	"maps"
+	"slices"
+	"time"
+)
+
+// State returns the download and parse state of each database
+func (s *Service) State(ctx context.Context) []*model.DatasetState {
+	reply := []*model.DatasetState{}
+
+	for _, dbType := range slices.Sorted(maps.Keys(s.DBMeta)) {
+		state := &model.DatasetState{
+			Name:        dbType,
+			Kind:        model.DatasetKindMaxmind,
+			Downloading: s.DBMeta[dbType].Downloading.Load(),
+			Parsing:     s.DBMeta[dbType].Parsing.Load(),
+			Version:     s.kvStore.GetRemoteVersion(ctx, dbType),
+			LastChecked: s.kvStore.GetLastChecked(ctx, dbType),
+		}
+
+		if h, err := s.acquire(dbType); err == nil {
+			state.BuildTime = time.Unix(int64(h.reader.Metadata().BuildEpoch), 0).UTC()
+			h.release()
+		}
+
+		reply = append(reply, state)
+	}
+
+	return reply
+}
+
+// TriggerUpdate queues an update of the database, force downloads it even if the remote version is unchanged.
+// A local database is reloaded from its file instead.
+func (s *Service) TriggerUpdate(ctx context.Context, dbType string, force bool) error {
+	if _, ok := s.DBMeta[dbType]; !ok {
+		return helpers.ErrUnknownDataset
+	}
+
+	// a local database is never downloaded, it is reloaded from its file
+	if s.cfg.IPService.MaxMind.IsLocal(dbType) {
+		select {
+		case s.reloadChan <- dbType:
+			s.Log.Info("Reload triggered", "dbType", dbType)
+			return nil
+		default:
+			return helpers.ErrUpdateInProgress
+		}
+	}
+
+	if s.DBMeta.IsDownloadingInProgresses(dbType) {
+		return helpers.ErrUpdateInProgress
+	}
+
+	ch := s.updateChan
+	if force {
+		ch = s.downloadChan
+	}
+
+	select {
+	case ch <- dbType:
+		s.Log.Info("Update triggered", "dbType", dbType, "force", force)
+		return nil
+	default:
+		return helpers.ErrUpdateInProgress
+	}
+}
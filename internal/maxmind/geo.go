@@ -0,0 +1,68 @@
+package maxmind
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// LookupGeo returns the normalized geolocation of ip from the City database
+func (s *Service) LookupGeo(ctx context.Context, ip net.IP) (*model.GeoRecord, error) {
+	city, err := s.City(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return geoRecordOf(city, s.cfg.IPService.MaxMind.Provider(model.MaxmindDBTypeCity)), nil
+}
+
+// geoRecordOf normalizes a City record of provider
+func geoRecordOf(city *geoip2.City, provider string) *model.GeoRecord {
+	record := &model.GeoRecord{
+		City:          city.City.Names,
+		Country:       city.Country.Names,
+		CountryISO:    city.Country.IsoCode,
+		IsEU:          city.Country.IsInEuropeanUnion,
+		PostalCode:    city.Postal.Code,
+		Timezone:      city.Location.TimeZone,
+		Continent:     city.Continent.Names,
+		ContinentCode: city.Continent.Code,
+		Provider:      provider,
+	}
+
+	// the accuracy radius is only set for records with a location, 0,0 is a valid coordinate
+	if city.Location.AccuracyRadius > 0 || city.Location.Latitude != 0 || city.Location.Longitude != 0 {
+		record.Coordinates = &model.Coordinates{
+			Latitude:  city.Location.Latitude,
+			Longitude: city.Location.Longitude,
+		}
+	}
+
+	if len(city.Subdivisions) > 0 {
+		record.Region = city.Subdivisions[0].Names
+		record.RegionCode = city.Subdivisions[0].IsoCode
+	}
+
+	return record
+}
+
+// LookupASN returns the normalized origin AS of ip from the ASN database
+func (s *Service) LookupASN(ctx context.Context, ip net.IP) (*model.ASNRecord, error) {
+	asn, err := s.ASN(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return asnRecordOf(asn, s.cfg.IPService.MaxMind.Provider(model.MaxmindDBTypeASN)), nil
+}
+
+// asnRecordOf normalizes an ASN record of provider
+func asnRecordOf(asn *geoip2.ASN, provider string) *model.ASNRecord {
+	return &model.ASNRecord{
+		ASN:          asn.AutonomousSystemNumber,
+		Organization: asn.AutonomousSystemOrganization,
+		Provider:     provider,
+	}
+}
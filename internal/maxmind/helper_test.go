@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"ip_service/internal/store"
+	"ip_service/pkg/model"
 	"github.com/SUNET/vc/pkg/logger"
 	"github.com/SUNET/vc/pkg/trace"
 	"net/url"
 	"testing"
 
+	"github.com/oschwald/geoip2-golang"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,3 +51,26 @@ func mockService(t *testing.T, storeDir, serverURL string, preOpenDB bool) *Serv
 
 	return s
 }
+
+// newTestService returns a service with the database files loaded by type, without store, downloads or update loop
+func newTestService(tp *trace.Tracer, log *logger.Log, dbFiles map[string]string) (*Service, error) {
+	s := &Service{
+		probeStore: &model.StatusProbeStore{},
+		cfg:        &model.Cfg{IPService: &model.IPService{}},
+		Log:        log,
+		TP:         tp,
+		reloadChan: make(chan string, 10),
+		DBMeta:     DBMeta{},
+	}
+
+	for dbType, dbFile := range dbFiles {
+		db, err := geoip2.Open(dbFile)
+		if err != nil {
+			return nil, err
+		}
+		s.DBMeta[dbType] = &DBObject{}
+		s.swapReader(dbType, db, dbFile)
+	}
+
+	return s, nil
+}
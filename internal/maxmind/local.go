@@ -0,0 +1,68 @@
+package maxmind
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+const defaultPollInterval = time.Minute
+
+// fileState is the part of a file's stat that marks it as changed
+type fileState struct {
+	modTime int64
+	size    int64
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
+
+// initialLocal loads a local database and starts watching it, a missing or invalid file is loaded once it changes
+func (s *Service) initialLocal(ctx context.Context, dbType string) {
+	path := s.cfg.IPService.MaxMind.DBFilePath(dbType)
+
+	// stat before loading, so a change during the load is picked up by the next poll
+	state := statFile(path)
+
+	if err := s.loadDB(ctx, dbType); err != nil {
+		s.Log.Error(err, "failed to load local database, waiting for it to change", "dbType", dbType, "path", path)
+		metrics.lastFailure.WithLabelValues(dbType).SetToCurrentTime()
+	}
+
+	go s.watchLocalDB(ctx, dbType, path, state)
+}
+
+// watchLocalDB polls path for mtime and size changes and reloads the database through reloadChan
+func (s *Service) watchLocalDB(ctx context.Context, dbType, path string, state fileState) {
+	interval := s.cfg.IPService.MaxMind.DB[dbType].PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.Log.Info("Watching local database", "dbType", dbType, "path", path, "interval", interval)
+
+	for {
+		select {
+		case <-ticker.C:
+			current := statFile(path)
+			if current == state || current == (fileState{}) {
+				continue
+			}
+			state = current
+
+			s.Log.Info("Local database changed", "dbType", dbType, "path", path, "size", current.size, "modTime", time.Unix(0, current.modTime))
+			s.reloadChan <- dbType
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package maxmind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ip_service/pkg/model"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func copyTestFile(t *testing.T, src, dst string) {
+	b, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, b, 0600))
+}
+
+func TestLocalDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asn.mmdb")
+	copyTestFile(t, "../../testdata/GeoLite2-asn-Test.mmdb", path)
+
+	tracer, err := trace.NewForTesting(t.Context(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	s := &Service{
+		cfg: &model.Cfg{
+			IPService: &model.IPService{
+				MaxMind: model.MaxMind{
+					DB: map[string]model.MaxMindDB{
+						model.MaxmindDBTypeASN: {Source: model.MaxMindSourceLocal, FilePath: path, PollInterval: 10 * time.Millisecond},
+					},
+				},
+			},
+		},
+		Log:        logger.NewSimple("test"),
+		TP:         tracer,
+		reloadChan: make(chan string, 10),
+		DBMeta: DBMeta{
+			model.MaxmindDBTypeASN: &DBObject{},
+		},
+	}
+
+	s.initialLocal(t.Context(), model.MaxmindDBTypeASN)
+	loaded := s.DBMeta[model.MaxmindDBTypeASN].reader.Load()
+	require.NotNil(t, loaded)
+
+	// a city database does not validate as ASN, the loaded one is kept
+	copyTestFile(t, "../../testdata/GeoLite2-city-Test.mmdb", path)
+
+	select {
+	case dbType := <-s.reloadChan:
+		assert.Equal(t, model.MaxmindDBTypeASN, dbType)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change of the local database was not detected")
+	}
+
+	assert.Error(t, s.loadDB(t.Context(), model.MaxmindDBTypeASN))
+	assert.Same(t, loaded, s.DBMeta[model.MaxmindDBTypeASN].reader.Load())
+}
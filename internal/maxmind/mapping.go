@@ -0,0 +1,181 @@
+package maxmind
+
+import (
+	"fmt"
+	"ip_service/pkg/model"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// compatReader reads GeoIP2 shaped records from db's whose database type geoip2 does not know,
+// so unlike geoip2.Reader it does not refuse lookups that do not match the database type
+type compatReader struct {
+	db *maxminddb.Reader
+}
+
+func openCompatReader(path string) (geoReader, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &compatReader{db: db}, nil
+}
+
+func (r *compatReader) City(ip net.IP) (*geoip2.City, error) {
+	city := &geoip2.City{}
+	return city, r.db.Lookup(ip, city)
+}
+
+func (r *compatReader) ASN(ip net.IP) (*geoip2.ASN, error) {
+	asn := &geoip2.ASN{}
+	return asn, r.db.Lookup(ip, asn)
+}
+
+func (r *compatReader) ISP(ip net.IP) (*geoip2.ISP, error) {
+	isp := &geoip2.ISP{}
+	return isp, r.db.Lookup(ip, isp)
+}
+
+func (r *compatReader) AnonymousIP(ip net.IP) (*geoip2.AnonymousIP, error) {
+	anonymousIP := &geoip2.AnonymousIP{}
+	return anonymousIP, r.db.Lookup(ip, anonymousIP)
+}
+
+func (r *compatReader) Metadata() maxminddb.Metadata {
+	return r.db.Metadata
+}
+
+func (r *compatReader) Close() error {
+	return r.db.Close()
+}
+
+// ipinfoRecord holds the fields of the IPinfo db's, lite has the country and asn fields only
+type ipinfoRecord struct {
+	City          string `maxminddb:"city"`
+	Region        string `maxminddb:"region"`
+	RegionCode    string `maxminddb:"region_code"`
+	Country       string `maxminddb:"country"`
+	CountryCode   string `maxminddb:"country_code"`
+	Continent     string `maxminddb:"continent"`
+	ContinentCode string `maxminddb:"continent_code"`
+	PostalCode    string `maxminddb:"postal_code"`
+	Timezone      string `maxminddb:"timezone"`
+	// Latitude and Longitude are strings in some IPinfo db's and doubles in others
+	Latitude  any    `maxminddb:"lat"`
+	Longitude any    `maxminddb:"lng"`
+	ASN       string `maxminddb:"asn"`
+	ASName    string `maxminddb:"as_name"`
+}
+
+// ipinfoReader maps IPinfo records to the geoip2 records, names are in English only
+type ipinfoReader struct {
+	db *maxminddb.Reader
+}
+
+func (r *ipinfoReader) lookup(ip net.IP) (*ipinfoRecord, error) {
+	record := &ipinfoRecord{}
+	if err := r.db.Lookup(ip, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *ipinfoReader) City(ip net.IP) (*geoip2.City, error) {
+	record, err := r.lookup(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	city := &geoip2.City{}
+	city.City.Names = englishName(record.City)
+	city.Country.Names = englishName(record.Country)
+	city.Country.IsoCode = record.CountryCode
+	city.Country.IsInEuropeanUnion = model.IsEUCountry(record.CountryCode)
+	city.Continent.Names = englishName(record.Continent)
+	city.Continent.Code = record.ContinentCode
+	city.Postal.Code = record.PostalCode
+	city.Location.TimeZone = record.Timezone
+	city.Location.Latitude = toFloat(record.Latitude)
+	city.Location.Longitude = toFloat(record.Longitude)
+	if record.Region != "" {
+		// Subdivisions is a slice of an unnamed struct, grow it instead of spelling out the type
+		city.Subdivisions = slices.Grow(city.Subdivisions, 1)[:1]
+		city.Subdivisions[0].Names = englishName(record.Region)
+		city.Subdivisions[0].IsoCode = record.RegionCode
+	}
+
+	return city, nil
+}
+
+func (r *ipinfoReader) ASN(ip net.IP) (*geoip2.ASN, error) {
+	record, err := r.lookup(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return &geoip2.ASN{
+		AutonomousSystemNumber:       parseASN(record.ASN),
+		AutonomousSystemOrganization: record.ASName,
+	}, nil
+}
+
+func (r *ipinfoReader) ISP(ip net.IP) (*geoip2.ISP, error) {
+	record, err := r.lookup(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return &geoip2.ISP{
+		AutonomousSystemNumber:       parseASN(record.ASN),
+		AutonomousSystemOrganization: record.ASName,
+	}, nil
+}
+
+func (r *ipinfoReader) AnonymousIP(ip net.IP) (*geoip2.AnonymousIP, error) {
+	return nil, fmt.Errorf("ipinfo %s has no anonymous ip data", r.db.Metadata.DatabaseType)
+}
+
+func (r *ipinfoReader) Metadata() maxminddb.Metadata {
+	return r.db.Metadata
+}
+
+func (r *ipinfoReader) Close() error {
+	return r.db.Close()
+}
+
+func englishName(name string) map[string]string {
+	if name == "" {
+		return nil
+	}
+	return map[string]string{"en": name}
+}
+
+// parseASN parses an IPinfo asn, e.g. AS1653
+func parseASN(asn string) uint {
+	n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(asn), "AS"), 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(n)
+}
+
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0
+		}
+		return f
+	}
+	return 0
+}
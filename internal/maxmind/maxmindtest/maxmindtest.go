@@ -0,0 +1,56 @@
+// Package maxmindtest provides a maxmind service serving mmdb test files, for the tests of the packages using maxmind.
+package maxmindtest
+
+import (
+	"context"
+	"ip_service/internal/maxmind"
+	"ip_service/internal/store"
+	"ip_service/pkg/model"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+)
+
+// New returns a maxmind service with the files of dbFiles, keyed by database type, loaded as local databases. A
+// database type without a file is not loaded. The service has no downloads and is closed with the test.
+func New(t testing.TB, tp *trace.Tracer, log *logger.Log, dbFiles map[string]string) (*maxmind.Service, error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := &model.Cfg{
+		IPService: &model.IPService{
+			MaxMind: model.MaxMind{
+				BaseFolder:        filepath.Join(dir, "maxmind"),
+				UpdatePeriodicity: 86400,
+				DB:                map[string]model.MaxMindDB{},
+			},
+			Store: model.Store{File: model.FileStorage{Path: filepath.Join(dir, "store")}},
+		},
+	}
+	for _, dbType := range []string{model.MaxmindDBTypeASN, model.MaxmindDBTypeCity} {
+		cfg.IPService.MaxMind.DB[dbType] = model.MaxMindDB{
+			Source:       model.MaxMindSourceLocal,
+			FilePath:     dbFiles[dbType],
+			PollInterval: time.Hour,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	st, err := store.New(ctx, cfg, tp, log.New("store"))
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := maxmind.New(ctx, cfg, st, tp, log.New("maxmind"))
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { s.Close(context.Background()) })
+
+	return s, nil
+}
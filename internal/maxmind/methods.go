@@ -2,6 +2,8 @@ package maxmind
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -9,6 +11,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/oschwald/geoip2-golang"
@@ -30,23 +33,13 @@ func (s *Service) downloadArchive(ctx context.Context, dbType string) error {
 		return fmt.Errorf("rate limit exceeded")
 	}
 
-	remoteURL, err := s.cfg.IPService.MaxMind.URL(dbType)
-	if err != nil {
-		span.SetStatus(codes.Error, err.Error())
-		return err
-	}
-
-	s.Log.Info("Downloading", "url", remoteURL)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, remoteURL, nil)
-	if err != nil {
-		return err
-	}
+	s.Log.Info("Downloading", "dbType", dbType, "provider", s.cfg.IPService.MaxMind.Provider(dbType))
 
-	req.SetBasicAuth(s.cfg.IPService.MaxMind.Username, s.cfg.IPService.MaxMind.Password)
+	downloadStart := time.Now()
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := s.do(ctx, http.MethodGet, dbType)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 	defer resp.Body.Close()
@@ -56,77 +49,153 @@ func (s *Service) downloadArchive(ctx context.Context, dbType string) error {
 		return errors.New("remote rate limit exceeded")
 
 	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("errors http status code: %d", resp.StatusCode)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
 
-	s.Log.Debug("downloadArchive", "path", s.cfg.IPService.MaxMind.ArchiveFilePath(dbType))
+	// the version is stored once the database is installed, so a failed download is retried on the next check
+	version, err := s.parseHeader(ctx, resp)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
 
-	archiveFile, err := os.OpenFile(s.cfg.IPService.MaxMind.ArchiveFilePath(dbType), os.O_CREATE|os.O_WRONLY, 0600)
+	// download to a temp file next to the archive, it is renamed into place once the checksum matches
+	archiveFile, err := os.CreateTemp(s.cfg.IPService.MaxMind.BaseFolder, fmt.Sprintf("GeoLite2-%s-*.part", dbType))
 	if err != nil {
-		s.Log.Error(err, "open file")
+		s.Log.Error(err, "create temp file")
 		return err
 	}
-	defer archiveFile.Close()
+	defer os.Remove(archiveFile.Name())
+
+	s.Log.Debug("downloadArchive", "path", archiveFile.Name())
 
-	//bar := progressbar.DefaultBytes(resp.ContentLength, fmt.Sprintf("downloading %s", dbType))
-	_, err = io.Copy(archiveFile, resp.Body)
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(archiveFile, hash), resp.Body)
+	if closeErr := archiveFile.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
 		s.Log.Error(err, "copy to broke")
 		return err
 	}
 
-	s.Log.Info("download finished", "dbType", dbType)
-	stat, err := archiveFile.Stat()
+	s.Log.Info("download finished", "dbType", dbType, "size", size)
+	metrics.downloadDuration.WithLabelValues(dbType).Observe(time.Since(downloadStart).Seconds())
+	metrics.downloadSize.WithLabelValues(dbType).Observe(float64(size))
+
+	want, err := s.getRemoteSHA256(ctx, dbType)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if want == "" {
+		s.Log.Info("provider publishes no sha256, relying on validation of the database", "dbType", dbType)
+	} else if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		err := fmt.Errorf("sha256 mismatch for %s archive, got %s want %s", dbType, got, want)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	if err := os.Rename(archiveFile.Name(), s.cfg.IPService.MaxMind.ArchiveFilePath(dbType)); err != nil {
 		return err
 	}
-	fmt.Println("stat size!!!!!!", dbType, stat.Size())
 
-	if !s.cfg.IPService.MaxMind.IsArchivePresent(dbType) {
-		return fmt.Errorf("archive file missing dbType: %s", dbType)
+	s.Log.Info("Extract", "dbType", dbType)
+	if err := s.extractDB(ctx, dbType); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
 	}
 
-	s.Log.Info("UnTar", "dbType", dbType)
-	//if err := s.unTAR(ctx, dbType); err != nil {
-	if err := s.unTarV3(ctx, dbType); err != nil {
+	if err := s.installDB(ctx, dbType); err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
+	if err := s.kvStore.SetRemoteVersion(ctx, dbType, version); err != nil {
+		s.Log.Error(err, "failed to store the remote version", "dbType", dbType)
+	}
+
 	s.reloadChan <- dbType
 
 	return nil
 }
 
-func (s *Service) parseHeader(ctx context.Context, resp *http.Response) (string, error) {
-	remoteLastMod, err := time.Parse(time.RFC1123, resp.Header.Get("last-modified"))
+// getRemoteSHA256 returns the sha256 the provider publishes for the download of dbType, empty if it publishes none
+func (s *Service) getRemoteSHA256(ctx context.Context, dbType string) (string, error) {
+	// the published sha256 belongs to the download of the provider, not to a mirror in url
+	if s.cfg.IPService.MaxMind.DB[dbType].URL != "" {
+		return "", nil
+	}
+
+	p := s.provider(dbType)
+
+	sha256URL, err := p.sha256URL(&s.cfg.IPService.MaxMind, dbType)
+	if err != nil || sha256URL == "" {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sha256URL, nil)
 	if err != nil {
 		return "", err
 	}
 
-	return remoteLastMod.String(), nil
-}
+	p.authorize(&s.cfg.IPService.MaxMind, dbType, req)
 
-// getRemoteVersion retrieve the latest remote version.
-func (s *Service) getRemoteVersion(ctx context.Context, dbType string) (string, error) {
-	_, span := s.TP.Start(ctx, "maxmind:getRemoteVersion")
-	defer span.End()
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("errors http status code: %d", resp.StatusCode)
+	}
 
-	remoteURL, err := s.cfg.IPService.MaxMind.URL(dbType)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
 	if err != nil {
-		span.SetStatus(codes.Error, err.Error())
 		return "", err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodHead, remoteURL, nil)
+	// the file holds "<sha256>  <archive name>"
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("malformed sha256 file for %s: %q", dbType, string(body))
+	}
+
+	return strings.ToLower(fields[0]), nil
+}
+
+// parseHeader returns the remote version from last-modified, or the etag for providers without it.
+// An empty version is always treated as new, installDB skips an unchanged database.
+func (s *Service) parseHeader(ctx context.Context, resp *http.Response) (string, error) {
+	lastModified := resp.Header.Get("last-modified")
+	if lastModified == "" {
+		return resp.Header.Get("etag"), nil
+	}
+
+	remoteLastMod, err := time.Parse(time.RFC1123, lastModified)
 	if err != nil {
 		return "", err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	return remoteLastMod.String(), nil
+}
+
+// getRemoteVersion retrieve the latest remote version.
+func (s *Service) getRemoteVersion(ctx context.Context, dbType string) (string, error) {
+	_, span := s.TP.Start(ctx, "maxmind:getRemoteVersion")
+	defer span.End()
+
+	resp, err := s.do(ctx, http.MethodHead, dbType)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		s.Log.Error(err, "http head request failed")
 		return "", err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		err := fmt.Errorf("errors http status code: %d", resp.StatusCode)
@@ -174,15 +243,11 @@ func (s *Service) compareVersion(ctx context.Context, dbType string) (bool, erro
 
 	local := s.kvStore.GetRemoteVersion(ctx, dbType)
 
-	if remote == local {
+	if remote != "" && remote == local {
 		s.Log.Info("No new maxmind database version found", "local_version", local, "remote_version", remote, "dbType", dbType)
 		return false, nil
 	}
 
-	if err := s.kvStore.SetRemoteVersion(ctx, dbType, remote); err != nil {
-		return true, err
-	}
-
 	s.Log.Info("New version of MaxMind database found", "version", remote)
 
 	return true, nil
@@ -194,10 +259,19 @@ func (s *Service) initial(ctx context.Context, dbType string) error {
 
 	s.Log.Info("Initial", "dbType", dbType)
 
+	if s.cfg.IPService.MaxMind.IsLocal(dbType) {
+		s.initialLocal(ctx, dbType)
+		return nil
+	}
+
 	if s.cfg.IPService.MaxMind.IsArchivePresent(dbType) {
 		s.Log.Info("Archive file already exists", "dbType", dbType)
 
-		if err := s.unTarV3(ctx, dbType); err != nil {
+		if err := s.extractDB(ctx, dbType); err != nil {
+			return err
+		}
+
+		if err := s.installDB(ctx, dbType); err != nil {
 			return err
 		}
 
@@ -230,10 +304,13 @@ func (s *Service) City(ctx context.Context, ip net.IP) (*geoip2.City, error) {
 	_, span := s.TP.Start(ctx, "maxmind:City")
 	defer span.End()
 
-	s.DBMeta["City"].MU.RLock()
-	defer s.DBMeta["City"].MU.RUnlock()
+	h, err := s.acquire(model.MaxmindDBTypeCity)
+	if err != nil {
+		return nil, err
+	}
+	defer h.release()
 
-	return s.DBCity.City(ip)
+	return h.reader.City(ip)
 }
 
 // ASN return information about the ASN
@@ -242,14 +319,13 @@ func (s *Service) ASN(ctx context.Context, ip net.IP) (*geoip2.ASN, error) {
 	_, span := s.TP.Start(ctx, "maxmind:City")
 	defer span.End()
 
-	s.Log.Debug("maxmind:ASN before RLock", "ip", ip.String())
-
-	s.DBMeta[model.MaxmindDBTypeASN].MU.RLock()
-	defer s.DBMeta[model.MaxmindDBTypeASN].MU.RUnlock()
-
-	s.Log.Debug("maxmind:ASN after RUnlock")
+	h, err := s.acquire(model.MaxmindDBTypeASN)
+	if err != nil {
+		return nil, err
+	}
+	defer h.release()
 
-	asn, err := s.DBASN.ASN(ip)
+	asn, err := h.reader.ASN(ip)
 	if err != nil {
 		s.Log.Error(err, "failed to get ASN")
 		return nil, err
@@ -265,10 +341,13 @@ func (s *Service) ISP(ctx context.Context, ip net.IP) (*geoip2.ISP, error) {
 	_, span := s.TP.Start(ctx, "maxmind:ISP")
 	defer span.End()
 
-	s.DBMeta["City"].MU.RLock()
-	defer s.DBMeta["City"].MU.RUnlock()
+	h, err := s.acquire(model.MaxmindDBTypeCity)
+	if err != nil {
+		return nil, err
+	}
+	defer h.release()
 
-	isp, err := s.DBCity.ISP(ip)
+	isp, err := h.reader.ISP(ip)
 	if err != nil {
 		s.Log.Error(err, "failed to get ISP")
 		return nil, err
@@ -282,10 +361,13 @@ func (s *Service) AnonymousIP(ctx context.Context, ip net.IP) (*geoip2.Anonymous
 	_, span := s.TP.Start(ctx, "maxmind:AnonymousIP")
 	defer span.End()
 
-	s.DBMeta[model.MaxmindDBTypeCity].MU.RLock()
-	defer s.DBMeta[model.MaxmindDBTypeCity].MU.RUnlock()
+	h, err := s.acquire(model.MaxmindDBTypeASN)
+	if err != nil {
+		return nil, err
+	}
+	defer h.release()
 
-	asnIP, err := s.DBASN.AnonymousIP(ip)
+	asnIP, err := h.reader.AnonymousIP(ip)
 	if err != nil {
 		s.Log.Error(err, "failed to get AnonymousIP")
 		return nil, err
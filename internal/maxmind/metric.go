@@ -0,0 +1,59 @@
+package maxmind
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the prometheus metrics for maxmind, labeled by database type
+var metrics = struct {
+	buildEpoch       *prometheus.GaugeVec
+	lastSuccess      *prometheus.GaugeVec
+	lastFailure      *prometheus.GaugeVec
+	downloadSize     *prometheus.HistogramVec
+	downloadDuration *prometheus.HistogramVec
+	parseDuration    *prometheus.HistogramVec
+	openReaders      *prometheus.GaugeVec
+	readerGeneration *prometheus.GaugeVec
+	rollbacks        *prometheus.CounterVec
+}{
+	buildEpoch: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_maxmind_build_epoch_seconds",
+		Help: "Build time of the loaded maxmind database, from the database metadata",
+	}, []string{"db"}),
+	lastSuccess: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_maxmind_last_success_timestamp_seconds",
+		Help: "Time of the last successfully loaded maxmind database",
+	}, []string{"db"}),
+	lastFailure: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_maxmind_last_failure_timestamp_seconds",
+		Help: "Time of the last failed maxmind download or load",
+	}, []string{"db"}),
+	downloadSize: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ip_service_maxmind_download_size_bytes",
+		Help:    "Size of downloaded maxmind archives",
+		Buckets: prometheus.ExponentialBuckets(1024*1024, 2, 10),
+	}, []string{"db"}),
+	downloadDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ip_service_maxmind_download_duration_seconds",
+		Help:    "Time spent downloading maxmind archives",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+	}, []string{"db"}),
+	parseDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ip_service_maxmind_parse_duration_seconds",
+		Help:    "Time spent extracting maxmind databases from the downloaded archive",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"db"}),
+	openReaders: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_maxmind_open_readers",
+		Help: "Open maxmind reader generations, above one while lookups still use a replaced reader",
+	}, []string{"db"}),
+	readerGeneration: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_maxmind_reader_generation",
+		Help: "Generation of the current maxmind reader, incremented on every load",
+	}, []string{"db"}),
+	rollbacks: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ip_service_maxmind_rollbacks_total",
+		Help: "Rollbacks of a maxmind database to a previous version",
+	}, []string{"db"}),
+}
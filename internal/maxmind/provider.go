@@ -0,0 +1,227 @@
+package maxmind
+
+import (
+	"context"
+	"fmt"
+	"ip_service/pkg/model"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// provider knows where a vendor publishes its db's and how its records map to the geoip2 records
+type provider interface {
+	// downloadURL returns the URL of the download of dbType
+	downloadURL(cfg *model.MaxMind, dbType string) (string, error)
+	// sha256URL returns the URL of the published sha256 of the download, empty when the vendor publishes none
+	sha256URL(cfg *model.MaxMind, dbType string) (string, error)
+	// authorize adds the credentials of the vendor to req
+	authorize(cfg *model.MaxMind, dbType string, req *http.Request)
+	// open opens the db at path as a geoReader
+	open(path string) (geoReader, error)
+}
+
+// fallbackProvider is a provider with a second URL for a download that is not found at downloadURL
+type fallbackProvider interface {
+	fallbackURL(cfg *model.MaxMind, dbType string) (string, error)
+}
+
+var providers = map[string]provider{
+	model.GeoProviderMaxMind:     maxmindProvider{},
+	model.GeoProviderDBIP:        dbipProvider{},
+	model.GeoProviderIPinfo:      ipinfoProvider{},
+	model.GeoProviderIP2Location: ip2locationProvider{},
+	model.GeoProviderURL:         urlProvider{},
+}
+
+// provider returns the provider of dbType
+func (s *Service) provider(dbType string) provider {
+	return providers[s.cfg.IPService.MaxMind.Provider(dbType)]
+}
+
+// newRequest returns an authorized request for the download of dbType, url overrides the URL of the provider
+func (s *Service) newRequest(ctx context.Context, method, dbType string) (*http.Request, error) {
+	p := s.provider(dbType)
+
+	remoteURL := s.cfg.IPService.MaxMind.DB[dbType].URL
+	if remoteURL == "" {
+		var err error
+		remoteURL, err = p.downloadURL(&s.cfg.IPService.MaxMind, dbType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, remoteURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.authorize(&s.cfg.IPService.MaxMind, dbType, req)
+
+	return req, nil
+}
+
+// do sends the request for the download of dbType, a download that is not found is retried at the fallback URL of the provider
+func (s *Service) do(ctx context.Context, method, dbType string) (*http.Response, error) {
+	req, err := s.newRequest(ctx, method, dbType)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Log.Debug("request", "method", method, "url", req.URL.Redacted(), "provider", s.cfg.IPService.MaxMind.Provider(dbType))
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := s.provider(dbType).(fallbackProvider)
+	if !ok || resp.StatusCode != http.StatusNotFound || s.cfg.IPService.MaxMind.DB[dbType].URL != "" {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	fallbackURL, err := p.fallbackURL(&s.cfg.IPService.MaxMind, dbType)
+	if err != nil {
+		return nil, err
+	}
+	req, err = http.NewRequestWithContext(ctx, method, fallbackURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.provider(dbType).authorize(&s.cfg.IPService.MaxMind, dbType, req)
+
+	s.Log.Info("download not found, trying the fallback", "dbType", dbType, "url", req.URL.Redacted())
+	return s.httpClient.Do(req)
+}
+
+// maxmindProvider downloads GeoLite2/GeoIP2 archives from remote_url
+type maxmindProvider struct{}
+
+func (maxmindProvider) downloadURL(cfg *model.MaxMind, dbType string) (string, error) {
+	return cfg.URL(dbType)
+}
+
+func (maxmindProvider) sha256URL(cfg *model.MaxMind, dbType string) (string, error) {
+	return cfg.SHA256URL(dbType)
+}
+
+func (maxmindProvider) authorize(cfg *model.MaxMind, dbType string, req *http.Request) {
+	req.SetBasicAuth(cfg.Username, cfg.Password)
+}
+
+func (maxmindProvider) open(path string) (geoReader, error) {
+	return geoip2.Open(path)
+}
+
+// dbipProvider downloads the monthly DB-IP Lite db's, which geoip2 reads as GeoLite2 compatible
+type dbipProvider struct{}
+
+func (dbipProvider) downloadURL(cfg *model.MaxMind, dbType string) (string, error) {
+	return dbipURL(dbType, time.Now().UTC()), nil
+}
+
+// fallbackURL returns the db of the previous month, DB-IP publishes the db of a month some days into it
+func (dbipProvider) fallbackURL(cfg *model.MaxMind, dbType string) (string, error) {
+	now := time.Now().UTC()
+	return dbipURL(dbType, time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC)), nil
+}
+
+// dbipURL returns the URL of the DB-IP Lite db of dbType of the month of t
+func dbipURL(dbType string, t time.Time) string {
+	edition := "city"
+	if dbType == model.MaxmindDBTypeASN {
+		edition = "asn"
+	}
+	return fmt.Sprintf("https://download.db-ip.com/free/dbip-%s-lite-%s.mmdb.gz", edition, t.Format("2006-01"))
+}
+
+func (dbipProvider) sha256URL(cfg *model.MaxMind, dbType string) (string, error) {
+	return "", nil
+}
+
+func (dbipProvider) authorize(cfg *model.MaxMind, dbType string, req *http.Request) {}
+
+func (dbipProvider) open(path string) (geoReader, error) {
+	return geoip2.Open(path)
+}
+
+// ipinfoProvider downloads IPinfo db's, their flat records are mapped by ipinfoReader
+type ipinfoProvider struct{}
+
+func (ipinfoProvider) downloadURL(cfg *model.MaxMind, dbType string) (string, error) {
+	return "https://ipinfo.io/data/ipinfo_lite.mmdb", nil
+}
+
+func (ipinfoProvider) sha256URL(cfg *model.MaxMind, dbType string) (string, error) {
+	return "", nil
+}
+
+func (ipinfoProvider) authorize(cfg *model.MaxMind, dbType string, req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+cfg.DB[dbType].Token)
+}
+
+func (ipinfoProvider) open(path string) (geoReader, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ipinfoReader{db: db}, nil
+}
+
+// ip2locationProvider downloads IP2Location LITE zip archives, their db's have GeoIP2 shaped records
+type ip2locationProvider struct{}
+
+func (ip2locationProvider) downloadURL(cfg *model.MaxMind, dbType string) (string, error) {
+	file := "DB11LITEMMDB"
+	if dbType == model.MaxmindDBTypeASN {
+		file = "DBASNLITEMMDB"
+	}
+	return "https://www.ip2location.com/download/?file=" + file, nil
+}
+
+func (ip2locationProvider) sha256URL(cfg *model.MaxMind, dbType string) (string, error) {
+	return "", nil
+}
+
+// authorize adds the token as a query parameter, ip2location does not take it in a header
+func (ip2locationProvider) authorize(cfg *model.MaxMind, dbType string, req *http.Request) {
+	q := req.URL.Query()
+	q.Set("token", cfg.DB[dbType].Token)
+	req.URL.RawQuery = q.Encode()
+}
+
+func (ip2locationProvider) open(path string) (geoReader, error) {
+	return openCompatReader(path)
+}
+
+// urlProvider downloads from the url of the db, which has GeoIP2 shaped records
+type urlProvider struct{}
+
+func (urlProvider) downloadURL(cfg *model.MaxMind, dbType string) (string, error) {
+	u, err := url.Parse(cfg.DB[dbType].URL)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(u.Scheme, "http") {
+		return "", fmt.Errorf("url of %s is not http(s): %q", dbType, u.Redacted())
+	}
+	return u.String(), nil
+}
+
+func (urlProvider) sha256URL(cfg *model.MaxMind, dbType string) (string, error) {
+	return "", nil
+}
+
+func (urlProvider) authorize(cfg *model.MaxMind, dbType string, req *http.Request) {
+	if token := cfg.DB[dbType].Token; token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func (urlProvider) open(path string) (geoReader, error) {
+	return openCompatReader(path)
+}
@@ -0,0 +1,256 @@
+package maxmind
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"ip_service/pkg/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderDownload(t *testing.T) {
+	db, err := os.ReadFile("../../testdata/GeoLite2-asn-Test.mmdb")
+	require.NoError(t, err)
+
+	gz := &bytes.Buffer{}
+	gzw := gzip.NewWriter(gz)
+	_, err = gzw.Write(db)
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+
+	zipped := &bytes.Buffer{}
+	zw := zip.NewWriter(zipped)
+	w, err := zw.Create("IP2LOCATION-LITE-ASN.MMDB")
+	require.NoError(t, err)
+	_, err = w.Write(db)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	files := map[string][]byte{
+		"/asn.mmdb":    db,
+		"/asn.mmdb.gz": gz.Bytes(),
+		"/asn.zip":     zipped.Bytes(),
+		"/asn.tar.gz":  testArchive(t, model.MaxmindDBTypeASN, "../../testdata/GeoLite2-asn-Test.mmdb"),
+	}
+
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		b, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	}))
+	defer server.Close()
+
+	tts := []struct {
+		name       string
+		db         model.MaxMindDB
+		wantFormat string
+		wantErr    bool
+	}{
+		{
+			name:       "mmdb",
+			db:         model.MaxMindDB{Provider: model.GeoProviderURL, URL: server.URL + "/asn.mmdb", Token: "secret"},
+			wantFormat: model.DownloadFormatMMDB,
+		},
+		{
+			name:       "gz",
+			db:         model.MaxMindDB{Provider: model.GeoProviderURL, URL: server.URL + "/asn.mmdb.gz"},
+			wantFormat: model.DownloadFormatGz,
+		},
+		{
+			name:       "zip",
+			db:         model.MaxMindDB{Provider: model.GeoProviderURL, URL: server.URL + "/asn.zip"},
+			wantFormat: model.DownloadFormatZip,
+		},
+		{
+			name:       "tar.gz",
+			db:         model.MaxMindDB{Provider: model.GeoProviderURL, URL: server.URL + "/asn.tar.gz"},
+			wantFormat: model.DownloadFormatTarGz,
+		},
+		{
+			name:       "url of a maxmind db",
+			db:         model.MaxMindDB{URL: server.URL + "/asn.mmdb.gz", Format: model.DownloadFormatGz},
+			wantFormat: model.DownloadFormatGz,
+		},
+		{
+			name:       "wrong format",
+			db:         model.MaxMindDB{Provider: model.GeoProviderURL, URL: server.URL + "/asn.mmdb", Format: model.DownloadFormatZip},
+			wantFormat: model.DownloadFormatZip,
+			wantErr:    true,
+		},
+		{
+			name:       "not found",
+			db:         model.MaxMindDB{Provider: model.GeoProviderURL, URL: server.URL + "/missing.mmdb"},
+			wantFormat: model.DownloadFormatMMDB,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newVersionsTestService(t, server.URL)
+			s.cfg.IPService.MaxMind.DB[model.MaxmindDBTypeASN] = tt.db
+			assert.Equal(t, tt.wantFormat, s.cfg.IPService.MaxMind.Format(model.MaxmindDBTypeASN))
+
+			err := s.downloadArchive(t.Context(), model.MaxmindDBTypeASN)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.False(t, s.cfg.IPService.MaxMind.IsDBPresent(model.MaxmindDBTypeASN))
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, s.loadDB(t.Context(), <-s.reloadChan))
+
+			if tt.db.Token != "" {
+				assert.Equal(t, "Bearer "+tt.db.Token, gotAuth)
+			}
+
+			asn, err := s.ASN(t.Context(), net.ParseIP("1.128.0.0"))
+			require.NoError(t, err)
+			assert.Equal(t, uint(1221), asn.AutonomousSystemNumber)
+		})
+	}
+}
+
+func TestProviderURL(t *testing.T) {
+	s := newVersionsTestService(t, "https://example.com/geoip/databases")
+	s.cfg.IPService.MaxMind.Username = "user"
+	s.cfg.IPService.MaxMind.Password = "pass"
+
+	tts := []struct {
+		name string
+		db   model.MaxMindDB
+		want string
+	}{
+		{
+			name: "maxmind",
+			db:   model.MaxMindDB{},
+			want: "https://example.com/geoip/databases/GeoLite2-ASN/download?suffix=tar.gz",
+		},
+		{
+			name: "ipinfo",
+			db:   model.MaxMindDB{Provider: model.GeoProviderIPinfo, Token: "secret"},
+			want: "https://ipinfo.io/data/ipinfo_lite.mmdb",
+		},
+		{
+			name: "ip2location",
+			db:   model.MaxMindDB{Provider: model.GeoProviderIP2Location, Token: "secret"},
+			want: "https://www.ip2location.com/download/?file=DBASNLITEMMDB&token=secret",
+		},
+		{
+			name: "url overrides the provider",
+			db:   model.MaxMindDB{Provider: model.GeoProviderIPinfo, URL: "https://mirror.example.com/ipinfo.mmdb", Token: "secret"},
+			want: "https://mirror.example.com/ipinfo.mmdb",
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			s.cfg.IPService.MaxMind.DB[model.MaxmindDBTypeASN] = tt.db
+
+			req, err := s.newRequest(t.Context(), http.MethodGet, model.MaxmindDBTypeASN)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, req.URL.String())
+		})
+	}
+}
+
+// roundTripFunc serves the requests of a http.Client without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestDBIPFallback(t *testing.T) {
+	now := time.Now().UTC()
+	current := dbipURL(model.MaxmindDBTypeASN, now)
+	previous := dbipURL(model.MaxmindDBTypeASN, time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NotEqual(t, current, previous)
+
+	tts := []struct {
+		name      string
+		published map[string]string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "db of the current month",
+			published: map[string]string{current: "current", previous: "previous"},
+			want:      "current",
+		},
+		{
+			name:      "db of the current month not yet published",
+			published: map[string]string{previous: "previous"},
+			want:      "previous",
+		},
+		{
+			name:      "no db published",
+			published: map[string]string{},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newVersionsTestService(t, "")
+			s.cfg.IPService.MaxMind.DB[model.MaxmindDBTypeASN] = model.MaxMindDB{Provider: model.GeoProviderDBIP}
+			s.httpClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				rec := httptest.NewRecorder()
+				etag, ok := tt.published[r.URL.String()]
+				if !ok {
+					rec.WriteHeader(http.StatusNotFound)
+					return rec.Result(), nil
+				}
+				rec.Header().Set("ETag", etag)
+				return rec.Result(), nil
+			})
+
+			got, err := s.getRemoteVersion(t.Context(), model.MaxmindDBTypeASN)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIPinfoMapping(t *testing.T) {
+	assert.Equal(t, uint(1653), parseASN("AS1653"))
+	assert.Equal(t, uint(1653), parseASN("1653"))
+	assert.Equal(t, uint(0), parseASN(""))
+
+	assert.Equal(t, 58.4167, toFloat("58.4167"))
+	assert.Equal(t, 58.4167, toFloat(58.4167))
+	assert.Equal(t, float64(0), toFloat(nil))
+}
+
+func TestCompatReader(t *testing.T) {
+	r, err := openCompatReader("../../testdata/GeoLite2-city-Test.mmdb")
+	require.NoError(t, err)
+	defer r.Close()
+
+	city, err := r.City(net.ParseIP("89.160.20.112"))
+	require.NoError(t, err)
+	assert.Equal(t, "SE", city.Country.IsoCode)
+
+	// unlike geoip2, a lookup that does not match the database type returns an empty record
+	asn, err := r.ASN(net.ParseIP("89.160.20.112"))
+	require.NoError(t, err)
+	assert.Zero(t, asn.AutonomousSystemNumber)
+}
@@ -0,0 +1,107 @@
+package maxmind
+
+import (
+	"fmt"
+	"ip_service/pkg/helpers"
+	"net"
+	"sync/atomic"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// geoReader is the part of geoip2.Reader used by the service, each provider opens its db's as one, see provider
+type geoReader interface {
+	City(ip net.IP) (*geoip2.City, error)
+	ASN(ip net.IP) (*geoip2.ASN, error)
+	ISP(ip net.IP) (*geoip2.ISP, error)
+	AnonymousIP(ip net.IP) (*geoip2.AnonymousIP, error)
+	Metadata() maxminddb.Metadata
+	Close() error
+}
+
+// readerHandle is a reference counted reader. The service holds one reference until the handle is
+// replaced, each lookup holds one while it runs, and the reader is closed when the last one is released.
+type readerHandle struct {
+	dbType     string
+	generation uint64
+	reader     geoReader
+	// path is the file the reader was opened from
+	path string
+	refs atomic.Int64
+}
+
+func newReaderHandle(dbType string, generation uint64, reader geoReader, path string) *readerHandle {
+	h := &readerHandle{
+		dbType:     dbType,
+		generation: generation,
+		reader:     reader,
+		path:       path,
+	}
+	h.refs.Store(1)
+	metrics.openReaders.WithLabelValues(dbType).Inc()
+	return h
+}
+
+// tryAcquire takes a reference, unless the last one has already been released
+func (h *readerHandle) tryAcquire() bool {
+	for {
+		refs := h.refs.Load()
+		if refs <= 0 {
+			return false
+		}
+		if h.refs.CompareAndSwap(refs, refs+1) {
+			return true
+		}
+	}
+}
+
+// release drops a reference and closes the reader when it was the last one
+func (h *readerHandle) release() {
+	if h.refs.Add(-1) != 0 {
+		return
+	}
+	if err := h.reader.Close(); err != nil {
+		metrics.lastFailure.WithLabelValues(h.dbType).SetToCurrentTime()
+	}
+	metrics.openReaders.WithLabelValues(h.dbType).Dec()
+}
+
+// acquire returns the current reader of dbType with a reference held, the caller must release it
+func (s *Service) acquire(dbType string) (*readerHandle, error) {
+	dbObject, ok := s.DBMeta[dbType]
+	if !ok {
+		return nil, helpers.ErrUnknownDataset
+	}
+
+	for {
+		h := dbObject.reader.Load()
+		if h == nil {
+			return nil, fmt.Errorf("%s %w", dbType, helpers.ErrDBNotLoaded)
+		}
+		if h.tryAcquire() {
+			return h, nil
+		}
+		// h was replaced and released after the load, the next load returns its replacement
+	}
+}
+
+// swapReader makes reader, opened from path, the current reader of dbType without waiting for lookups on the previous one
+func (s *Service) swapReader(dbType string, reader geoReader, path string) {
+	generation := s.readerGeneration.Add(1)
+
+	previous := s.DBMeta[dbType].reader.Swap(newReaderHandle(dbType, generation, reader, path))
+	if previous != nil {
+		previous.release()
+	}
+
+	metrics.readerGeneration.WithLabelValues(dbType).Set(float64(generation))
+	s.Log.Debug("reader swapped", "dbType", dbType, "generation", generation)
+}
+
+// dropReader removes the current reader of dbType, it is closed when its last lookup is done
+func (s *Service) dropReader(dbType string) {
+	if previous := s.DBMeta[dbType].reader.Swap(nil); previous != nil {
+		previous.release()
+	}
+}
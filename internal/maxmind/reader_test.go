@@ -0,0 +1,65 @@
+package maxmind
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/oschwald/geoip2-golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testASNFile = "../../testdata/GeoLite2-asn-Test.mmdb"
+
+func TestReaderHandle(t *testing.T) {
+	tracer, err := trace.NewForTesting(t.Context(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	s, err := newTestService(tracer, logger.NewSimple("test"), map[string]string{model.MaxmindDBTypeASN: testASNFile})
+	require.NoError(t, err)
+
+	// an in-flight lookup keeps the replaced reader open
+	inFlight, err := s.acquire(model.MaxmindDBTypeASN)
+	require.NoError(t, err)
+
+	db, err := geoip2.Open(testASNFile)
+	require.NoError(t, err)
+	s.swapReader(model.MaxmindDBTypeASN, db, testASNFile)
+
+	current := s.DBMeta[model.MaxmindDBTypeASN].reader.Load()
+	assert.NotSame(t, inFlight, current)
+	assert.Greater(t, current.generation, inFlight.generation)
+
+	_, err = inFlight.reader.ASN(net.ParseIP("1.128.0.0"))
+	assert.NoError(t, err)
+
+	inFlight.release()
+	assert.False(t, inFlight.tryAcquire(), "released handle must not be acquired again")
+
+	// lookups during concurrent swaps always get an open reader
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 100 {
+				_, err := s.ASN(t.Context(), net.ParseIP("1.128.0.0"))
+				assert.NoError(t, err)
+			}
+		})
+	}
+	for range 10 {
+		db, err := geoip2.Open(testASNFile)
+		require.NoError(t, err)
+		s.swapReader(model.MaxmindDBTypeASN, db, testASNFile)
+	}
+	wg.Wait()
+
+	s.dropReader(model.MaxmindDBTypeASN)
+	_, err = s.ASN(t.Context(), net.ParseIP("1.128.0.0"))
+	assert.ErrorIs(t, err, helpers.ErrDBNotLoaded)
+}
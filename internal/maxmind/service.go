@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"ip_service/internal/store"
@@ -14,7 +15,6 @@ import (
 	"ip_service/pkg/model"
 	"github.com/SUNET/vc/pkg/trace"
 
-	"github.com/oschwald/geoip2-golang"
 	"go.opentelemetry.io/otel/codes"
 	"golang.org/x/time/rate"
 )
@@ -22,33 +22,48 @@ import (
 type DBMeta map[string]*DBObject
 
 func (d DBMeta) DownloadInProgress(dbType string) {
-	d[dbType].Downloading = true
+	d[dbType].Downloading.Store(true)
 }
 
 func (d DBMeta) DownloadingDone(dbType string) {
-	d[dbType].Downloading = false
+	d[dbType].Downloading.Store(false)
 }
 
 func (d DBMeta) IsDownloadingInProgresses(dbType string) bool {
-	return d[dbType].Downloading
+	return d[dbType].Downloading.Load()
+}
+
+func (d DBMeta) ParsingInProgress(dbType string) {
+	d[dbType].Parsing.Store(true)
+}
+
+func (d DBMeta) ParsingDone(dbType string) {
+	d[dbType].Parsing.Store(false)
 }
 
 // Service holds the maxmind service object
 type Service struct {
+	// probeMU guards probeStore, /health requests run the probe concurrently
+	probeMU      sync.Mutex
 	probeStore   *model.StatusProbeStore
 	cfg          *model.Cfg
 	Log          *logger.Log
 	TP           *trace.Tracer
 	httpClient   *http.Client
 	DBMeta       DBMeta
-	DBCity       *geoip2.Reader
-	DBASN        *geoip2.Reader
 	kvStore      kvStore
 	quitChan     chan struct{}
 	reloadChan   chan string
 	downloadChan chan string
 	updateChan   chan string
 	initialChan  chan string
+	hooksMU      sync.Mutex
+	reloadHooks  []func(ctx context.Context)
+	// updateTicker and automaticUpdate are hot reloadable
+	updateTicker    *time.Ticker
+	automaticUpdate atomic.Bool
+	// readerGeneration numbers the loaded readers across database types
+	readerGeneration atomic.Uint64
 }
 
 type kvStore interface {
@@ -62,9 +77,13 @@ type kvStore interface {
 // DBObject holds the maxmind database object
 type DBObject struct {
 	rateLimit   rate.Limiter
-	MU          sync.RWMutex
+	reader      atomic.Pointer[readerHandle]
 	Missing     bool
-	Downloading bool
+	// Downloading and Parsing are written by the update loop and read by the admin api
+	Downloading atomic.Bool
+	Parsing     atomic.Bool
+	// filesMu serialises the install, rollback and load of the database file, between the update loop and the admin api
+	filesMu sync.Mutex
 }
 
 // New creates a new instance of maxmind
@@ -85,24 +104,29 @@ func New(ctx context.Context, cfg *model.Cfg, store *store.Service, tp *trace.Tr
 		},
 		DBMeta: map[string]*DBObject{
 			model.MaxmindDBTypeCity: {
-				Missing:     true,
-				Downloading: false,
-				rateLimit:   *rate.NewLimiter(rate.Every(24*time.Hour), 4),
+				Missing:   true,
+				rateLimit: *rate.NewLimiter(rate.Every(24*time.Hour), 4),
 			},
 			model.MaxmindDBTypeASN: {
-				Missing:     true,
-				Downloading: false,
-				rateLimit:   *rate.NewLimiter(rate.Every(24*time.Hour), 4),
+				Missing:   true,
+				rateLimit: *rate.NewLimiter(rate.Every(24*time.Hour), 4),
 			},
 		},
 	}
 
-	updateTicker := time.NewTicker(s.cfg.IPService.MaxMind.UpdatePeriodicity * time.Second)
+	s.updateTicker = time.NewTicker(s.cfg.IPService.MaxMind.UpdatePeriodicity * time.Second)
+	s.automaticUpdate.Store(cfg.IPService.MaxMind.AutomaticUpdate)
 
 	if err := os.MkdirAll(s.cfg.IPService.MaxMind.BaseFolder, 0750); err != nil {
 		return nil, err
 	}
 
+	for dbType := range s.DBMeta {
+		if err := s.cfg.IPService.MaxMind.CheckDownload(dbType); err != nil {
+			return nil, err
+		}
+	}
+
 	for dbType := range s.DBMeta {
 		s.Log.Info("init db", "dbType", dbType)
 		if err := s.initial(ctx, dbType); err != nil {
@@ -117,10 +141,13 @@ func New(ctx context.Context, cfg *model.Cfg, store *store.Service, tp *trace.Tr
 		for {
 			select {
 			// check periodically if there is a new version of the database available
-			case <-updateTicker.C:
+			case <-s.updateTicker.C:
 				s.Log.Info("UpdateTicker")
-				if cfg.IPService.MaxMind.AutomaticUpdate {
+				if s.automaticUpdate.Load() {
 					for dbType := range s.DBMeta {
+						if s.cfg.IPService.MaxMind.IsLocal(dbType) {
+							continue
+						}
 						if !s.DBMeta.IsDownloadingInProgresses(dbType) {
 							s.Log.Info("No downloading in progress", "dbtype", dbType)
 							s.updateChan <- dbType
@@ -138,18 +165,16 @@ func New(ctx context.Context, cfg *model.Cfg, store *store.Service, tp *trace.Tr
 				s.Log.Info("downloadChan", "dbType", dbType)
 				if err := s.downloadArchive(ctx, dbType); err != nil {
 					s.Log.Error(err, "dbDownloader")
-					return
+					metrics.lastFailure.WithLabelValues(dbType).SetToCurrentTime()
 				}
 
 			case dbType := <-s.reloadChan:
 				s.Log.Info("reloadChan", "dbType", dbType)
-				if err := s.loadDB(ctx, dbType); err != nil {
-					s.Log.Error(err, "loadDB")
-				}
+				s.reload(ctx, dbType)
 
 			case <-s.quitChan:
 				s.Log.Info("quit database update")
-				updateTicker.Stop()
+				s.updateTicker.Stop()
 				return
 			}
 		}
@@ -158,13 +183,31 @@ func New(ctx context.Context, cfg *model.Cfg, store *store.Service, tp *trace.Tr
 	return s, nil
 }
 
-func (s *Service) loadDB(ctx context.Context, dbType string) error {
-	s.DBMeta[dbType].MU.Lock()
-	defer s.DBMeta[dbType].MU.Unlock()
+// reload loads the installed database, a downloaded database that fails to load is rolled back to the newest kept version
+func (s *Service) reload(ctx context.Context, dbType string) {
+	meta := s.DBMeta[dbType]
+	meta.filesMu.Lock()
+	defer meta.filesMu.Unlock()
+
+	if err := s.loadDB(ctx, dbType); err != nil {
+		s.Log.Error(err, "loadDB")
+		metrics.lastFailure.WithLabelValues(dbType).SetToCurrentTime()
+
+		if !s.cfg.IPService.MaxMind.IsLocal(dbType) {
+			if err := s.rollback(ctx, dbType); err != nil {
+				s.Log.Error(err, "rollback failed", "dbType", dbType)
+			}
+		}
+	}
+}
 
+func (s *Service) loadDB(ctx context.Context, dbType string) error {
 	_, span := s.TP.Start(ctx, "maxmind:loadDB")
 	defer span.End()
 
+	s.DBMeta.ParsingInProgress(dbType)
+	defer s.DBMeta.ParsingDone(dbType)
+
 	s.Log.Info("Run loadDB for", "dbType", dbType)
 
 	if !s.cfg.IPService.MaxMind.IsDBPresent(dbType) {
@@ -175,36 +218,66 @@ func (s *Service) loadDB(ctx context.Context, dbType string) error {
 
 	s.Log.Debug("load", "dbFileName", dbFileName)
 
-	db, err := geoip2.Open(dbFileName)
+	// open and validate before the swap, lookups keep using the current reader meanwhile
+	db, err := s.provider(dbType).open(dbFileName)
 	if err != nil {
-		s.Log.Error(err, "geoip2.Open failed")
+		s.Log.Error(err, "open failed", "provider", s.cfg.IPService.MaxMind.Provider(dbType))
 		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
 	if db == nil {
-		return errors.New("geoip2.Open returned nil db")
+		return errors.New("open returned nil db")
 	}
 
-	switch dbType {
-	case model.MaxmindDBTypeCity:
-		s.DBCity = db
-	case model.MaxmindDBTypeASN:
-		s.DBASN = db
-		metadata := s.DBASN.Metadata()
-		s.Log.Info("Maxmind", "dbType", dbType, "metadata", metadata)
-	default:
-		err := errors.New("unknown dbType: " + dbType)
-		s.Log.Error(err, "cannot load db")
+	if err := probeReader(dbType, db); err != nil {
+		s.Log.Error(err, "database failed validation, keeping the current one", "dbType", dbType, "dbFileName", dbFileName)
+		span.SetStatus(codes.Error, err.Error())
+		db.Close()
 		return err
 	}
 
+	s.swapReader(dbType, db, dbFileName)
+
+	s.Log.Info("Maxmind", "dbType", dbType, "metadata", db.Metadata())
+
+	metrics.buildEpoch.WithLabelValues(dbType).Set(float64(db.Metadata().BuildEpoch))
+	metrics.lastSuccess.WithLabelValues(dbType).SetToCurrentTime()
+
+	s.runReloadHooks(ctx)
+
 	return nil
 }
 
+// OnReload registers fn to be called each time a database has been loaded
+func (s *Service) OnReload(fn func(ctx context.Context)) {
+	s.hooksMU.Lock()
+	defer s.hooksMU.Unlock()
+	s.reloadHooks = append(s.reloadHooks, fn)
+}
+
+func (s *Service) runReloadHooks(ctx context.Context) {
+	s.hooksMU.Lock()
+	defer s.hooksMU.Unlock()
+	for _, fn := range s.reloadHooks {
+		fn(ctx)
+	}
+}
+
+// Reload applies the hot reloadable settings of cfg, automatic_update and update_periodicity
+func (s *Service) Reload(ctx context.Context, cfg *model.Cfg) {
+	s.automaticUpdate.Store(cfg.IPService.MaxMind.AutomaticUpdate)
+	if cfg.IPService.MaxMind.UpdatePeriodicity > 0 {
+		s.updateTicker.Reset(cfg.IPService.MaxMind.UpdatePeriodicity * time.Second)
+	}
+	s.Log.Info("Reloaded", "automatic_update", cfg.IPService.MaxMind.AutomaticUpdate, "update_periodicity", cfg.IPService.MaxMind.UpdatePeriodicity)
+}
+
 // Close closes maxmind service
 func (s *Service) Close(ctx context.Context) error {
 	s.Log.Info("Quit")
-	ctx.Done()
+	for dbType := range s.DBMeta {
+		s.dropReader(dbType)
+	}
 	return nil
 }
@@ -2,65 +2,103 @@ package maxmind
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"ip_service/pkg/model"
+	"maps"
 	"net"
+	"slices"
+	"strings"
 	"time"
 )
 
+const defaultMaxAge = 30 * 24 * time.Hour
+
+// probeIPs are looked up to check that a database works
+var probeIPs = []string{"95.142.107.181", "110.50.243.6", "69.162.81.155"}
+
 // Status return status for maxmind database and last saved database version
 func (s *Service) Status(ctx context.Context) *model.StatusProbe {
+	s.probeMU.Lock()
+	defer s.probeMU.Unlock()
+
 	if time.Now().Before(s.probeStore.NextCheck) {
 		return s.probeStore.PreviousResult
 	}
 
 	probe := &model.StatusProbe{
-		Name:    "maxmind",
-		Healthy: true,
-		Message: map[string]any{
-			"asn_db_status":   "ok",
-			"city_db_status":  "ok",
-			"asn_db_version":  "n/a",
-			"asn_last_check":  "n/a",
-			"city_db_version": "n/a",
-			"city_last_check": "n/a",
-		},
+		Name:          "maxmind",
+		Healthy:       true,
+		Message:       map[string]any{},
 		LastCheckedTS: time.Now(),
 	}
 
-	for _, testIP := range []string{"95.142.107.181", "110.50.243.6", "69.162.81.155"} {
-		_, err := s.DBASN.ASN(net.ParseIP(testIP))
-		if err != nil {
-			probe.Message["asn"] = fmt.Sprintf("%v", err)
+	maxAge := defaultMaxAge
+	if s.cfg.IPService.Health.MaxmindMaxAge > 0 {
+		maxAge = s.cfg.IPService.Health.MaxmindMaxAge
+	}
+
+	for _, dbType := range slices.Sorted(maps.Keys(s.DBMeta)) {
+		prefix := strings.ToLower(dbType)
+		probe.Message[prefix+"_db_status"] = "ok"
+		probe.Message[prefix+"_db_version"] = "n/a"
+		probe.Message[prefix+"_last_check"] = "n/a"
+
+		if err := s.probeDB(dbType, maxAge); err != nil {
+			probe.Message[prefix+"_db_status"] = err.Error()
 			probe.Healthy = false
 		}
-		_, err = s.DBCity.Country(net.ParseIP(testIP))
-		if err != nil {
-			probe.Message["city"] = fmt.Sprintf("%v", err)
-			probe.Healthy = false
+
+		if remoteVersion := s.kvStore.GetRemoteVersion(ctx, dbType); remoteVersion != "" {
+			probe.Message[prefix+"_db_version"] = remoteVersion
+		}
+		if lastCheck := s.kvStore.GetLastChecked(ctx, dbType); lastCheck != "" {
+			probe.Message[prefix+"_last_check"] = lastCheck
 		}
 	}
 
-	remoteVersionASN := s.kvStore.GetRemoteVersion(ctx, "asn")
-	if remoteVersionASN != "" {
-		probe.Message["asn_db_version"] = remoteVersionASN
-	}
-	remoteVersionCity := s.kvStore.GetRemoteVersion(ctx, "city")
-	if remoteVersionCity != "" {
-		probe.Message["city_db_version"] = remoteVersionCity
+	s.probeStore.PreviousResult = probe
+	s.probeStore.NextCheck = time.Now().Add(10 * time.Second)
+
+	return probe
+}
+
+// probeDB checks the loaded database, see probeReader
+func (s *Service) probeDB(dbType string, maxAge time.Duration) error {
+	h, err := s.acquire(dbType)
+	if err != nil {
+		return err
 	}
+	defer h.release()
 
-	lastCheckASN := s.kvStore.GetLastChecked(ctx, "asn")
-	if lastCheckASN != "" {
-		probe.Message["asn_last_check"] = lastCheckASN
+	if err := probeReader(dbType, h.reader); err != nil {
+		return err
 	}
-	lastCheckCity := s.kvStore.GetLastChecked(ctx, "city")
-	if lastCheckCity != "" {
-		probe.Message["city_last_check"] = lastCheckCity
+
+	buildTime := time.Unix(int64(h.reader.Metadata().BuildEpoch), 0)
+	if age := time.Since(buildTime); age > maxAge {
+		return fmt.Errorf("%s database built %s ago, max age is %s", dbType, age.Truncate(time.Hour), maxAge)
 	}
 
-	s.probeStore.PreviousResult = probe
-	s.probeStore.NextCheck = time.Now().Add(10 * time.Second)
+	return nil
+}
 
-	return probe
+// probeReader looks up the probe IPs in db, geoip2 refuses lookups that do not match the database type
+func probeReader(dbType string, db geoReader) error {
+	for _, testIP := range probeIPs {
+		var err error
+		switch dbType {
+		case model.MaxmindDBTypeASN:
+			_, err = db.ASN(net.ParseIP(testIP))
+		case model.MaxmindDBTypeCity:
+			_, err = db.City(net.ParseIP(testIP))
+		default:
+			err = errors.New("unknown dbType: " + dbType)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,79 @@
+package maxmind
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"ip_service/pkg/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusNotLoaded(t *testing.T) {
+	s := &Service{
+		probeStore: &model.StatusProbeStore{},
+		cfg:        &model.Cfg{IPService: &model.IPService{}},
+		DBMeta: DBMeta{
+			model.MaxmindDBTypeASN:  &DBObject{},
+			model.MaxmindDBTypeCity: &DBObject{},
+		},
+		kvStore: mockKVStore{model.MaxmindDBTypeASN: "1"},
+	}
+
+	probe := s.Status(t.Context())
+	assert.False(t, probe.Healthy)
+	assert.Equal(t, "ASN database not loaded", probe.Message["asn_db_status"])
+	assert.Equal(t, "City database not loaded", probe.Message["city_db_status"])
+	assert.Equal(t, "1", probe.Message["asn_db_version"])
+	assert.Equal(t, "n/a", probe.Message["city_db_version"])
+}
+
+func TestStatusConcurrent(t *testing.T) {
+	s := &Service{
+		probeStore: &model.StatusProbeStore{},
+		cfg:        &model.Cfg{IPService: &model.IPService{}},
+		DBMeta: DBMeta{
+			model.MaxmindDBTypeASN: &DBObject{},
+		},
+		kvStore: mockKVStore{},
+	}
+
+	// concurrent /health requests share the cached probe, run with -race
+	probes := make([]*model.StatusProbe, 10)
+	var wg sync.WaitGroup
+	for i := range probes {
+		wg.Go(func() {
+			probes[i] = s.Status(t.Context())
+		})
+	}
+	wg.Wait()
+
+	for _, probe := range probes {
+		assert.Same(t, probes[0], probe)
+	}
+}
+
+// mockKVStore holds remote versions by database type
+type mockKVStore map[string]string
+
+func (m mockKVStore) GetRemoteVersion(ctx context.Context, k string) string {
+	return m[k]
+}
+
+func (m mockKVStore) GetLastChecked(ctx context.Context, k string) string {
+	return ""
+}
+
+func (m mockKVStore) SetLastChecked(ctx context.Context, k string) error {
+	return nil
+}
+
+func (m mockKVStore) SetPreviousVersion(ctx context.Context, k string) error {
+	return nil
+}
+
+func (m mockKVStore) SetRemoteVersion(ctx context.Context, k, v string) error {
+	m[k] = v
+	return nil
+}
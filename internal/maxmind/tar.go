@@ -1,145 +1,158 @@
 package maxmind
 
 import (
-	"archive/tar"
+	"archive/zip"
 	"compress/gzip"
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"ip_service/pkg/model"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/walle/targz"
 )
 
-func (s *Service) unTarV3(ctx context.Context, dbType string) error {
-	//tmpDir := os.TempDir()
+// extractDB extracts the database of the download to the staging path, see installDB
+func (s *Service) extractDB(ctx context.Context, dbType string) error {
+	s.DBMeta.ParsingInProgress(dbType)
+	start := time.Now()
+	defer func() {
+		metrics.parseDuration.WithLabelValues(dbType).Observe(time.Since(start).Seconds())
+		s.DBMeta.ParsingDone(dbType)
+	}()
+
+	archive := s.cfg.IPService.MaxMind.ArchiveFilePath(dbType)
+
+	switch format := s.cfg.IPService.MaxMind.Format(dbType); format {
+	case model.DownloadFormatTarGz:
+		return s.unTarV3(ctx, dbType)
+	case model.DownloadFormatZip:
+		return s.unZip(ctx, dbType)
+	case model.DownloadFormatGz:
+		return gunzipFile(archive, s.stagingFilePath(dbType))
+	case model.DownloadFormatMMDB:
+		return copyFile(archive, s.stagingFilePath(dbType))
+	default:
+		return fmt.Errorf("unknown download format %q of %s", format, dbType)
+	}
+}
+
+// isDBFileName reports whether name is a database in an archive, vendors name and case them differently
+func isDBFileName(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".mmdb")
+}
 
-	err := targz.Extract(s.cfg.IPService.MaxMind.ArchiveFilePath(dbType), s.cfg.IPService.MaxMind.BaseFolder)
+// unTarV3 extracts the database of the tar.gz archive to the staging path
+func (s *Service) unTarV3(ctx context.Context, dbType string) error {
+	// extract into a folder of its own, the base folder holds the installed database with the same name
+	extractDir, err := os.MkdirTemp(s.cfg.IPService.MaxMind.BaseFolder, "extract-"+dbType+"-")
 	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(extractDir)
+
+	if err := targz.Extract(s.cfg.IPService.MaxMind.ArchiveFilePath(dbType), extractDir); err != nil {
 		s.Log.Error(err, "targz extract failed")
 		return err
 	}
 
 	// Walk through the extracted files to find the .mmdb file, since maxmind names the folder with a version number
-	err = filepath.Walk(s.cfg.IPService.MaxMind.BaseFolder, func(path string, info fs.FileInfo, err error) error {
+	found := false
+	err = filepath.Walk(extractDir, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
-			fmt.Printf("prevent panic by handling failure accessing a path %q: %v\n", path, err)
 			return err
 		}
-		if info.Name() == fmt.Sprintf("GeoLite2-%s.mmdb", dbType) {
-			f, err := os.Open(filepath.Clean(path))
-			if err != nil {
-				s.Log.Error(err, "open extracted db file failed")
-				return err
-			}
-			//dest, err := os.Create("/tmp/db/GeoLite2-" + dbType + ".mmdb")
-			dest, err := os.Create(filepath.Clean(s.cfg.IPService.MaxMind.DBFilePath(dbType)))
-			if err != nil {
-				s.Log.Error(err, "create dest db file failed")
-				return err
-			}
-			_, err = io.Copy(dest, f)
-			if err != nil {
-				s.Log.Error(err, "copy extracted db file failed")
-				return err
-			}
+		if info.IsDir() || !isDBFileName(info.Name()) {
+			return nil
 		}
-		if info.IsDir() && info.Name() == "skip" {
-			return filepath.SkipDir
+
+		if err := copyFile(path, s.stagingFilePath(dbType)); err != nil {
+			s.Log.Error(err, "copy extracted db file failed")
+			return err
 		}
-		return nil
+		found = true
+		return filepath.SkipAll
 	})
 	if err != nil {
-		s.Log.Error(err, "walk tmp dir failed")
+		s.Log.Error(err, "walk extract dir failed")
 		return err
 	}
+	if !found {
+		return fmt.Errorf("no %s mmdb in archive", dbType)
+	}
 
 	return nil
 }
 
-func (s *Service) unTAR(ctx context.Context, dbType string) error {
-	s.Log.Debug("entering unTAR")
+// unZip extracts the database of the zip archive to the staging path
+func (s *Service) unZip(ctx context.Context, dbType string) error {
+	r, err := zip.OpenReader(s.cfg.IPService.MaxMind.ArchiveFilePath(dbType))
+	if err != nil {
+		s.Log.Error(err, "open zip failed")
+		return err
+	}
+	defer r.Close()
 
-	_, span := s.TP.Start(ctx, "maxmind:unTAR")
-	defer span.End()
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !isDBFileName(f.Name) {
+			continue
+		}
 
-	if !s.cfg.IPService.MaxMind.IsArchivePresent(dbType) {
-		return errors.New("archive file missing")
-	}
+		in, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer in.Close()
 
-	s.Log.Info("Unpacking tar archive", "dbType", dbType)
+		return writeFile(s.stagingFilePath(dbType), in)
+	}
 
-	fileName := s.cfg.IPService.MaxMind.ArchiveFilePath(dbType)
-	s.Log.Debug("unTAR", "fileName", fileName)
+	return fmt.Errorf("no %s mmdb in archive", dbType)
+}
 
-	archiveFile, err := os.Open(filepath.Clean(fileName))
+// gunzipFile decompresses the gzip file src to dst
+func gunzipFile(src, dst string) error {
+	in, err := os.Open(filepath.Clean(src))
 	if err != nil {
-		s.Log.Error(err, "open archive file")
 		return err
 	}
+	defer in.Close()
 
-	gzr, err := gzip.NewReader(archiveFile)
+	gzr, err := gzip.NewReader(in)
 	if err != nil {
-		s.Log.Error(err, "gzip new reader")
 		return err
 	}
 	defer gzr.Close()
 
-	tr := tar.NewReader(gzr)
-
-	s.Log.Debug("untarring", "dbType", dbType)
-	for {
-		header, err := tr.Next()
-		switch {
-		case err == io.EOF:
-			s.Log.Debug("end of tar archive")
-			return nil
-		case err != nil:
-			s.Log.Error(err, "tar error")
-			return err
-		case header == nil:
-			s.Log.Debug("tar header is nil")
-			continue
-		case filepath.Base(header.Name) != fmt.Sprintf("GeoLite2-%s.mmdb", dbType):
-			continue
-		}
+	return writeFile(dst, gzr)
+}
 
-		target := s.cfg.IPService.MaxMind.DBFilePath(dbType)
-		s.Log.Debug("untar", "target", target)
-
-		switch header.Typeflag {
-		case tar.TypeDir:
-			s.Log.Error(errors.New("is a dir, needs to be a file"), "unTAR")
-			if _, err := os.Stat(header.Name); err != nil {
-				if err := os.MkdirAll(target, 0750); err != nil {
-					return err
-				}
-			}
-		case tar.TypeReg:
-			file, err := os.OpenFile(filepath.Clean(target), os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
-			if err != nil {
-				return err
-			}
-
-			if _, err := io.CopyN(file, tr, 90000000); err != nil {
-				return err
-			}
-
-			if err := file.Close(); err != nil {
-				return err
-			}
-		}
+// copyFile copies src to dst, dst is created or truncated
+func copyFile(src, dst string) error {
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
 	}
+	defer in.Close()
+
+	return writeFile(dst, in)
 }
 
-func (s *Service) cleanUpTarArchive(ctx context.Context, dbType string) error {
-	_, span := s.TP.Start(ctx, "maxmind:cleanUpTarArchive")
-	defer span.End()
+// writeFile writes r to dst, dst is created or truncated
+func writeFile(dst string, r io.Reader) error {
+	out, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		return err
+	}
 
-	s.Log.Info("Cleaning up tar archive", "dbType", dbType)
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
 
-	return os.Remove(s.cfg.IPService.MaxMind.ArchiveFilePath(dbType))
+	return out.Close()
 }
@@ -0,0 +1,192 @@
+package maxmind
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"ip_service/pkg/helpers"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	defaultKeepVersions = 2
+
+	// versionTimeFormat names the kept versions, so they sort by the time they were replaced
+	versionTimeFormat = "20060102T150405.000000000Z"
+)
+
+// stagingFilePath is where extractDB extracts a database before installDB validates it
+func (s *Service) stagingFilePath(dbType string) string {
+	return s.cfg.IPService.MaxMind.DBFilePath(dbType) + ".new"
+}
+
+// installDB validates the staged database and renames it into place, the installed one is kept as a previous version
+func (s *Service) installDB(ctx context.Context, dbType string) error {
+	meta := s.DBMeta[dbType]
+	meta.filesMu.Lock()
+	defer meta.filesMu.Unlock()
+
+	staging := s.stagingFilePath(dbType)
+	defer os.Remove(staging)
+
+	if err := s.validateDBFile(dbType, staging); err != nil {
+		s.Log.Error(err, "downloaded database failed validation, keeping the installed one", "dbType", dbType)
+		return err
+	}
+
+	current := s.cfg.IPService.MaxMind.DBFilePath(dbType)
+
+	if s.cfg.IPService.MaxMind.IsDBPresent(dbType) {
+		same, err := sameFile(current, staging)
+		if err != nil {
+			return err
+		}
+		if same {
+			s.Log.Debug("database unchanged", "dbType", dbType)
+			return nil
+		}
+
+		if err := s.keepVersion(dbType); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(staging, current); err != nil {
+		return err
+	}
+
+	s.Log.Info("Installed database", "dbType", dbType)
+
+	return nil
+}
+
+// keepVersion moves the installed database to the versions folder and prunes the oldest versions
+func (s *Service) keepVersion(dbType string) error {
+	folder := s.cfg.IPService.MaxMind.VersionsFolder()
+	if err := os.MkdirAll(folder, 0750); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("GeoLite2-%s.%s.mmdb", dbType, time.Now().UTC().Format(versionTimeFormat))
+	if err := os.Rename(s.cfg.IPService.MaxMind.DBFilePath(dbType), filepath.Join(folder, name)); err != nil {
+		return err
+	}
+
+	keep := s.cfg.IPService.MaxMind.KeepVersions
+	if keep <= 0 {
+		keep = defaultKeepVersions
+	}
+
+	versions, err := s.versions(dbType)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions[min(keep, len(versions)):] {
+		s.Log.Debug("remove old version", "dbType", dbType, "path", version)
+		if err := os.Remove(version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// versions returns the kept versions of dbType, newest first
+func (s *Service) versions(dbType string) ([]string, error) {
+	versions, err := filepath.Glob(filepath.Join(s.cfg.IPService.MaxMind.VersionsFolder(), fmt.Sprintf("GeoLite2-%s.*.mmdb", dbType)))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(versions)
+	slices.Reverse(versions)
+	return versions, nil
+}
+
+// Rollback replaces the installed database with the newest kept version that validates, and loads it. It waits for
+// an install or load of the database by the update loop to finish.
+func (s *Service) Rollback(ctx context.Context, dbType string) error {
+	meta, ok := s.DBMeta[dbType]
+	if !ok {
+		return helpers.ErrUnknownDataset
+	}
+	meta.filesMu.Lock()
+	defer meta.filesMu.Unlock()
+
+	return s.rollback(ctx, dbType)
+}
+
+// rollback is Rollback, with the files lock of dbType held
+func (s *Service) rollback(ctx context.Context, dbType string) error {
+	if s.cfg.IPService.MaxMind.IsLocal(dbType) {
+		return fmt.Errorf("%s is a local database, roll back its file instead", dbType)
+	}
+
+	versions, err := s.versions(dbType)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		if err := s.validateDBFile(dbType, version); err != nil {
+			s.Log.Error(err, "kept version failed validation, removing it", "dbType", dbType, "path", version)
+			if err := os.Remove(version); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.Rename(version, s.cfg.IPService.MaxMind.DBFilePath(dbType)); err != nil {
+			return err
+		}
+
+		s.Log.Info("Rolled back database", "dbType", dbType, "version", filepath.Base(version))
+		metrics.rollbacks.WithLabelValues(dbType).Inc()
+
+		return s.loadDB(ctx, dbType)
+	}
+
+	return fmt.Errorf("%s %w", dbType, helpers.ErrNoPreviousVersion)
+}
+
+// validateDBFile opens path with the provider of dbType and runs the probe lookups on it
+func (s *Service) validateDBFile(dbType, path string) error {
+	db, err := s.provider(dbType).open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return probeReader(dbType, db)
+}
+
+// sameFile reports whether the files at a and b have the same content
+func sameFile(a, b string) (bool, error) {
+	hashA, err := fileSHA256(a)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := fileSHA256(b)
+	if err != nil {
+		return false, err
+	}
+	return hashA == hashB, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,203 @@
+package maxmind
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// testArchive returns a tar.gz holding dbFile as GeoLite2-<dbType>.mmdb in a versioned folder, like maxmind does
+func testArchive(t *testing.T, dbType, dbFile string) []byte {
+	b, err := os.ReadFile(dbFile)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name:     "GeoLite2-" + dbType + "_20240101/GeoLite2-" + dbType + ".mmdb",
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+		Size:     int64(len(b)),
+	}))
+	_, err = tw.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	return buf.Bytes()
+}
+
+func newVersionsTestService(t *testing.T, remoteURL string) *Service {
+	tracer, err := trace.NewForTesting(t.Context(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	return &Service{
+		cfg: &model.Cfg{
+			IPService: &model.IPService{
+				MaxMind: model.MaxMind{
+					RemoteURL:     remoteURL,
+					ArchiveFormat: "tar.gz",
+					BaseFolder:    t.TempDir(),
+					KeepVersions:  2,
+					DB: map[string]model.MaxMindDB{
+						model.MaxmindDBTypeASN: {},
+					},
+				},
+			},
+		},
+		Log:        logger.NewSimple("test"),
+		TP:         tracer,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		reloadChan: make(chan string, 10),
+		kvStore:    mockKVStore{},
+		DBMeta: DBMeta{
+			model.MaxmindDBTypeASN: &DBObject{rateLimit: *rate.NewLimiter(rate.Inf, 1)},
+		},
+	}
+}
+
+func TestDownloadArchive(t *testing.T) {
+	archive := testArchive(t, model.MaxmindDBTypeASN, "../../testdata/GeoLite2-asn-Test.mmdb")
+	sum := sha256.Sum256(archive)
+
+	tts := []struct {
+		name    string
+		sha256  string
+		wantErr bool
+	}{
+		{
+			name:   "checksum matches",
+			sha256: hex.EncodeToString(sum[:]) + "  GeoLite2-ASN_20240101.tar.gz\n",
+		},
+		{
+			name:    "checksum mismatch",
+			sha256:  hex.EncodeToString(make([]byte, sha256.Size)) + "  GeoLite2-ASN_20240101.tar.gz\n",
+			wantErr: true,
+		},
+		{
+			name:    "malformed checksum",
+			sha256:  "not found",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("suffix") == "tar.gz.sha256" {
+					w.Write([]byte(tt.sha256))
+					return
+				}
+				w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+				w.Write(archive)
+			}))
+			defer server.Close()
+
+			s := newVersionsTestService(t, server.URL)
+
+			err := s.downloadArchive(t.Context(), model.MaxmindDBTypeASN)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.False(t, s.cfg.IPService.MaxMind.IsDBPresent(model.MaxmindDBTypeASN))
+				assert.False(t, s.cfg.IPService.MaxMind.IsArchivePresent(model.MaxmindDBTypeASN))
+				// a failed download is retried on the next check
+				assert.Empty(t, s.kvStore.GetRemoteVersion(t.Context(), model.MaxmindDBTypeASN))
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, s.cfg.IPService.MaxMind.IsDBPresent(model.MaxmindDBTypeASN))
+			assert.Equal(t, "2024-01-01 00:00:00 +0000 GMT", s.kvStore.GetRemoteVersion(t.Context(), model.MaxmindDBTypeASN))
+			assert.Equal(t, model.MaxmindDBTypeASN, <-s.reloadChan)
+		})
+	}
+}
+
+func TestInstallDB(t *testing.T) {
+	s := newVersionsTestService(t, "")
+	dbType := model.MaxmindDBTypeASN
+
+	// a city database does not validate as ASN and is not installed
+	copyTestFile(t, "../../testdata/GeoLite2-city-Test.mmdb", s.stagingFilePath(dbType))
+	assert.Error(t, s.installDB(t.Context(), dbType))
+	assert.False(t, s.cfg.IPService.MaxMind.IsDBPresent(dbType))
+
+	copyTestFile(t, "../../testdata/GeoLite2-asn-Test.mmdb", s.stagingFilePath(dbType))
+	require.NoError(t, s.installDB(t.Context(), dbType))
+	assert.True(t, s.cfg.IPService.MaxMind.IsDBPresent(dbType))
+
+	// the same content is not kept as a version
+	copyTestFile(t, "../../testdata/GeoLite2-asn-Test.mmdb", s.stagingFilePath(dbType))
+	require.NoError(t, s.installDB(t.Context(), dbType))
+	versions, err := s.versions(dbType)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	// each change keeps the installed database, up to keep_versions
+	for i := range 3 {
+		b, err := os.ReadFile("../../testdata/GeoLite2-asn-Test.mmdb")
+		require.NoError(t, err)
+		// trailing bytes after the metadata do not change the lookups, but do change the content
+		require.NoError(t, os.WriteFile(s.stagingFilePath(dbType), append(b, make([]byte, i+1)...), 0600))
+		require.NoError(t, s.installDB(t.Context(), dbType))
+	}
+
+	versions, err = s.versions(dbType)
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+	_, err = os.Stat(s.stagingFilePath(dbType))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRollback(t *testing.T) {
+	s := newVersionsTestService(t, "")
+	dbType := model.MaxmindDBTypeASN
+
+	assert.ErrorIs(t, s.Rollback(t.Context(), dbType), helpers.ErrNoPreviousVersion)
+	assert.ErrorIs(t, s.Rollback(t.Context(), "Unknown"), helpers.ErrUnknownDataset)
+
+	copyTestFile(t, "../../testdata/GeoLite2-asn-Test.mmdb", s.stagingFilePath(dbType))
+	require.NoError(t, s.installDB(t.Context(), dbType))
+	previous, err := fileSHA256(s.cfg.IPService.MaxMind.DBFilePath(dbType))
+	require.NoError(t, err)
+
+	// an installed database that turns out broken, the kept version is restored and loaded
+	require.NoError(t, s.keepVersion(dbType))
+	require.NoError(t, os.WriteFile(s.cfg.IPService.MaxMind.DBFilePath(dbType), []byte("broken"), 0600))
+	assert.Error(t, s.loadDB(t.Context(), dbType))
+
+	require.NoError(t, s.Rollback(t.Context(), dbType))
+	current, err := fileSHA256(s.cfg.IPService.MaxMind.DBFilePath(dbType))
+	require.NoError(t, err)
+	assert.Equal(t, previous, current)
+	assert.NotNil(t, s.DBMeta[dbType].reader.Load())
+
+	versions, err := s.versions(dbType)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	// an invalid kept version is removed instead of restored
+	require.NoError(t, os.MkdirAll(s.cfg.IPService.MaxMind.VersionsFolder(), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(s.cfg.IPService.MaxMind.VersionsFolder(), "GeoLite2-ASN.20240101T000000.000000000Z.mmdb"), []byte("broken"), 0600))
+	assert.ErrorIs(t, s.Rollback(t.Context(), dbType), helpers.ErrNoPreviousVersion)
+	versions, err = s.versions(dbType)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+}
@@ -0,0 +1,88 @@
+package maxmind
+
+import (
+	"context"
+	"fmt"
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// WalkGeo calls fn with each network of the City database and its normalized geolocation
+func (s *Service) WalkGeo(ctx context.Context, fn func(prefix netip.Prefix, record *model.GeoRecord) error) error {
+	provider := s.cfg.IPService.MaxMind.Provider(model.MaxmindDBTypeCity)
+
+	return s.walk(ctx, model.MaxmindDBTypeCity, func(prefix netip.Prefix, ip net.IP, reader geoReader) error {
+		city, err := reader.City(ip)
+		if err != nil {
+			return err
+		}
+		return fn(prefix, geoRecordOf(city, provider))
+	})
+}
+
+// WalkASN calls fn with each network of the ASN database and its normalized origin AS
+func (s *Service) WalkASN(ctx context.Context, fn func(prefix netip.Prefix, record *model.ASNRecord) error) error {
+	provider := s.cfg.IPService.MaxMind.Provider(model.MaxmindDBTypeASN)
+
+	return s.walk(ctx, model.MaxmindDBTypeASN, func(prefix netip.Prefix, ip net.IP, reader geoReader) error {
+		asn, err := reader.ASN(ip)
+		if err != nil {
+			return err
+		}
+		return fn(prefix, asnRecordOf(asn, provider))
+	})
+}
+
+// walk calls fn with each network of dbType holding data, records are read with the loaded reader so they are
+// mapped like lookups are. IPv4 networks are passed as IPv4 prefixes, once.
+func (s *Service) walk(ctx context.Context, dbType string, fn func(prefix netip.Prefix, ip net.IP, reader geoReader) error) error {
+	h, err := s.acquire(dbType)
+	if err != nil {
+		return err
+	}
+	defer h.release()
+
+	// the loaded reader has no network iterator, so its file is opened again and checked to still be the loaded one
+	db, err := maxminddb.Open(h.path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	loaded := h.reader.Metadata()
+	if db.Metadata.BuildEpoch != loaded.BuildEpoch || db.Metadata.NodeCount != loaded.NodeCount {
+		return fmt.Errorf("%s %w", dbType, helpers.ErrDatasetChanged)
+	}
+
+	networks := db.Networks(maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// the records are read by fn, so the iterator decodes nothing
+		network, err := networks.Network(&struct{}{})
+		if err != nil {
+			return err
+		}
+
+		addr, ok := netip.AddrFromSlice(network.IP)
+		if !ok {
+			return fmt.Errorf("invalid network %s", network)
+		}
+		bits, _ := network.Mask.Size()
+		if addr.Is4In6() {
+			addr, bits = addr.Unmap(), bits-96
+		}
+
+		if err := fn(netip.PrefixFrom(addr, bits), network.IP, h.reader); err != nil {
+			return err
+		}
+	}
+
+	return networks.Err()
+}
@@ -0,0 +1,75 @@
+package maxmind
+
+import (
+	"errors"
+	"ip_service/pkg/model"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	tracer, err := trace.NewForTesting(t.Context(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	s, err := newTestService(tracer, logger.NewSimple("test"), map[string]string{
+		model.MaxmindDBTypeCity: "../../testdata/GeoLite2-city-Test.mmdb",
+		model.MaxmindDBTypeASN:  testASNFile,
+	})
+	require.NoError(t, err)
+
+	ip := netip.MustParseAddr("89.160.20.112")
+
+	var geoNetworks int
+	var found *model.GeoRecord
+	require.NoError(t, s.WalkGeo(t.Context(), func(prefix netip.Prefix, record *model.GeoRecord) error {
+		geoNetworks++
+		assert.True(t, prefix.IsValid())
+		if prefix.Contains(ip) {
+			found = record
+		}
+		return nil
+	}))
+	assert.Greater(t, geoNetworks, 1)
+	require.NotNil(t, found)
+	assert.Equal(t, "SE", found.CountryISO)
+
+	lookup, err := s.LookupGeo(t.Context(), ip.AsSlice())
+	require.NoError(t, err)
+	assert.Equal(t, lookup, found)
+
+	var asnNetworks int
+	require.NoError(t, s.WalkASN(t.Context(), func(prefix netip.Prefix, record *model.ASNRecord) error {
+		asnNetworks++
+		assert.NotZero(t, record.ASN)
+		return nil
+	}))
+	assert.Greater(t, asnNetworks, 1)
+
+	stop := errors.New("stop")
+	assert.ErrorIs(t, s.WalkASN(t.Context(), func(prefix netip.Prefix, record *model.ASNRecord) error { return stop }), stop)
+}
+
+func TestWalkChangedFile(t *testing.T) {
+	tracer, err := trace.NewForTesting(t.Context(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "asn.mmdb")
+	copyTestFile(t, testASNFile, path)
+
+	s, err := newTestService(tracer, logger.NewSimple("test"), map[string]string{model.MaxmindDBTypeASN: path})
+	require.NoError(t, err)
+
+	// the file is replaced by another database after it was loaded
+	require.NoError(t, os.Remove(path))
+	copyTestFile(t, "../../testdata/GeoLite2-city-Test.mmdb", path)
+
+	err = s.WalkASN(t.Context(), func(prefix netip.Prefix, record *model.ASNRecord) error { return nil })
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package mmdbexport
+
+import (
+	"cmp"
+	"ip_service/pkg/model"
+	"ip_service/pkg/rpsl"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// geoData returns the fields of a GeoIP2 City record, so readers use the paths they use for MaxMind's db's
+func geoData(record *model.GeoRecord) map[string]any {
+	data := map[string]any{}
+
+	if len(record.City) > 0 {
+		data["city"] = map[string]any{"names": record.City}
+	}
+
+	continent := map[string]any{}
+	if record.ContinentCode != "" {
+		continent["code"] = record.ContinentCode
+	}
+	if len(record.Continent) > 0 {
+		continent["names"] = record.Continent
+	}
+	if len(continent) > 0 {
+		data["continent"] = continent
+	}
+
+	country := map[string]any{}
+	if record.CountryISO != "" {
+		country["iso_code"] = record.CountryISO
+	}
+	if len(record.Country) > 0 {
+		country["names"] = record.Country
+	}
+	if record.IsEU {
+		country["is_in_european_union"] = true
+	}
+	if len(country) > 0 {
+		data["country"] = country
+	}
+
+	location := map[string]any{}
+	if record.Coordinates != nil {
+		location["latitude"] = record.Coordinates.Latitude
+		location["longitude"] = record.Coordinates.Longitude
+	}
+	if record.Timezone != "" {
+		location["time_zone"] = record.Timezone
+	}
+	if len(location) > 0 {
+		data["location"] = location
+	}
+
+	if record.PostalCode != "" {
+		data["postal"] = map[string]any{"code": record.PostalCode}
+	}
+
+	subdivision := map[string]any{}
+	if record.RegionCode != "" {
+		subdivision["iso_code"] = record.RegionCode
+	}
+	if len(record.Region) > 0 {
+		subdivision["names"] = record.Region
+	}
+	if len(subdivision) > 0 {
+		data["subdivisions"] = []any{subdivision}
+	}
+
+	return data
+}
+
+// asnData returns the fields of a GeoLite2 ASN record
+func asnData(record *model.ASNRecord) map[string]any {
+	data := map[string]any{}
+	if record.ASN != 0 {
+		data["autonomous_system_number"] = uint32(record.ASN)
+	}
+	if record.Organization != "" {
+		data["autonomous_system_organization"] = record.Organization
+	}
+	return data
+}
+
+// irrData returns the irr section of a route, its origin AS numbers and the first organization of its objects
+func irrData(prefix netip.Prefix, asn rpsl.ASN) map[string]any {
+	type origin struct {
+		asn    uint32
+		object *rpsl.Object
+	}
+
+	var origins []origin
+	for key, object := range asn {
+		n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(key), "AS"), 10, 32)
+		if err != nil {
+			continue
+		}
+		origins = append(origins, origin{asn: uint32(n), object: object})
+	}
+	if len(origins) == 0 {
+		return nil
+	}
+	slices.SortFunc(origins, func(a, b origin) int { return cmp.Compare(a.asn, b.asn) })
+
+	irr := map[string]any{"route": prefix.String()}
+
+	asns := make([]any, 0, len(origins))
+	for _, o := range origins {
+		asns = append(asns, o.asn)
+		if _, ok := irr["organization"]; ok || o.object == nil {
+			continue
+		}
+		if organization := cmp.Or(o.object.ORGName, o.object.ORG, o.object.Owner); organization != "" {
+			irr["organization"] = organization
+		}
+	}
+	irr["origin_asns"] = asns
+
+	return map[string]any{"irr": irr}
+}
+
+// labelsData returns the labels section, with the fields of the labels reply section
+func labelsData(labels *model.Labels) map[string]any {
+	if labels == nil {
+		return nil
+	}
+
+	section := map[string]any{"prefixes": labels.Prefixes}
+	if labels.Organization != "" {
+		section["organization"] = labels.Organization
+	}
+	if labels.Coordinates != nil {
+		section["coordinates"] = map[string]any{
+			"latitude":  labels.Coordinates.Latitude,
+			"longitude": labels.Coordinates.Longitude,
+		}
+	}
+	if len(labels.Labels) > 0 {
+		section["labels"] = labels.Labels
+	}
+
+	return map[string]any{"labels": section}
+}
@@ -0,0 +1,32 @@
+package mmdbexport
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the prometheus metrics for the mmdb export
+var metrics = struct {
+	duration    prometheus.Histogram
+	size        prometheus.Gauge
+	lastSuccess prometheus.Gauge
+	lastFailure prometheus.Gauge
+}{
+	duration: promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ip_service_mmdb_export_duration_seconds",
+		Help:    "Time spent building an mmdb export",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+	}),
+	size: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_mmdb_export_size_bytes",
+		Help: "Size of the last mmdb export",
+	}),
+	lastSuccess: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_mmdb_export_last_success_timestamp_seconds",
+		Help: "Time of the last successful mmdb export",
+	}),
+	lastFailure: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_mmdb_export_last_failure_timestamp_seconds",
+		Help: "Time of the last failed mmdb export",
+	}),
+}
@@ -0,0 +1,162 @@
+// Package mmdbexport writes the loaded datasets as one MaxMind DB file, for nginx, HAProxy, Suricata and other mmdb readers
+package mmdbexport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/mmdb"
+	"ip_service/pkg/model"
+	"ip_service/pkg/rpsl"
+	"net/netip"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+)
+
+// DatabaseType is the database_type of the exported file
+const DatabaseType = "ip_service-Enriched"
+
+// GeoWalker walks the networks of the geolocation and ASN databases, e.g. maxmind.Service
+type GeoWalker interface {
+	WalkGeo(ctx context.Context, fn func(prefix netip.Prefix, record *model.GeoRecord) error) error
+	WalkASN(ctx context.Context, fn func(prefix netip.Prefix, record *model.ASNRecord) error) error
+}
+
+// RouteWalker walks the IRR route objects, least specific first, e.g. whois.Service
+type RouteWalker interface {
+	WalkRoutes(ctx context.Context, fn func(prefix netip.Prefix, asn rpsl.ASN) error) error
+}
+
+// LabelWalker walks the operator defined prefix labels, least specific first, e.g. prefixlabels.Service
+type LabelWalker interface {
+	WalkLabels(ctx context.Context, fn func(prefix netip.Prefix, labels *model.Labels) error) error
+}
+
+// Service exports the loaded datasets as a MaxMind DB file
+type Service struct {
+	log     *logger.Log
+	geo     GeoWalker
+	routes  RouteWalker
+	labels  LabelWalker
+	running atomic.Bool
+}
+
+// New creates a new mmdbexport service, a nil walker leaves its dataset out of the export
+func New(ctx context.Context, geo GeoWalker, routes RouteWalker, labels LabelWalker, log *logger.Log) (*Service, error) {
+	s := &Service{
+		log:    log,
+		geo:    geo,
+		routes: routes,
+		labels: labels,
+	}
+
+	s.log.Info("Started")
+
+	return s, nil
+}
+
+// Export writes the geolocation, ASN, IRR origins and prefix labels of every network to w.
+// The records have the GeoIP2 City and ASN fields, and irr and labels sections. One export runs at a time.
+func (s *Service) Export(ctx context.Context, w io.Writer) error {
+	if !s.running.CompareAndSwap(false, true) {
+		return helpers.ErrExportInProgress
+	}
+	defer s.running.Store(false)
+
+	start := time.Now()
+
+	n, err := s.export(ctx, w)
+	if err != nil {
+		s.log.Error(err, "export failed")
+		metrics.lastFailure.SetToCurrentTime()
+		return err
+	}
+
+	metrics.duration.Observe(time.Since(start).Seconds())
+	metrics.size.Set(float64(n))
+	metrics.lastSuccess.SetToCurrentTime()
+	s.log.Info("Exported", "bytes", n, "duration", time.Since(start))
+
+	return nil
+}
+
+func (s *Service) export(ctx context.Context, w io.Writer) (int64, error) {
+	languages := map[string]bool{}
+	writer := mmdb.NewWriter(mmdb.Metadata{
+		DatabaseType: DatabaseType,
+		Description:  map[string]string{"en": "ip_service geolocation, ASN, IRR origins and prefix labels"},
+		BuildEpoch:   time.Now(),
+	})
+
+	// the datasets have distinct keys, and each is walked least specific first
+	if s.geo != nil {
+		err := s.geo.WalkGeo(ctx, func(prefix netip.Prefix, record *model.GeoRecord) error {
+			for _, names := range []map[string]string{record.City, record.Country, record.Region, record.Continent} {
+				for language := range names {
+					languages[language] = true
+				}
+			}
+			return s.insert(writer, prefix, geoData(record))
+		})
+		if err := s.skipMissing("City", err); err != nil {
+			return 0, err
+		}
+
+		err = s.geo.WalkASN(ctx, func(prefix netip.Prefix, record *model.ASNRecord) error {
+			return s.insert(writer, prefix, asnData(record))
+		})
+		if err := s.skipMissing("ASN", err); err != nil {
+			return 0, err
+		}
+	}
+
+	if s.routes != nil {
+		err := s.routes.WalkRoutes(ctx, func(prefix netip.Prefix, asn rpsl.ASN) error {
+			return s.insert(writer, prefix, irrData(prefix, asn))
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if s.labels != nil {
+		err := s.labels.WalkLabels(ctx, func(prefix netip.Prefix, labels *model.Labels) error {
+			return s.insert(writer, prefix, labelsData(labels))
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	writer.SetLanguages(slices.Sorted(maps.Keys(languages)))
+
+	return writer.WriteTo(w)
+}
+
+// insert stores data for prefix, empty data is left out
+func (s *Service) insert(writer *mmdb.Writer, prefix netip.Prefix, data map[string]any) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return writer.Insert(prefix, data)
+}
+
+// skipMissing leaves a database that is not loaded out of the export
+func (s *Service) skipMissing(dbType string, err error) error {
+	if errors.Is(err, helpers.ErrDBNotLoaded) || errors.Is(err, helpers.ErrUnknownDataset) {
+		s.log.Info("database not loaded, left out of the export", "dbType", dbType)
+		return nil
+	}
+	return err
+}
+
+// Close closes the service
+func (s *Service) Close(ctx context.Context) error {
+	s.log.Info("Quit")
+	return nil
+}
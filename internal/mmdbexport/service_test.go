@@ -0,0 +1,131 @@
+package mmdbexport
+
+import (
+	"bytes"
+	"context"
+	"ip_service/internal/lctree"
+	"ip_service/internal/maxmind/maxmindtest"
+	"ip_service/internal/prefixlabels"
+	"ip_service/internal/whois"
+	"ip_service/pkg/model"
+	"ip_service/pkg/rpsl"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/SUNET/vc/pkg/trace"
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportRecord holds the sections of an exported record that are not GeoIP2 fields
+type exportRecord struct {
+	IRR struct {
+		Route        string   `maxminddb:"route"`
+		OriginASNs   []uint32 `maxminddb:"origin_asns"`
+		Organization string   `maxminddb:"organization"`
+	} `maxminddb:"irr"`
+	Labels struct {
+		Prefixes     []string          `maxminddb:"prefixes"`
+		Organization string            `maxminddb:"organization"`
+		Labels       map[string]string `maxminddb:"labels"`
+	} `maxminddb:"labels"`
+}
+
+func newTestExport(t *testing.T) *Service {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	max, err := maxmindtest.New(t, tracer, logger.NewSimple("test"), map[string]string{
+		model.MaxmindDBTypeCity: "../../testdata/GeoLite2-city-Test.mmdb",
+		model.MaxmindDBTypeASN:  "../../testdata/GeoLite2-asn-Test.mmdb",
+	})
+	require.NoError(t, err)
+
+	routes := whois.NewTestService(lctree.New(logger.NewSimple("test")), rpsl.RouterClass{
+		"89.160.0.0/16":   rpsl.ASN{"AS29518": &rpsl.Object{ORGName: "Bredband2"}},
+		"89.160.20.0/24":  rpsl.ASN{"AS64512": &rpsl.Object{}, "AS64500": &rpsl.Object{ORG: "ORG-EX1"}},
+		"2001:db8::/32":   rpsl.ASN{"AS64496": nil},
+		"not a prefix/99": rpsl.ASN{"AS1": nil},
+	})
+
+	path := filepath.Join(t.TempDir(), "labels.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("prefixes:\n  - prefix: 89.160.20.112/28\n    organization: Example\n    labels: {network: eduroam}\n"), 0600))
+	labels, err := prefixlabels.New(context.TODO(), &model.Cfg{IPService: &model.IPService{PrefixLabels: model.PrefixLabels{FilePath: path}}}, logger.NewSimple("test"))
+	require.NoError(t, err)
+	t.Cleanup(func() { labels.Close(context.TODO()) })
+
+	s, err := New(context.TODO(), max, routes, labels, logger.NewSimple("test"))
+	require.NoError(t, err)
+	return s
+}
+
+func TestExport(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, newTestExport(t).Export(context.TODO(), buf))
+
+	db, err := maxminddb.FromBytes(buf.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, db.Verify())
+	assert.Equal(t, DatabaseType, db.Metadata.DatabaseType)
+	assert.Contains(t, db.Metadata.Languages, "en")
+	assert.Contains(t, db.Metadata.Languages, "de")
+
+	ip := net.ParseIP("89.160.20.115")
+
+	// GeoIP2 readers read the City and ASN fields
+	city := &geoip2.City{}
+	require.NoError(t, db.Lookup(ip, city))
+	assert.Equal(t, "SE", city.Country.IsoCode)
+	assert.Equal(t, "Linköping", city.City.Names["en"])
+	assert.True(t, city.Country.IsInEuropeanUnion)
+	assert.NotZero(t, city.Location.Latitude)
+	assert.Equal(t, "E", city.Subdivisions[0].IsoCode)
+
+	asn := &geoip2.ASN{}
+	require.NoError(t, db.Lookup(ip, asn))
+	assert.Equal(t, uint(29518), asn.AutonomousSystemNumber)
+	assert.Equal(t, "Bredband2 AB", asn.AutonomousSystemOrganization)
+
+	got := &exportRecord{}
+	require.NoError(t, db.Lookup(ip, got))
+	assert.Equal(t, "89.160.20.0/24", got.IRR.Route)
+	assert.Equal(t, []uint32{64500, 64512}, got.IRR.OriginASNs)
+	assert.Equal(t, "ORG-EX1", got.IRR.Organization)
+	assert.Equal(t, []string{"89.160.20.112/28"}, got.Labels.Prefixes)
+	assert.Equal(t, "Example", got.Labels.Organization)
+	assert.Equal(t, map[string]string{"network": "eduroam"}, got.Labels.Labels)
+
+	// outside the labels prefix the less specific route is kept
+	got = &exportRecord{}
+	require.NoError(t, db.Lookup(net.ParseIP("89.160.21.1"), got))
+	assert.Equal(t, "89.160.0.0/16", got.IRR.Route)
+	assert.Equal(t, "Bredband2", got.IRR.Organization)
+	assert.Empty(t, got.Labels.Prefixes)
+
+	got = &exportRecord{}
+	require.NoError(t, db.Lookup(net.ParseIP("2001:db8::1"), got))
+	assert.Equal(t, []uint32{64496}, got.IRR.OriginASNs)
+}
+
+func TestExportWithoutDatasets(t *testing.T) {
+	tracer, err := trace.NewForTesting(context.TODO(), "test", logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	max, err := maxmindtest.New(t, tracer, logger.NewSimple("test"), nil)
+	require.NoError(t, err)
+
+	s, err := New(context.TODO(), max, nil, nil, logger.NewSimple("test"))
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, s.Export(context.TODO(), buf))
+
+	db, err := maxminddb.FromBytes(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), db.Metadata.NodeCount)
+}
@@ -0,0 +1,204 @@
+package prefixlabels
+
+import (
+	"cmp"
+	"fmt"
+	"ip_service/pkg/model"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+
+	patricia "github.com/kentik/patricia"
+	tree "github.com/kentik/patricia/generics_tree"
+	"gopkg.in/yaml.v2"
+)
+
+// file is the prefix labels file, json is read as yaml
+type file struct {
+	Prefixes []*entry `yaml:"prefixes"`
+}
+
+// entry is an operator defined prefix, a bare address is a /32 or /128
+type entry struct {
+	Prefix       string               `yaml:"prefix"`
+	Organization string               `yaml:"organization"`
+	Coordinates  *model.Coordinates   `yaml:"coordinates"`
+	Labels       map[string]string    `yaml:"labels"`
+	Override     *model.LabelOverride `yaml:"override"`
+
+	prefix netip.Prefix
+}
+
+// table holds the entries of a file by prefix
+type table struct {
+	v4 *tree.TreeV4[*entry]
+	v6 *tree.TreeV6[*entry]
+	// prefixes holds the prefixes of the entries, least specific first
+	prefixes []netip.Prefix
+}
+
+func newTable() *table {
+	return &table{
+		v4: tree.NewTreeV4[*entry](),
+		v6: tree.NewTreeV6[*entry](),
+	}
+}
+
+// loadFile reads path into a new table, any invalid or duplicate prefix fails the whole file
+func loadFile(path string) (*table, int, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	f := &file{}
+	if err := yaml.UnmarshalStrict(b, f); err != nil {
+		return nil, 0, err
+	}
+
+	t := newTable()
+	seen := map[netip.Prefix]bool{}
+
+	for i, e := range f.Prefixes {
+		prefix, err := parsePrefix(e.Prefix)
+		if err != nil {
+			return nil, 0, fmt.Errorf("prefixes[%d]: %w", i, err)
+		}
+		if seen[prefix] {
+			return nil, 0, fmt.Errorf("prefixes[%d]: duplicate prefix %s", i, prefix)
+		}
+		seen[prefix] = true
+		e.prefix = prefix
+		e.Prefix = prefix.String()
+		t.prefixes = append(t.prefixes, prefix)
+
+		v4Addr, v6Addr, err := patricia.ParseFromNetIPPrefix(prefix)
+		if err != nil {
+			return nil, 0, fmt.Errorf("prefixes[%d]: %w", i, err)
+		}
+		if prefix.Addr().Is4() {
+			t.v4.Add(*v4Addr, e, nil)
+		} else {
+			t.v6.Add(*v6Addr, e, nil)
+		}
+	}
+
+	slices.SortFunc(t.prefixes, func(a, b netip.Prefix) int {
+		return cmp.Or(cmp.Compare(a.Bits(), b.Bits()), a.Addr().Compare(b.Addr()))
+	})
+
+	return t, len(f.Prefixes), nil
+}
+
+// parsePrefix parses a prefix or a bare address, masked and with v4 mapped addresses as IPv4
+func parsePrefix(s string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if prefix.Addr().Is4In6() {
+		if prefix.Bits() < 96 {
+			return netip.Prefix{}, fmt.Errorf("invalid v4 mapped prefix %s", s)
+		}
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Masked(), nil
+}
+
+// lookup merges the entries of the prefixes holding addr, least specific first, nil if there are none
+func (t *table) lookup(addr netip.Addr) *model.Labels {
+	return t.lookupPrefix(netip.PrefixFrom(addr, addr.BitLen()))
+}
+
+// lookupPrefix merges the entries of the prefixes holding all of prefix
+func (t *table) lookupPrefix(prefix netip.Prefix) *model.Labels {
+	addr := prefix.Addr()
+	v4Addr, v6Addr, err := patricia.ParseFromNetIPAddr(addr)
+	if err != nil {
+		return nil
+	}
+
+	var entries []*entry
+	if addr.Is4() {
+		entries = t.v4.FindTags(*v4Addr)
+	} else {
+		entries = t.v6.FindTags(*v6Addr)
+	}
+	// entries more specific than prefix hold only part of it
+	entries = slices.DeleteFunc(entries, func(e *entry) bool { return e.prefix.Bits() > prefix.Bits() })
+	if len(entries) == 0 {
+		return nil
+	}
+
+	labels := &model.Labels{}
+	for _, e := range entries {
+		labels.Prefixes = append(labels.Prefixes, e.Prefix)
+		if e.Organization != "" {
+			labels.Organization = e.Organization
+		}
+		if e.Coordinates != nil {
+			labels.Coordinates = e.Coordinates
+		}
+		for key, value := range e.Labels {
+			if labels.Labels == nil {
+				labels.Labels = map[string]string{}
+			}
+			labels.Labels[key] = value
+		}
+		if e.Override != nil {
+			labels.Override = mergeOverride(labels.Override, e.Override)
+		}
+	}
+
+	return labels
+}
+
+// mergeOverride returns base with the non empty fields of o, base is nil for the first override
+func mergeOverride(base, o *model.LabelOverride) *model.LabelOverride {
+	if base == nil {
+		merged := *o
+		return &merged
+	}
+
+	merged := *base
+	if o.ASN != 0 {
+		merged.ASN = o.ASN
+	}
+	if o.ASNOrganization != "" {
+		merged.ASNOrganization = o.ASNOrganization
+	}
+	if o.City != "" {
+		merged.City = o.City
+	}
+	if o.Country != "" {
+		merged.Country = o.Country
+	}
+	if o.CountryISO != "" {
+		merged.CountryISO = o.CountryISO
+	}
+	if o.Region != "" {
+		merged.Region = o.Region
+	}
+	if o.RegionCode != "" {
+		merged.RegionCode = o.RegionCode
+	}
+	if o.PostalCode != "" {
+		merged.PostalCode = o.PostalCode
+	}
+	if o.Coordinates != nil {
+		merged.Coordinates = o.Coordinates
+	}
+	if o.Timezone != "" {
+		merged.Timezone = o.Timezone
+	}
+	if o.Continent != "" {
+		merged.Continent = o.Continent
+	}
+	return &merged
+}
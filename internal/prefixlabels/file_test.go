@@ -0,0 +1,144 @@
+package prefixlabels
+
+import (
+	"ip_service/pkg/model"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLabelsYAML = `
+prefixes:
+  - prefix: 192.0.2.0/24
+    organization: Example University
+    coordinates:
+      latitude: 59.85
+      longitude: 17.63
+    labels:
+      institution: example
+      network: campus
+    override:
+      city: Uppsala
+      country_iso: SE
+  - prefix: 192.0.2.128/25
+    labels:
+      network: eduroam
+    override:
+      city: Stockholm
+  - prefix: 2001:db8::1
+    organization: Example Host
+`
+
+var testLabelsJSON = `{"prefixes": [{"prefix": "198.51.100.0/24", "labels": {"network": "internal"}}]}`
+
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	tbl, count, err := loadFile(writeTestFile(t, "labels.yaml", testLabelsYAML))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	tts := []struct {
+		name string
+		ip   string
+		want *model.Labels
+	}{
+		{
+			name: "single prefix",
+			ip:   "192.0.2.1",
+			want: &model.Labels{
+				Prefixes:     []string{"192.0.2.0/24"},
+				Organization: "Example University",
+				Coordinates:  &model.Coordinates{Latitude: 59.85, Longitude: 17.63},
+				Labels:       map[string]string{"institution": "example", "network": "campus"},
+				Override:     &model.LabelOverride{City: "Uppsala", CountryISO: "SE"},
+			},
+		},
+		{
+			name: "more specific prefix wins",
+			ip:   "192.0.2.200",
+			want: &model.Labels{
+				Prefixes:     []string{"192.0.2.0/24", "192.0.2.128/25"},
+				Organization: "Example University",
+				Coordinates:  &model.Coordinates{Latitude: 59.85, Longitude: 17.63},
+				Labels:       map[string]string{"institution": "example", "network": "eduroam"},
+				Override:     &model.LabelOverride{City: "Stockholm", CountryISO: "SE"},
+			},
+		},
+		{
+			name: "bare address",
+			ip:   "2001:db8::1",
+			want: &model.Labels{
+				Prefixes:     []string{"2001:db8::1/128"},
+				Organization: "Example Host",
+			},
+		},
+		{
+			name: "no prefix",
+			ip:   "203.0.113.1",
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tbl.lookup(netip.MustParseAddr(tt.ip)))
+		})
+	}
+}
+
+func TestLoadFileJSON(t *testing.T) {
+	tbl, count, err := loadFile(writeTestFile(t, "labels.json", testLabelsJSON))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	got := tbl.lookup(netip.MustParseAddr("198.51.100.7"))
+	require.NotNil(t, got)
+	assert.Equal(t, map[string]string{"network": "internal"}, got.Labels)
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	tts := []struct {
+		name    string
+		content string
+	}{
+		{name: "invalid prefix", content: "prefixes:\n  - prefix: 192.0.2.0/33\n"},
+		{name: "duplicate prefix", content: "prefixes:\n  - prefix: 192.0.2.0/24\n  - prefix: 192.0.2.1/24\n"},
+		{name: "unknown field", content: "prefixes:\n  - prefix: 192.0.2.0/24\n    organisation: typo\n"},
+		{name: "not yaml", content: "prefixes: [\n"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := loadFile(writeTestFile(t, "labels.yaml", tt.content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	tts := []struct {
+		in   string
+		want string
+	}{
+		{in: "192.0.2.1/24", want: "192.0.2.0/24"},
+		{in: "192.0.2.1", want: "192.0.2.1/32"},
+		{in: "::ffff:192.0.2.0/120", want: "192.0.2.0/24"},
+		{in: "2001:db8::/32", want: "2001:db8::/32"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parsePrefix(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
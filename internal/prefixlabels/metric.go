@@ -0,0 +1,26 @@
+package prefixlabels
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the prometheus metrics for the prefix labels
+var metrics = struct {
+	prefixes    prometheus.Gauge
+	lastSuccess prometheus.Gauge
+	lastFailure prometheus.Gauge
+}{
+	prefixes: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_prefix_labels_prefixes",
+		Help: "Prefixes in the loaded prefix labels file",
+	}),
+	lastSuccess: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_prefix_labels_last_success_timestamp_seconds",
+		Help: "Time of the last successfully loaded prefix labels file",
+	}),
+	lastFailure: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_prefix_labels_last_failure_timestamp_seconds",
+		Help: "Time of the last failed prefix labels load",
+	}),
+}
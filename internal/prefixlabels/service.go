@@ -0,0 +1,162 @@
+package prefixlabels
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+)
+
+const defaultPollInterval = time.Minute
+
+// Service serves the operator defined prefix labels, the file is reloaded when it changes and on Reload
+type Service struct {
+	log         *logger.Log
+	table       atomic.Pointer[table]
+	mu          sync.Mutex
+	filePath    string
+	state       fileState
+	quitChan    chan struct{}
+	hooksMu     sync.Mutex
+	reloadHooks []func(ctx context.Context)
+}
+
+// fileState is the part of a file's stat that marks it as changed
+type fileState struct {
+	modTime int64
+	size    int64
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
+
+// New creates a new prefixlabels service, without a file_path it has no labels until one is configured by Reload
+func New(ctx context.Context, cfg *model.Cfg, log *logger.Log) (*Service, error) {
+	s := &Service{
+		log:      log,
+		filePath: cfg.IPService.PrefixLabels.FilePath,
+		quitChan: make(chan struct{}),
+	}
+	s.table.Store(newTable())
+
+	s.mu.Lock()
+	s.load(ctx)
+	s.mu.Unlock()
+
+	interval := cfg.IPService.PrefixLabels.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.mu.Lock()
+				if s.filePath != "" {
+					if current := statFile(s.filePath); current != s.state && current != (fileState{}) {
+						s.log.Info("Prefix labels file changed", "path", s.filePath)
+						s.load(ctx)
+					}
+				}
+				s.mu.Unlock()
+			case <-s.quitChan:
+				return
+			}
+		}
+	}()
+
+	s.log.Info("Started")
+
+	return s, nil
+}
+
+// load reads the file and swaps in its table, an invalid file keeps the previous table. s.mu must be held.
+func (s *Service) load(ctx context.Context) {
+	// stat before loading, so a change during the load is picked up by the next poll
+	s.state = statFile(s.filePath)
+
+	t, count := newTable(), 0
+	if s.filePath != "" {
+		var err error
+		t, count, err = loadFile(s.filePath)
+		if err != nil {
+			s.log.Error(err, "failed to load prefix labels, keeping the previous ones", "path", s.filePath)
+			metrics.lastFailure.SetToCurrentTime()
+			return
+		}
+		metrics.lastSuccess.SetToCurrentTime()
+		s.log.Info("Prefix labels loaded", "path", s.filePath, "prefixes", count)
+	}
+
+	s.table.Store(t)
+	metrics.prefixes.Set(float64(count))
+
+	s.hooksMu.Lock()
+	for _, fn := range s.reloadHooks {
+		fn(ctx)
+	}
+	s.hooksMu.Unlock()
+}
+
+// LookupLabels returns the merged labels of the prefixes holding ip, nil if there are none
+func (s *Service) LookupLabels(ctx context.Context, ip net.IP) *model.Labels {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil
+	}
+	return s.table.Load().lookup(addr.Unmap())
+}
+
+// OnReload registers fn to be called each time the labels have been reloaded
+func (s *Service) OnReload(fn func(ctx context.Context)) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.reloadHooks = append(s.reloadHooks, fn)
+}
+
+// Reload re-reads the file, from the file_path of cfg
+func (s *Service) Reload(ctx context.Context, cfg *model.Cfg) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.filePath = cfg.IPService.PrefixLabels.FilePath
+	s.load(ctx)
+}
+
+// Close stops watching the file
+func (s *Service) Close(ctx context.Context) error {
+	close(s.quitChan)
+	s.log.Info("Quit")
+	return nil
+}
+
+// WalkLabels calls fn with each prefix of the file and the labels merged for it, least specific first
+func (s *Service) WalkLabels(ctx context.Context, fn func(prefix netip.Prefix, labels *model.Labels) error) error {
+	t := s.table.Load()
+
+	for _, prefix := range t.prefixes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(prefix, t.lookupPrefix(prefix)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
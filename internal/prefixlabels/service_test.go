@@ -0,0 +1,75 @@
+package prefixlabels
+
+import (
+	"context"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+	"os"
+	"testing"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	path := writeTestFile(t, "labels.yaml", testLabelsYAML)
+	cfg := &model.Cfg{IPService: &model.IPService{PrefixLabels: model.PrefixLabels{FilePath: path}}}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	reloads := 0
+	s.OnReload(func(ctx context.Context) { reloads++ })
+
+	require.NotNil(t, s.LookupLabels(context.TODO(), net.ParseIP("192.0.2.1")))
+
+	// an invalid file keeps the previous labels
+	require.NoError(t, os.WriteFile(path, []byte("prefixes:\n  - prefix: invalid\n"), 0600))
+	s.Reload(context.TODO(), cfg)
+	assert.Equal(t, 0, reloads)
+	assert.NotNil(t, s.LookupLabels(context.TODO(), net.ParseIP("192.0.2.1")))
+
+	require.NoError(t, os.WriteFile(path, []byte(testLabelsJSON), 0600))
+	s.Reload(context.TODO(), cfg)
+	assert.Equal(t, 1, reloads)
+	assert.Nil(t, s.LookupLabels(context.TODO(), net.ParseIP("192.0.2.1")))
+	assert.NotNil(t, s.LookupLabels(context.TODO(), net.ParseIP("198.51.100.1")))
+
+	// without a file there are no labels
+	s.Reload(context.TODO(), &model.Cfg{IPService: &model.IPService{}})
+	assert.Equal(t, 2, reloads)
+	assert.Nil(t, s.LookupLabels(context.TODO(), net.ParseIP("198.51.100.1")))
+}
+
+func TestWalkLabels(t *testing.T) {
+	path := writeTestFile(t, "labels.yaml", `
+prefixes:
+  - prefix: 192.0.2.0/26
+    labels: {network: eduroam}
+  - prefix: 192.0.2.0/24
+    labels: {network: campus, institution: example}
+  - prefix: 2001:db8::1
+`)
+	cfg := &model.Cfg{IPService: &model.IPService{PrefixLabels: model.PrefixLabels{FilePath: path}}}
+
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	defer s.Close(context.TODO())
+
+	got := map[string]*model.Labels{}
+	var order []string
+	require.NoError(t, s.WalkLabels(context.TODO(), func(prefix netip.Prefix, labels *model.Labels) error {
+		got[prefix.String()] = labels
+		order = append(order, prefix.String())
+		return nil
+	}))
+
+	assert.Equal(t, []string{"192.0.2.0/24", "192.0.2.0/26", "2001:db8::1/128"}, order)
+	// a prefix has the labels of the prefixes holding it, not of those it holds
+	assert.Equal(t, map[string]string{"network": "campus", "institution": "example"}, got["192.0.2.0/24"].Labels)
+	assert.Equal(t, map[string]string{"network": "eduroam", "institution": "example"}, got["192.0.2.0/26"].Labels)
+	assert.Equal(t, []string{"192.0.2.0/24", "192.0.2.0/26"}, got["192.0.2.0/26"].Prefixes)
+}
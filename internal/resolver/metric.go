@@ -0,0 +1,32 @@
+package resolver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the prometheus metrics for the resolver pool and its cache
+var metrics = struct {
+	cache            *prometheus.CounterVec
+	queryDuration    *prometheus.HistogramVec
+	deadlineExceeded prometheus.Counter
+	queueFull        prometheus.Counter
+}{
+	cache: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ip_service_resolver_cache_total",
+		Help: "Resolver cache lookups by result, hit, negative_hit for a cached missing name or failure, or miss",
+	}, []string{"result"}),
+	queryDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ip_service_resolver_query_duration_seconds",
+		Help:    "Time spent querying the DNS servers, by record type and rcode, timeout or error",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"type", "result"}),
+	deadlineExceeded: promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ip_service_resolver_deadline_exceeded_total",
+		Help: "Lookups returned without an answer at the request deadline, their query finishes for the cache",
+	}),
+	queueFull: promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ip_service_resolver_queue_full_total",
+		Help: "Lookups failed because every worker was busy and the queue was full",
+	}),
+}
@@ -0,0 +1,287 @@
+package resolver
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// ednsSize is the UDP payload size advertised with EDNS(0), the DNS flag day 2020 default
+	ednsSize = 1232
+	// defaultNegativeTTL is the TTL of a missing name or record without an SOA in the answer
+	defaultNegativeTTL = time.Minute
+	// failureTTL is the TTL of a failed query, so a slow or broken zone is not queried by every request
+	failureTTL = 30 * time.Second
+)
+
+// answer is the result of a query, kept in the cache for ttl
+type answer struct {
+	names []string
+	addrs []net.IPAddr
+	err   error
+	ttl   time.Duration
+}
+
+// servers returns the DNS server of cfg, or the nameservers of resolv.conf, or the local resolver
+func servers(resolver, resolvConf string) []string {
+	if resolver != "" {
+		return []string{resolver}
+	}
+
+	var reply []string
+	if file, err := os.Open(resolvConf); err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				reply = append(reply, net.JoinHostPort(fields[1], "53"))
+			}
+		}
+	}
+	if len(reply) == 0 {
+		reply = []string{"127.0.0.1:53"}
+	}
+
+	return reply
+}
+
+// query asks the servers in turn for the records of qtype of name, a server that fails is skipped
+func (s *Service) query(ctx context.Context, name string, qtype dnsmessage.Type) answer {
+	start := time.Now()
+
+	var (
+		resp *dnsmessage.Message
+		err  error
+	)
+	for _, server := range s.servers {
+		resp, err = s.exchange(ctx, server, name, qtype)
+		if err == nil {
+			break
+		}
+	}
+
+	a := parseAnswer(name, qtype, resp, err)
+	metrics.queryDuration.WithLabelValues(strings.TrimPrefix(qtype.String(), "Type"), resultOf(resp, err)).Observe(time.Since(start).Seconds())
+
+	return a
+}
+
+// resultOf names the outcome of a query, its rcode, timeout or error
+func resultOf(resp *dnsmessage.Message, err error) string {
+	if err != nil {
+		if isTimeout(err) {
+			return "timeout"
+		}
+		return "error"
+	}
+	switch resp.RCode {
+	case dnsmessage.RCodeSuccess:
+		return "NOERROR"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	default:
+		return "error"
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout() || errors.Is(err, context.DeadlineExceeded)
+}
+
+// parseAnswer returns the records of qtype in resp with the lowest TTL of the answer, or the error of a missing name,
+// missing records or a failure. A missing name or record is cached for the negative TTL of the SOA (RFC 2308 5).
+func parseAnswer(name string, qtype dnsmessage.Type, resp *dnsmessage.Message, err error) answer {
+	if err != nil {
+		if isTimeout(err) {
+			return answer{err: &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}, ttl: failureTTL}
+		}
+		return answer{err: &net.DNSError{Err: "server unreachable", Name: name}, ttl: failureTTL}
+	}
+
+	switch resp.RCode {
+	case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError:
+	default:
+		return answer{err: &net.DNSError{Err: "server misbehaving", Name: name}, ttl: failureTTL}
+	}
+
+	var (
+		a   answer
+		ttl uint32
+		set bool
+	)
+	lowest := func(t uint32) {
+		if !set || t < ttl {
+			ttl, set = t, true
+		}
+	}
+
+	if resp.RCode == dnsmessage.RCodeSuccess {
+		for _, rr := range resp.Answers {
+			switch body := rr.Body.(type) {
+			case *dnsmessage.PTRResource:
+				if qtype == dnsmessage.TypePTR {
+					a.names = append(a.names, body.PTR.String())
+					lowest(rr.Header.TTL)
+				}
+			case *dnsmessage.AResource:
+				if qtype == dnsmessage.TypeA {
+					a.addrs = append(a.addrs, net.IPAddr{IP: net.IP(body.A[:])})
+					lowest(rr.Header.TTL)
+				}
+			case *dnsmessage.AAAAResource:
+				if qtype == dnsmessage.TypeAAAA {
+					a.addrs = append(a.addrs, net.IPAddr{IP: net.IP(body.AAAA[:])})
+					lowest(rr.Header.TTL)
+				}
+			case *dnsmessage.CNAMEResource:
+				// the chain is only as fresh as its links
+				lowest(rr.Header.TTL)
+			}
+		}
+		if len(a.names) > 0 || len(a.addrs) > 0 {
+			a.ttl = time.Duration(ttl) * time.Second
+			return a
+		}
+	}
+
+	a = answer{err: &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}, ttl: defaultNegativeTTL}
+	for _, rr := range resp.Authorities {
+		if soa, ok := rr.Body.(*dnsmessage.SOAResource); ok {
+			a.ttl = time.Duration(min(rr.Header.TTL, soa.MinTTL)) * time.Second
+			break
+		}
+	}
+
+	return a
+}
+
+// exchange sends a query to server over UDP, and again over TCP when the answer is truncated
+func (s *Service) exchange(ctx context.Context, server, name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	question := dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: uint16(rand.Uint32()), RecursionDesired: true},
+		Questions: []dnsmessage.Question{question},
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(ednsSize, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, err
+	}
+	query.Additionals = []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}}
+
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	resp, err := s.exchangeUDP(ctx, server, packed, query.ID, question)
+	if err == nil && resp.Truncated {
+		resp, err = s.exchangeTCP(ctx, server, packed, query.ID, question)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// answers reports whether resp is the answer to the query of id and question, the name compared case-insensitively
+// (RFC 4343)
+func answers(resp *dnsmessage.Message, id uint16, question dnsmessage.Question) bool {
+	if resp.ID != id || !resp.Response || len(resp.Questions) != 1 {
+		return false
+	}
+	q := resp.Questions[0]
+	return q.Type == question.Type && q.Class == question.Class && strings.EqualFold(q.Name.String(), question.Name.String())
+}
+
+func (s *Service) exchangeUDP(ctx context.Context, server string, packed []byte, id uint16, question dnsmessage.Question) (*dnsmessage.Message, error) {
+	conn, err := s.dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		resp := &dnsmessage.Message{}
+		// a spoofed or late answer of another query is ignored
+		if err := resp.Unpack(buf[:n]); err != nil || !answers(resp, id, question) {
+			continue
+		}
+		return resp, nil
+	}
+}
+
+func (s *Service) exchangeTCP(ctx context.Context, server string, packed []byte, id uint16, question dnsmessage.Question) (*dnsmessage.Message, error) {
+	conn, err := s.dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(packed))), packed...)); err != nil {
+		return nil, err
+	}
+
+	length := make([]byte, 2)
+	if _, err := io.ReadFull(conn, length); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+
+	resp := &dnsmessage.Message{}
+	if err := resp.Unpack(buf); err != nil {
+		return nil, err
+	}
+	if !answers(resp, id, question) {
+		return nil, fmt.Errorf("answer does not match query %d for %s", id, question.Name.String())
+	}
+
+	return resp, nil
+}
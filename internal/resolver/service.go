@@ -0,0 +1,245 @@
+// Package resolver resolves PTR names and addresses with a bounded pool of workers, concurrent lookups of the same
+// name share a query and answers are cached for their TTL, missing names for the negative TTL of their zone.
+package resolver
+
+import (
+	"context"
+	"errors"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/jellydator/ttlcache/v3"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultQueryTimeout = 5 * time.Second
+	defaultWorkers      = 16
+	defaultQueueSize    = 1024
+	defaultCacheSize    = 10000
+	defaultMaxTTL       = time.Hour
+	resolvConf          = "/etc/resolv.conf"
+)
+
+// ErrQueueFull is returned for a lookup when every worker is busy and the queue is full
+var ErrQueueFull = errors.New("resolver queue is full")
+
+// key identifies a cached answer
+type key struct {
+	name  string
+	qtype dnsmessage.Type
+}
+
+// Service resolves with a pool of workers and a cache of their answers
+type Service struct {
+	log          *logger.Log
+	servers      []string
+	dialer       net.Dialer
+	queryTimeout time.Duration
+	maxTTL       time.Duration
+	jobs         chan func()
+	quitChan     chan struct{}
+	wg           sync.WaitGroup
+	group        singleflight.Group
+	cache        *ttlcache.Cache[key, answer]
+}
+
+// New creates a new resolver service and starts its workers
+func New(ctx context.Context, cfg *model.Cfg, log *logger.Log) (*Service, error) {
+	dns := cfg.IPService.DNS
+
+	s := &Service{
+		log:          log,
+		servers:      servers(dns.Resolver, resolvConf),
+		queryTimeout: orDefault(dns.QueryTimeout, defaultQueryTimeout),
+		maxTTL:       orDefault(dns.MaxTTL, defaultMaxTTL),
+		jobs:         make(chan func(), orDefault(dns.QueueSize, defaultQueueSize)),
+		quitChan:     make(chan struct{}),
+		cache: ttlcache.New(
+			ttlcache.WithCapacity[key, answer](uint64(orDefault(dns.CacheSize, defaultCacheSize))),
+			ttlcache.WithDisableTouchOnHit[key, answer](),
+		),
+	}
+
+	go s.cache.Start()
+
+	for range orDefault(dns.Workers, defaultWorkers) {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for {
+				select {
+				case job := <-s.jobs:
+					job()
+				case <-s.quitChan:
+					return
+				}
+			}
+		}()
+	}
+
+	s.log.Info("Started", "servers", s.servers)
+
+	return s, nil
+}
+
+func orDefault[T int | time.Duration](v, def T) T {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+// LookupAddr returns the PTR names of addr
+func (s *Service) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return nil, &net.DNSError{Err: "unrecognized address", Name: addr}
+	}
+
+	a, err := s.lookup(ctx, reverseName(ip.Unmap()), dnsmessage.TypePTR)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.names, a.err
+}
+
+// LookupIPAddr returns the IPv4 and IPv6 addresses of host, the name is only missing when it has neither
+func (s *Service) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	var (
+		wg      sync.WaitGroup
+		answers [2]answer
+		errs    [2]error
+	)
+	for i, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answers[i], errs[i] = s.lookup(ctx, host, qtype)
+		}()
+	}
+	wg.Wait()
+
+	var addrs []net.IPAddr
+	for i := range answers {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		addrs = append(addrs, answers[i].addrs...)
+	}
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+
+	// a failure is worse than a missing record
+	for _, a := range answers {
+		var dnsErr *net.DNSError
+		if errors.As(a.err, &dnsErr) && !dnsErr.IsNotFound {
+			return nil, a.err
+		}
+	}
+	return nil, answers[0].err
+}
+
+// lookup returns the cached answer of name, or queues a query shared with the concurrent lookups of name. The query
+// is not canceled with ctx, when ctx is done first lookup returns its error and the answer is cached for the next one.
+func (s *Service) lookup(ctx context.Context, name string, qtype dnsmessage.Type) (answer, error) {
+	k := key{name: strings.ToLower(fqdn(name)), qtype: qtype}
+
+	if item := s.cache.Get(k); item != nil {
+		if item.Value().err != nil {
+			metrics.cache.WithLabelValues("negative_hit").Inc()
+		} else {
+			metrics.cache.WithLabelValues("hit").Inc()
+		}
+		return item.Value(), nil
+	}
+	metrics.cache.WithLabelValues("miss").Inc()
+
+	ch := s.group.DoChan(k.name+"/"+strconv.Itoa(int(qtype)), func() (any, error) {
+		done := make(chan answer, 1)
+		select {
+		case s.jobs <- func() { done <- s.resolve(k) }:
+		default:
+			metrics.queueFull.Inc()
+			return answer{}, ErrQueueFull
+		}
+
+		select {
+		case a := <-done:
+			return a, nil
+		case <-s.quitChan:
+			return answer{}, net.ErrClosed
+		}
+	})
+
+	select {
+	case result := <-ch:
+		if result.Err != nil {
+			return answer{}, result.Err
+		}
+		return result.Val.(answer), nil
+	case <-ctx.Done():
+		metrics.deadlineExceeded.Inc()
+		return answer{}, ctx.Err()
+	}
+}
+
+// resolve queries the servers for k and caches the answer, for at most maxTTL
+func (s *Service) resolve(k key) answer {
+	a := s.query(context.Background(), k.name, k.qtype)
+
+	if ttl := min(a.ttl, s.maxTTL); ttl > 0 {
+		s.cache.Set(k, a, ttl)
+	}
+
+	return a
+}
+
+// reverseName returns the in-addr.arpa or ip6.arpa name of addr, RFC 1035 3.5 and RFC 3596 2.5
+func reverseName(addr netip.Addr) string {
+	b := &strings.Builder{}
+	raw := addr.AsSlice()
+	if addr.Is4() {
+		for i := len(raw) - 1; i >= 0; i-- {
+			b.WriteString(strconv.Itoa(int(raw[i])))
+			b.WriteByte('.')
+		}
+		b.WriteString("in-addr.arpa.")
+		return b.String()
+	}
+
+	const hex = "0123456789abcdef"
+	for i := len(raw) - 1; i >= 0; i-- {
+		b.WriteByte(hex[raw[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(hex[raw[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String()
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// Close stops the workers and the cache, lookups in flight fail
+func (s *Service) Close(ctx context.Context) error {
+	close(s.quitChan)
+	s.wg.Wait()
+	s.cache.Stop()
+	s.log.Info("Quit")
+	return nil
+}
@@ -0,0 +1,332 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SUNET/vc/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeServer answers queries over UDP from zone after delay, keyed by name and type, names outside it are NXDOMAIN.
+// With spoof each answer is preceded by one with the same id for the question spoof.
+type fakeServer struct {
+	conn    net.PacketConn
+	zone    map[key][]dnsmessage.Resource
+	delay   time.Duration
+	spoof   atomic.Pointer[dnsmessage.Question]
+	queries atomic.Int32
+}
+
+func newFakeServer(t *testing.T, zone map[key][]dnsmessage.Resource, delay time.Duration) *fakeServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	f := &fakeServer{conn: conn, zone: zone, delay: delay}
+	go f.serve()
+	return f
+}
+
+func (f *fakeServer) serve() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := f.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		query := dnsmessage.Message{}
+		if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+			continue
+		}
+		f.queries.Add(1)
+
+		go func() {
+			time.Sleep(f.delay)
+			q := query.Questions[0]
+			if spoof := f.spoof.Load(); spoof != nil {
+				spoofed := dnsmessage.Message{
+					Header:    dnsmessage.Header{ID: query.ID, Response: true},
+					Questions: []dnsmessage.Question{*spoof},
+					Answers:   []dnsmessage.Resource{rr(q.Name.String(), dnsmessage.TypeA, 3600, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 66}})},
+				}
+				if packed, err := spoofed.Pack(); err == nil {
+					_, _ = f.conn.WriteTo(packed, addr)
+				}
+			}
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionAvailable: true},
+				Questions: query.Questions,
+			}
+			answers, ok := f.zone[key{name: q.Name.String(), qtype: q.Type}]
+			if !ok {
+				resp.RCode = dnsmessage.RCodeNameError
+				resp.Authorities = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("example.org."), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 3600},
+					Body: &dnsmessage.SOAResource{
+						NS:     dnsmessage.MustNewName("ns.example.org."),
+						MBox:   dnsmessage.MustNewName("hostmaster.example.org."),
+						MinTTL: 120,
+					},
+				}}
+			}
+			resp.Answers = answers
+			packed, err := resp.Pack()
+			if err != nil {
+				return
+			}
+			_, _ = f.conn.WriteTo(packed, addr)
+		}()
+	}
+}
+
+func rr(name string, qtype dnsmessage.Type, ttl uint32, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   body,
+	}
+}
+
+func newTestService(t *testing.T, f *fakeServer, dns model.DNS) *Service {
+	dns.Resolver = f.conn.LocalAddr().String()
+	if dns.QueryTimeout == 0 {
+		dns.QueryTimeout = time.Second
+	}
+	cfg := &model.Cfg{IPService: &model.IPService{DNS: dns}}
+	s, err := New(context.TODO(), cfg, logger.NewSimple("test"))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close(context.TODO()) })
+	return s
+}
+
+// testZone holds a host with a PTR and an A record, and a host whose answer must not be cached
+func testZone() map[key][]dnsmessage.Resource {
+	return map[key][]dnsmessage.Resource{
+		{name: "1.1.242.130.in-addr.arpa.", qtype: dnsmessage.TypePTR}: {
+			rr("1.1.242.130.in-addr.arpa.", dnsmessage.TypePTR, 300, &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("host.example.org.")}),
+		},
+		{name: "host.example.org.", qtype: dnsmessage.TypeA}: {
+			rr("host.example.org.", dnsmessage.TypeA, 300, &dnsmessage.AResource{A: [4]byte{130, 242, 1, 1}}),
+		},
+		{name: "2.1.242.130.in-addr.arpa.", qtype: dnsmessage.TypePTR}: {
+			rr("2.1.242.130.in-addr.arpa.", dnsmessage.TypePTR, 0, &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("volatile.example.org.")}),
+		},
+	}
+}
+
+func TestLookup(t *testing.T) {
+	s := newTestService(t, newFakeServer(t, testZone(), 0), model.DNS{})
+
+	names, err := s.LookupAddr(context.TODO(), "130.242.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"host.example.org."}, names)
+
+	// AAAA is missing, the A record is enough
+	addrs, err := s.LookupIPAddr(context.TODO(), "HOST.example.org")
+	require.NoError(t, err)
+	assert.Equal(t, []net.IPAddr{{IP: net.IP{130, 242, 1, 1}}}, addrs)
+
+	_, err = s.LookupAddr(context.TODO(), "130.242.1.3")
+	var dnsErr *net.DNSError
+	require.True(t, errors.As(err, &dnsErr), "got %v", err)
+	assert.True(t, dnsErr.IsNotFound)
+
+	_, err = s.LookupIPAddr(context.TODO(), "missing.example.org.")
+	require.True(t, errors.As(err, &dnsErr), "got %v", err)
+	assert.True(t, dnsErr.IsNotFound)
+}
+
+func TestCache(t *testing.T) {
+	f := newFakeServer(t, testZone(), 0)
+	s := newTestService(t, f, model.DNS{})
+
+	for range 3 {
+		_, err := s.LookupAddr(context.TODO(), "130.242.1.1")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), f.queries.Load())
+
+	// a missing name is cached for the negative TTL of the SOA, min(3600, 120)
+	for range 3 {
+		_, err := s.LookupAddr(context.TODO(), "130.242.1.3")
+		require.Error(t, err)
+	}
+	assert.Equal(t, int32(2), f.queries.Load())
+	item := s.cache.Get(key{name: "3.1.242.130.in-addr.arpa.", qtype: dnsmessage.TypePTR})
+	require.NotNil(t, item)
+	assert.Equal(t, 120*time.Second, item.TTL())
+
+	// a TTL of 0 is not cached
+	for range 2 {
+		_, err := s.LookupAddr(context.TODO(), "130.242.1.2")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(4), f.queries.Load())
+}
+
+func TestSpoofedAnswer(t *testing.T) {
+	tts := []struct {
+		name  string
+		spoof dnsmessage.Question
+	}{
+		{name: "other name", spoof: dnsmessage.Question{Name: dnsmessage.MustNewName("attacker.example.net."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+		{name: "other type", spoof: dnsmessage.Question{Name: dnsmessage.MustNewName("host.example.org."), Type: dnsmessage.TypeAAAA, Class: dnsmessage.ClassINET}},
+		{name: "other class", spoof: dnsmessage.Question{Name: dnsmessage.MustNewName("host.example.org."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassCHAOS}},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeServer(t, testZone(), 0)
+			f.spoof.Store(&tt.spoof)
+			s := newTestService(t, f, model.DNS{})
+
+			// the spoofed answer arrives first and is ignored
+			addrs, err := s.LookupIPAddr(context.TODO(), "host.example.org.")
+			require.NoError(t, err)
+			assert.Equal(t, []net.IPAddr{{IP: net.IP{130, 242, 1, 1}}}, addrs)
+		})
+	}
+}
+
+func TestAnswers(t *testing.T) {
+	question := dnsmessage.Question{Name: dnsmessage.MustNewName("host.example.org."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}
+
+	tts := []struct {
+		name string
+		resp dnsmessage.Message
+		want bool
+	}{
+		{
+			name: "same question",
+			resp: dnsmessage.Message{Header: dnsmessage.Header{ID: 1, Response: true}, Questions: []dnsmessage.Question{question}},
+			want: true,
+		},
+		{
+			name: "name in another case",
+			resp: dnsmessage.Message{Header: dnsmessage.Header{ID: 1, Response: true}, Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName("HOST.Example.org."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}}},
+			want: true,
+		},
+		{
+			name: "other id",
+			resp: dnsmessage.Message{Header: dnsmessage.Header{ID: 2, Response: true}, Questions: []dnsmessage.Question{question}},
+		},
+		{
+			name: "query",
+			resp: dnsmessage.Message{Header: dnsmessage.Header{ID: 1}, Questions: []dnsmessage.Question{question}},
+		},
+		{
+			name: "no question",
+			resp: dnsmessage.Message{Header: dnsmessage.Header{ID: 1, Response: true}},
+		},
+		{
+			name: "other name",
+			resp: dnsmessage.Message{Header: dnsmessage.Header{ID: 1, Response: true}, Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName("other.example.org."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}}},
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, answers(&tt.resp, 1, question))
+		})
+	}
+}
+
+func TestMaxTTL(t *testing.T) {
+	s := newTestService(t, newFakeServer(t, testZone(), 0), model.DNS{MaxTTL: time.Minute})
+
+	_, err := s.LookupAddr(context.TODO(), "130.242.1.1")
+	require.NoError(t, err)
+
+	item := s.cache.Get(key{name: "1.1.242.130.in-addr.arpa.", qtype: dnsmessage.TypePTR})
+	require.NotNil(t, item)
+	assert.Equal(t, time.Minute, item.TTL())
+}
+
+func TestConcurrentLookupsShareAQuery(t *testing.T) {
+	f := newFakeServer(t, testZone(), 100*time.Millisecond)
+	s := newTestService(t, f, model.DNS{})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names, err := s.LookupAddr(context.TODO(), "130.242.1.1")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"host.example.org."}, names)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), f.queries.Load())
+}
+
+func TestDeadline(t *testing.T) {
+	f := newFakeServer(t, testZone(), 200*time.Millisecond)
+	s := newTestService(t, f, model.DNS{})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := s.LookupAddr(ctx, "130.242.1.1")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+
+	// the query finishes in the background, for the next lookup
+	require.Eventually(t, func() bool {
+		return s.cache.Get(key{name: "1.1.242.130.in-addr.arpa.", qtype: dnsmessage.TypePTR}) != nil
+	}, time.Second, 10*time.Millisecond)
+
+	names, err := s.LookupAddr(context.TODO(), "130.242.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"host.example.org."}, names)
+	assert.Equal(t, int32(1), f.queries.Load())
+}
+
+func TestQueueFull(t *testing.T) {
+	f := newFakeServer(t, testZone(), 200*time.Millisecond)
+	s := newTestService(t, f, model.DNS{Workers: 1, QueueSize: 1})
+
+	var (
+		wg   sync.WaitGroup
+		full atomic.Int32
+	)
+	for _, addr := range []string{"130.242.1.1", "130.242.1.2", "130.242.1.3"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.LookupAddr(context.TODO(), addr); errors.Is(err, ErrQueueFull) {
+				full.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.GreaterOrEqual(t, full.Load(), int32(1))
+}
+
+func TestReverseName(t *testing.T) {
+	tts := []struct {
+		addr string
+		want string
+	}{
+		{addr: "130.242.1.1", want: "1.1.242.130.in-addr.arpa."},
+		{addr: "2001:db8::567:89ab", want: "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, reverseName(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
@@ -90,6 +90,13 @@ func (s *Service) downloadArchive(ctx context.Context, remoteFile RemoteFile) er
 		return fmt.Errorf("rate limit exceeded")
 	}
 
+	s.downloading.Store(true)
+	start := time.Now()
+	defer func() {
+		metrics.downloadDuration.WithLabelValues(s.sourceCfg.Name).Observe(time.Since(start).Seconds())
+		s.downloading.Store(false)
+	}()
+
 	switch s.sourceCfg.Transport {
 	case TransportFTP:
 		return s.downloadArchiveFTP(ctx, remoteFile)
@@ -145,6 +152,7 @@ func (s *Service) downloadArchiveFTP(ctx context.Context, remoteFile RemoteFile)
 		return err
 	}
 	s.log.Debug("Downloaded", "file", remoteFile.Name, "size", stat.Size())
+	metrics.downloadSize.WithLabelValues(s.sourceCfg.Name).Observe(float64(stat.Size()))
 
 	return nil
 }
@@ -184,6 +192,7 @@ func (s *Service) downloadArchiveHTTP(ctx context.Context, remoteFile RemoteFile
 		return err
 	}
 	s.log.Info("Downloaded", "file", remoteFile.Name, "size", stat.Size())
+	metrics.downloadSize.WithLabelValues(s.sourceCfg.Name).Observe(float64(stat.Size()))
 
 	return nil
 }
@@ -264,7 +273,7 @@ func (s *Service) loadFromLocal(ctx context.Context) bool {
 
 	for _, archive := range s.sourceCfg.RemoteFiles {
 		localPath := s.localFilePath(archive.Name)
-		if err := rpslClient.Parse(ctx, localPath); err != nil {
+		if err := s.parse(ctx, rpslClient, localPath); err != nil {
 			s.log.Error(err, "failed to parse local cache", "source", s.sourceCfg.Name, "path", localPath)
 			return false
 		}
@@ -272,3 +281,15 @@ func (s *Service) loadFromLocal(ctx context.Context) bool {
 	s.RPSLRouterClass = rpslClient.RouterClass
 	return true
 }
+
+// parse parses a local RPSL file into the client and records the time spent
+func (s *Service) parse(ctx context.Context, rpslClient *rpsl.Client, localPath string) error {
+	s.parsing.Store(true)
+	start := time.Now()
+	defer func() {
+		metrics.parseDuration.WithLabelValues(s.sourceCfg.Name).Observe(time.Since(start).Seconds())
+		s.parsing.Store(false)
+	}()
+
+	return rpslClient.Parse(ctx, localPath)
+}
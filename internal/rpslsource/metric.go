@@ -0,0 +1,49 @@
+package rpslsource
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the prometheus metrics for the RPSL sources, labeled by source name
+var metrics = struct {
+	serial           *prometheus.GaugeVec
+	routeObjects     *prometheus.GaugeVec
+	lastSuccess      *prometheus.GaugeVec
+	lastFailure      *prometheus.GaugeVec
+	downloadSize     *prometheus.HistogramVec
+	downloadDuration *prometheus.HistogramVec
+	parseDuration    *prometheus.HistogramVec
+}{
+	serial: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_rpsl_serial",
+		Help: "Current serial of the RPSL source",
+	}, []string{"source"}),
+	routeObjects: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_rpsl_route_objects",
+		Help: "Number of route and route6 objects parsed from the RPSL source",
+	}, []string{"source"}),
+	lastSuccess: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_rpsl_last_success_timestamp_seconds",
+		Help: "Time of the last successful update of the RPSL source",
+	}, []string{"source"}),
+	lastFailure: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_rpsl_last_failure_timestamp_seconds",
+		Help: "Time of the last failed update of the RPSL source",
+	}, []string{"source"}),
+	downloadSize: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ip_service_rpsl_download_size_bytes",
+		Help:    "Size of downloaded RPSL archives",
+		Buckets: prometheus.ExponentialBuckets(1024*1024, 2, 12),
+	}, []string{"source"}),
+	downloadDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ip_service_rpsl_download_duration_seconds",
+		Help:    "Time spent downloading RPSL archives",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+	}, []string{"source"}),
+	parseDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ip_service_rpsl_parse_duration_seconds",
+		Help:    "Time spent parsing RPSL files",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+	}, []string{"source"}),
+}
@@ -9,6 +9,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
@@ -58,6 +61,10 @@ type Service struct {
 	store           kvStore
 	rateLimit       rate.Limiter
 	httpClient      *http.Client
+	downloading     atomic.Bool
+	parsing         atomic.Bool
+	// lastUpdate is the unix time of the last successful update
+	lastUpdate atomic.Int64
 }
 
 type kvStore interface {
@@ -94,6 +101,23 @@ func New(ctx context.Context, store *store.Service, cfg *model.Cfg, log *logger.
 
 // Update checks for updates and downloads/parses RPSL data. Uses local cache when possible.
 func (s *Service) Update(ctx context.Context) (bool, error) {
+	updated, err := s.update(ctx)
+	if err != nil {
+		metrics.lastFailure.WithLabelValues(s.sourceCfg.Name).SetToCurrentTime()
+		return updated, err
+	}
+
+	s.lastUpdate.Store(time.Now().Unix())
+	metrics.lastSuccess.WithLabelValues(s.sourceCfg.Name).SetToCurrentTime()
+	metrics.routeObjects.WithLabelValues(s.sourceCfg.Name).Set(float64(s.RPSLRouterClass.CountObjects()))
+	if serial, err := strconv.ParseFloat(strings.TrimSpace(s.Serial(ctx)), 64); err == nil {
+		metrics.serial.WithLabelValues(s.sourceCfg.Name).Set(serial)
+	}
+
+	return updated, nil
+}
+
+func (s *Service) update(ctx context.Context) (bool, error) {
 	storeKey := s.sourceCfg.Name + "_serial"
 	currentSerial := s.store.GetRemoteVersion(ctx, storeKey)
 	s.log.Info("Current serial", "source", s.sourceCfg.Name, "serial", currentSerial)
@@ -142,7 +166,7 @@ func (s *Service) Update(ctx context.Context) (bool, error) {
 		}
 
 		localPath := s.localFilePath(remoteFile.Name)
-		if err := rpslClient.Parse(ctx, localPath); err != nil {
+		if err := s.parse(ctx, rpslClient, localPath); err != nil {
 			return false, err
 		}
 	}
@@ -151,6 +175,36 @@ func (s *Service) Update(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
+// Name returns the name of the source, e.g. "ripe"
+func (s *Service) Name() string {
+	return s.sourceCfg.Name
+}
+
+// Serial returns the last known remote serial of the source
+func (s *Service) Serial(ctx context.Context) string {
+	return s.store.GetRemoteVersion(ctx, s.sourceCfg.Name+"_serial")
+}
+
+// LastUpdate returns the time of the last successful update, zero before the first one
+func (s *Service) LastUpdate() time.Time {
+	lastUpdate := s.lastUpdate.Load()
+	if lastUpdate == 0 {
+		return time.Time{}
+	}
+	return time.Unix(lastUpdate, 0)
+}
+
+// State returns the download and parse state of the source
+func (s *Service) State(ctx context.Context) *model.DatasetState {
+	return &model.DatasetState{
+		Name:        s.sourceCfg.Name,
+		Kind:        model.DatasetKindIRR,
+		Downloading: s.downloading.Load(),
+		Parsing:     s.parsing.Load(),
+		Version:     s.Serial(ctx),
+	}
+}
+
 // Close shuts down the service
 func (s *Service) Close(ctx context.Context) error {
 	s.log.Info("Quit", "source", s.sourceCfg.Name)
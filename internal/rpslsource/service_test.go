@@ -1,6 +1,8 @@
 package rpslsource
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"ip_service/internal/store"
 	"github.com/SUNET/vc/pkg/logger"
@@ -11,6 +13,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/time/rate"
 )
@@ -129,3 +133,49 @@ func TestGetRemoteSerialHTTP_ContextCancelled(t *testing.T) {
 	_, err := service.getRemoteSerial(ctx)
 	assert.Error(t, err)
 }
+
+func TestUpdateMetrics(t *testing.T) {
+	var routes bytes.Buffer
+	gz := gzip.NewWriter(&routes)
+	_, err := gz.Write([]byte("route:          192.0.2.0/24\norigin:         AS64500\nsource:         RIPE\n\nroute:          198.51.100.0/24\norigin:         AS64501\nsource:         RIPE\n\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/CURRENTSERIAL":
+			w.Write([]byte("4242\n"))
+		case "/route.gz":
+			w.Write(routes.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	service := mockService(t, Config{
+		Name:      "metrics_test",
+		Transport: TransportHTTP,
+		RemoteFiles: []RemoteFile{
+			{Name: "route", Path: "/route.gz"},
+		},
+		SerialPath:   "/CURRENTSERIAL",
+		Host:         ts.URL,
+		AddEOFMarker: true,
+	})
+
+	updated, err := service.Update(context.TODO())
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	gaugeValue := func(g prometheus.Gauge) float64 {
+		m := &dto.Metric{}
+		assert.NoError(t, g.Write(m))
+		return m.GetGauge().GetValue()
+	}
+
+	assert.Equal(t, float64(4242), gaugeValue(metrics.serial.WithLabelValues("metrics_test")))
+	assert.Equal(t, float64(2), gaugeValue(metrics.routeObjects.WithLabelValues("metrics_test")))
+	assert.NotZero(t, gaugeValue(metrics.lastSuccess.WithLabelValues("metrics_test")))
+	assert.Zero(t, gaugeValue(metrics.lastFailure.WithLabelValues("metrics_test")))
+}
@@ -2,11 +2,21 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"ip_service/pkg/helpers"
+	"ip_service/pkg/model"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	irrChangesPrefix = "irr_changes"
+	apiKeysPrefix    = "api_keys"
+)
+
 // Set sets key value in store
 func (s *KV) Set(ctx context.Context, k, v string) error {
 	return s.File.WriteString(k, v)
@@ -39,6 +49,140 @@ func (s *KV) Del(ctx context.Context, k string) error {
 	return s.File.Erase(k)
 }
 
+// AddIRRChangeSet stores the change set under its timestamp
+func (s *KV) AddIRRChangeSet(ctx context.Context, changeSet *model.IRRChangeSet) error {
+	b, err := json.Marshal(changeSet)
+	if err != nil {
+		return err
+	}
+
+	k := strings.Join([]string{irrChangesPrefix, strconv.FormatInt(changeSet.Timestamp.UnixNano(), 10)}, "/")
+	return s.File.Write(k, b)
+}
+
+// GetIRRChangeSets returns all stored change sets newer than since, oldest first
+func (s *KV) GetIRRChangeSets(ctx context.Context, since time.Time) ([]*model.IRRChangeSet, error) {
+	reply := []*model.IRRChangeSet{}
+
+	for _, k := range s.irrChangeSetKeys(ctx) {
+		ts, err := irrChangeSetTimestamp(k)
+		if err != nil || !ts.After(since) {
+			continue
+		}
+
+		b, err := s.File.Read(k)
+		if err != nil {
+			return nil, err
+		}
+
+		changeSet := &model.IRRChangeSet{}
+		if err := json.Unmarshal(b, changeSet); err != nil {
+			return nil, err
+		}
+		reply = append(reply, changeSet)
+	}
+
+	slices.SortFunc(reply, func(a, b *model.IRRChangeSet) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+
+	return reply, nil
+}
+
+// DelIRRChangeSetsBefore removes all change sets older than before
+func (s *KV) DelIRRChangeSetsBefore(ctx context.Context, before time.Time) error {
+	for _, k := range s.irrChangeSetKeys(ctx) {
+		ts, err := irrChangeSetTimestamp(k)
+		if err != nil || !ts.Before(before) {
+			continue
+		}
+		if err := s.Del(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *KV) irrChangeSetKeys(ctx context.Context) []string {
+	keys := []string{}
+	for k := range s.File.KeysPrefix(irrChangesPrefix+"/", ctx.Done()) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func irrChangeSetTimestamp(k string) (time.Time, error) {
+	_, tsStr, _ := strings.Cut(k, "/")
+	ts, err := strconv.ParseInt(tsStr, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, ts), nil
+}
+
+// AddAPIKey stores the api key under the hash of the key, the key itself is not stored
+func (s *KV) AddAPIKey(ctx context.Context, apiKey *model.APIKey) error {
+	if apiKey.Hash == "" {
+		apiKey.Hash = model.HashAPIKey(apiKey.Key)
+	}
+
+	b, err := json.Marshal(apiKey)
+	if err != nil {
+		return err
+	}
+
+	return s.File.Write(strings.Join([]string{apiKeysPrefix, apiKey.Hash}, "/"), b)
+}
+
+// GetAPIKey returns the stored api key, or helpers.ErrAPIKeyNotFound
+func (s *KV) GetAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+	return s.getAPIKey(strings.Join([]string{apiKeysPrefix, model.HashAPIKey(key)}, "/"))
+}
+
+// ListAPIKeys returns all stored api keys, sorted by name
+func (s *KV) ListAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	reply := []*model.APIKey{}
+	for k := range s.File.KeysPrefix(apiKeysPrefix+"/", ctx.Done()) {
+		apiKey, err := s.getAPIKey(k)
+		if err != nil {
+			return nil, err
+		}
+		reply = append(reply, apiKey)
+	}
+
+	slices.SortFunc(reply, func(a, b *model.APIKey) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return reply, nil
+}
+
+// DelAPIKey removes the api key with the given hash
+func (s *KV) DelAPIKey(ctx context.Context, hash string) error {
+	k := strings.Join([]string{apiKeysPrefix, hash}, "/")
+	if !s.File.Has(k) {
+		return helpers.ErrAPIKeyNotFound
+	}
+	return s.Del(ctx, k)
+}
+
+func (s *KV) getAPIKey(k string) (*model.APIKey, error) {
+	if !s.File.Has(k) {
+		return nil, helpers.ErrAPIKeyNotFound
+	}
+
+	b, err := s.File.Read(k)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := &model.APIKey{}
+	if err := json.Unmarshal(b, apiKey); err != nil {
+		return nil, err
+	}
+	return apiKey, nil
+}
+
 func (s *KV) statusTest(ctx context.Context) error {
 	if err := s.Set(ctx, "testK", "testV"); err != nil {
 		return err
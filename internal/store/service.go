@@ -53,11 +53,9 @@ func advancedTransform(key string) *diskv.PathKey {
 }
 
 func inverseTransform(pathKey *diskv.PathKey) (key string) {
-	txt := pathKey.FileName[len(pathKey.FileName)-4:]
-	if txt != ".txt" {
-		panic("Invalid file found in storage folder!")
-	}
-	return strings.Join(pathKey.Path, "/") + pathKey.FileName[:len(pathKey.FileName)-4]
+	// Directories are passed here as well when walking keys, they are filtered out by diskv
+	fileName, _ := strings.CutSuffix(pathKey.FileName, ".txt")
+	return strings.Join(append(pathKey.Path, fileName), "/")
 }
 
 func (s *Service) newKV(ctx context.Context) error {
@@ -4,9 +4,12 @@ import (
 	"context"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/SUNET/vc/pkg/logger"
+	"ip_service/pkg/helpers"
 	"ip_service/pkg/model"
+	"ip_service/pkg/rpsl"
 	"github.com/SUNET/vc/pkg/trace"
 
 	"github.com/stretchr/testify/assert"
@@ -134,3 +137,61 @@ func TestRemoteVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestIRRChangeSets(t *testing.T) {
+	s := mockNew(t, t.TempDir())
+	ctx := context.TODO()
+
+	now := time.Now()
+	for _, ts := range []time.Time{now.Add(-48 * time.Hour), now.Add(-2 * time.Hour), now} {
+		err := s.KV.AddIRRChangeSet(ctx, &model.IRRChangeSet{
+			Timestamp: ts,
+			Serials:   map[string]string{"ripe": "1"},
+			Sources: map[string]*rpsl.Changes{
+				"ripe": {Added: []*rpsl.Object{{Network: "192.0.2.0/24", Origin: "AS64500"}}},
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	got, err := s.KV.GetIRRChangeSets(ctx, now.Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.True(t, got[0].Timestamp.Before(got[1].Timestamp))
+	assert.Equal(t, "192.0.2.0/24", got[0].Sources["ripe"].Added[0].Network)
+
+	assert.NoError(t, s.KV.DelIRRChangeSetsBefore(ctx, now.Add(-time.Hour)))
+
+	got, err = s.KV.GetIRRChangeSets(ctx, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+}
+
+func TestAPIKeys(t *testing.T) {
+	s := mockNew(t, t.TempDir())
+	ctx := context.TODO()
+
+	assert.NoError(t, s.KV.AddAPIKey(ctx, &model.APIKey{Name: "b-customer", Key: "secret-b", Tier: "basic"}))
+	assert.NoError(t, s.KV.AddAPIKey(ctx, &model.APIKey{Name: "a-customer", Key: "secret-a", Tier: "bulk"}))
+
+	got, err := s.KV.GetAPIKey(ctx, "secret-a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a-customer", got.Name)
+	assert.Equal(t, "bulk", got.Tier)
+	assert.Empty(t, got.Key)
+
+	_, err = s.KV.GetAPIKey(ctx, "unknown")
+	assert.ErrorIs(t, err, helpers.ErrAPIKeyNotFound)
+
+	list, err := s.KV.ListAPIKeys(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "a-customer", list[0].Name)
+
+	assert.NoError(t, s.KV.DelAPIKey(ctx, model.HashAPIKey("secret-a")))
+	assert.ErrorIs(t, s.KV.DelAPIKey(ctx, model.HashAPIKey("secret-a")), helpers.ErrAPIKeyNotFound)
+
+	list, err = s.KV.ListAPIKeys(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
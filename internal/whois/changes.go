@@ -0,0 +1,55 @@
+package whois
+
+import (
+	"context"
+	"ip_service/internal/rpslsource"
+	"ip_service/pkg/model"
+	"ip_service/pkg/rpsl"
+	"time"
+)
+
+// irrChangesRetention is how long change sets are kept in store
+const irrChangesRetention = 30 * 24 * time.Hour
+
+// recordChanges stores the difference between the previous and the current router class, together with the serial of each source.
+// An update without added, removed or modified objects, e.g. a reload of the local cache, is not recorded.
+func (s *Service) recordChanges(ctx context.Context, previous, current rpsl.RouterClass) error {
+	// Nothing to compare against on initial load
+	if len(previous) == 0 {
+		return nil
+	}
+
+	sources := rpsl.Diff(ctx, previous, current)
+	if noChanges(sources) {
+		return nil
+	}
+
+	changeSet := &model.IRRChangeSet{
+		Timestamp: time.Now(),
+		Serials:   map[string]string{},
+		Sources:   sources,
+	}
+
+	for _, source := range []*rpslsource.Service{s.radb, s.ripe} {
+		changeSet.Serials[source.Name()] = source.Serial(ctx)
+	}
+
+	for source, changes := range changeSet.Sources {
+		s.log.Info("IRR changes", "source", source, "added", len(changes.Added), "removed", len(changes.Removed), "modified", len(changes.Modified))
+	}
+
+	if err := s.store.KV.AddIRRChangeSet(ctx, changeSet); err != nil {
+		return err
+	}
+
+	return s.store.KV.DelIRRChangeSetsBefore(ctx, time.Now().Add(-irrChangesRetention))
+}
+
+func noChanges(sources map[string]*rpsl.Changes) bool {
+	for _, changes := range sources {
+		if !changes.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
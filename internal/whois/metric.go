@@ -0,0 +1,37 @@
+package whois
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the prometheus metrics for the merged whois dataset
+var metrics = struct {
+	routeObjects      prometheus.Gauge
+	prefixes          *prometheus.GaugeVec
+	treeBuildDuration prometheus.Histogram
+	lastSuccess       prometheus.Gauge
+	lastFailure       prometheus.Gauge
+}{
+	routeObjects: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_whois_route_objects",
+		Help: "Number of route objects in the merged whois dataset",
+	}),
+	prefixes: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ip_service_whois_prefixes",
+		Help: "Number of prefixes in the whois lookup tree",
+	}, []string{"family"}),
+	treeBuildDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ip_service_whois_tree_build_duration_seconds",
+		Help:    "Time spent building the whois lookup tree",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+	}),
+	lastSuccess: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_whois_last_success_timestamp_seconds",
+		Help: "Time of the last successful whois dataset update",
+	}),
+	lastFailure: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ip_service_whois_last_failure_timestamp_seconds",
+		Help: "Time of the last failed whois dataset update",
+	}),
+}
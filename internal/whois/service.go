@@ -6,9 +6,11 @@ import (
 	"ip_service/internal/rpslsource"
 	"ip_service/internal/store"
 	"github.com/SUNET/vc/pkg/logger"
+	"ip_service/pkg/helpers"
 	"ip_service/pkg/model"
 	"ip_service/pkg/rpsl"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,13 +19,15 @@ type Service struct {
 	cfg             *model.Cfg
 	log             *logger.Log
 	wg              sync.WaitGroup
-	updateTicker    time.Ticker
+	updateTicker    *time.Ticker
 	ripe            *rpslsource.Service
 	radb            *rpslsource.Service
 	store           *store.Service
 	RPSLRouterClass rpsl.RouterClass
 	mu              sync.RWMutex
 	tree            *lctree.Service
+	updateChan      chan struct{}
+	updating        atomic.Bool
 }
 
 // New creates a new whois service
@@ -33,9 +37,10 @@ func New(ctx context.Context, cfg *model.Cfg, tree *lctree.Service, store *store
 		log:             log,
 		store:           store,
 		wg:              sync.WaitGroup{},
-		updateTicker:    *time.NewTicker(24 * time.Hour),
+		updateTicker:    time.NewTicker(updatePeriodicity(cfg)),
 		RPSLRouterClass: make(rpsl.RouterClass),
 		tree:            tree,
+		updateChan:      make(chan struct{}, 1),
 	}
 
 	var err error
@@ -89,7 +94,7 @@ func New(ctx context.Context, cfg *model.Cfg, tree *lctree.Service, store *store
 	service.radb.RPSLRouterClass = nil
 	service.ripe.RPSLRouterClass = nil
 
-	if err := service.tree.Build(ctx, service.RPSLRouterClass); err != nil {
+	if err := service.buildTree(ctx, service.RPSLRouterClass); err != nil {
 		return nil, err
 	}
 
@@ -99,37 +104,9 @@ func New(ctx context.Context, cfg *model.Cfg, tree *lctree.Service, store *store
 		for {
 			select {
 			case <-service.updateTicker.C:
-				log.Info("Starting whois update")
-				radbUpdated, err := service.radb.Update(ctx)
-				if err != nil {
-					service.log.Error(err, "Error updating radb")
-				}
-
-				ripeUpdated, err := service.ripe.Update(ctx)
-				if err != nil {
-					service.log.Error(err, "Error updating ripe")
-				}
-
-				if radbUpdated && ripeUpdated {
-					service.mu.Lock()
-					service.RPSLRouterClass, err = rpsl.RouterClassOpinionatedMerge(ctx, service.radb.RPSLRouterClass, service.ripe.RPSLRouterClass)
-					if err != nil {
-						service.log.Error(err, "Error merging RPSL router classes")
-					}
-					service.radb.RPSLRouterClass = nil
-					service.ripe.RPSLRouterClass = nil
-					service.mu.Unlock()
-
-					if err := service.tree.Build(ctx, service.RPSLRouterClass); err != nil {
-						service.log.Error(err, "Error rebuilding patricia tree")
-					}
-
-					q, err := service.QueryIP(ctx, "2001:67c:2564::1")
-					if err != nil {
-						service.log.Error(err, "Error querying IP after update")
-					}
-					service.log.Info("Example query after update", "result", q)
-				}
+				service.update(ctx)
+			case <-service.updateChan:
+				service.update(ctx)
 			case <-ctx.Done():
 				service.log.Info("Stopping whois update")
 				return
@@ -140,6 +117,110 @@ func New(ctx context.Context, cfg *model.Cfg, tree *lctree.Service, store *store
 	return service, nil
 }
 
+// update updates the RPSL sources and, when both are updated, merges them and rebuilds the lookup tree
+func (s *Service) update(ctx context.Context) {
+	s.updating.Store(true)
+	defer s.updating.Store(false)
+
+	s.log.Info("Starting whois update")
+	radbUpdated, err := s.radb.Update(ctx)
+	if err != nil {
+		s.log.Error(err, "Error updating radb")
+		metrics.lastFailure.SetToCurrentTime()
+	}
+
+	ripeUpdated, err := s.ripe.Update(ctx)
+	if err != nil {
+		s.log.Error(err, "Error updating ripe")
+		metrics.lastFailure.SetToCurrentTime()
+	}
+
+	if radbUpdated && ripeUpdated {
+		s.mu.Lock()
+		previous := s.RPSLRouterClass
+		current, err := rpsl.RouterClassOpinionatedMerge(ctx, s.radb.RPSLRouterClass, s.ripe.RPSLRouterClass)
+		s.radb.RPSLRouterClass = nil
+		s.ripe.RPSLRouterClass = nil
+		if err != nil {
+			// the previous router class and tree keep serving until the next update
+			s.mu.Unlock()
+			s.log.Error(err, "Error merging RPSL router classes, keeping the previous tree")
+			metrics.lastFailure.SetToCurrentTime()
+			return
+		}
+		s.RPSLRouterClass = current
+		s.mu.Unlock()
+
+		if err := s.buildTree(ctx, current); err != nil {
+			s.log.Error(err, "Error rebuilding patricia tree")
+			metrics.lastFailure.SetToCurrentTime()
+		}
+
+		if err := s.recordChanges(ctx, previous, current); err != nil {
+			s.log.Error(err, "Error recording IRR changes")
+		}
+
+		q, err := s.QueryIP(ctx, "2001:67c:2564::1")
+		if err != nil {
+			s.log.Error(err, "Error querying IP after update")
+		}
+		s.log.Info("Example query after update", "result", q)
+	}
+}
+
+// TriggerUpdate queues an update of the RPSL sources
+func (s *Service) TriggerUpdate(ctx context.Context) error {
+	if s.updating.Load() {
+		return helpers.ErrUpdateInProgress
+	}
+
+	select {
+	case s.updateChan <- struct{}{}:
+		s.log.Info("Update triggered")
+		return nil
+	default:
+		return helpers.ErrUpdateInProgress
+	}
+}
+
+// State returns the download and parse state of the RPSL sources
+func (s *Service) State(ctx context.Context) []*model.DatasetState {
+	return []*model.DatasetState{s.radb.State(ctx), s.ripe.State(ctx)}
+}
+
+// buildTree rebuilds the lookup tree from the router class and records dataset metrics
+func (s *Service) buildTree(ctx context.Context, routerClass rpsl.RouterClass) error {
+	start := time.Now()
+	if err := s.tree.Build(ctx, routerClass); err != nil {
+		return err
+	}
+	metrics.treeBuildDuration.Observe(time.Since(start).Seconds())
+
+	v4, v6 := s.tree.CountTags()
+	metrics.prefixes.WithLabelValues("ipv4").Set(float64(v4))
+	metrics.prefixes.WithLabelValues("ipv6").Set(float64(v6))
+	metrics.routeObjects.Set(float64(routerClass.CountObjects()))
+	metrics.lastSuccess.SetToCurrentTime()
+
+	return nil
+}
+
+// defaultUpdatePeriodicity is used when whois.update_periodicity is not set
+const defaultUpdatePeriodicity = 24 * time.Hour
+
+func updatePeriodicity(cfg *model.Cfg) time.Duration {
+	if cfg.IPService.Whois.UpdatePeriodicity > 0 {
+		return cfg.IPService.Whois.UpdatePeriodicity
+	}
+	return defaultUpdatePeriodicity
+}
+
+// Reload applies the hot reloadable settings of cfg, update_periodicity
+func (s *Service) Reload(ctx context.Context, cfg *model.Cfg) {
+	s.updateTicker.Reset(updatePeriodicity(cfg))
+	s.log.Info("Reloaded", "update_periodicity", updatePeriodicity(cfg))
+}
+
 func (s *Service) Close(ctx context.Context) error {
 	s.log.Info("Quit")
 	ctx.Done()
@@ -0,0 +1,62 @@
+package whois
+
+import (
+	"context"
+	"fmt"
+	"ip_service/internal/rpslsource"
+	"ip_service/pkg/model"
+	"time"
+)
+
+const defaultMaxAge = 72 * time.Hour
+
+// Status returns the route object count and the time since each RPSL source was last updated
+func (s *Service) Status(ctx context.Context) *model.StatusProbe {
+	probe := &model.StatusProbe{
+		Name:          "whois",
+		Healthy:       true,
+		Message:       map[string]any{},
+		LastCheckedTS: time.Now(),
+	}
+
+	maxAge := defaultMaxAge
+	if s.cfg.IPService.Health.WhoisMaxAge > 0 {
+		maxAge = s.cfg.IPService.Health.WhoisMaxAge
+	}
+
+	s.mu.RLock()
+	routeObjects := s.RPSLRouterClass.CountObjects()
+	s.mu.RUnlock()
+
+	probe.Message["route_objects"] = routeObjects
+	if routeObjects == 0 {
+		probe.Healthy = false
+		probe.Message["status"] = "no route objects"
+	}
+
+	for _, source := range []*rpslsource.Service{s.radb, s.ripe} {
+		name := source.Name()
+		probe.Message[name+"_serial"] = source.Serial(ctx)
+
+		lastUpdate := source.LastUpdate()
+		if lastUpdate.IsZero() {
+			probe.Healthy = false
+			probe.Message[name+"_status"] = "never updated"
+			continue
+		}
+
+		age := time.Since(lastUpdate)
+		probe.Message[name+"_last_update"] = lastUpdate.UTC().Format(time.RFC3339)
+		if age > maxAge {
+			probe.Healthy = false
+			probe.Message[name+"_status"] = fmt.Sprintf("updated %s ago, max age is %s", age.Truncate(time.Minute), maxAge)
+		}
+	}
+
+	return probe
+}
+
+// TreeStatus returns the status of the lookup tree built from the RPSL sources
+func (s *Service) TreeStatus(ctx context.Context) *model.StatusProbe {
+	return s.tree.Status(ctx)
+}
@@ -0,0 +1,43 @@
+package whois
+
+import (
+	"cmp"
+	"context"
+	"ip_service/pkg/rpsl"
+	"net/netip"
+	"slices"
+)
+
+// WalkRoutes calls fn with each route prefix of the merged RPSL sources and its origins, least specific first
+func (s *Service) WalkRoutes(ctx context.Context, fn func(prefix netip.Prefix, asn rpsl.ASN) error) error {
+	// the router class is replaced on update, never changed, so it is read without holding the lock
+	s.mu.RLock()
+	routerClass := s.RPSLRouterClass
+	s.mu.RUnlock()
+
+	prefixes := make([]netip.Prefix, 0, len(routerClass))
+	networks := make(map[netip.Prefix]string, len(routerClass))
+	for network := range routerClass {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+		networks[prefix.Masked()] = network
+	}
+
+	slices.SortFunc(prefixes, func(a, b netip.Prefix) int {
+		return cmp.Or(cmp.Compare(a.Bits(), b.Bits()), a.Addr().Compare(b.Addr()))
+	})
+
+	for _, prefix := range prefixes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(prefix, routerClass[networks[prefix]]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
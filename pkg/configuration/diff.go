@@ -0,0 +1,103 @@
+package configuration
+
+import (
+	"fmt"
+	"ip_service/pkg/model"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// hotReloadable holds the config paths that are applied without a restart
+var hotReloadable = []string{
+	"ip_service.log.level",
+	"ip_service.api_server.behind_proxy",
+	"ip_service.api_server.trusted_proxy_hops",
+	"ip_service.maxmind.automatic_update",
+	"ip_service.maxmind.update_periodicity",
+	"ip_service.whois.update_periodicity",
+	"ip_service.geo_range.update_periodicity",
+	"ip_service.prefix_labels.file_path",
+	"ip_service.feeds.update_periodicity",
+	"ip_service.blocklists.update_periodicity",
+	"ip_service.cloud.update_periodicity",
+	"ip_service.bgp.update_periodicity",
+}
+
+// Change is a changed config setting, identified by its yaml path
+type Change struct {
+	Path            string
+	RestartRequired bool
+}
+
+// Diff returns the settings that differ between previous and current, sorted by path.
+// Values are not part of the reply since the config holds secrets.
+func Diff(previous, current *model.Cfg) []Change {
+	paths := []string{}
+	diffValue(reflect.ValueOf(previous), reflect.ValueOf(current), "", &paths)
+	slices.Sort(paths)
+
+	changes := make([]Change, 0, len(paths))
+	for _, path := range paths {
+		changes = append(changes, Change{
+			Path:            path,
+			RestartRequired: !slices.Contains(hotReloadable, path),
+		})
+	}
+	return changes
+}
+
+func diffValue(a, b reflect.Value, path string, paths *[]string) {
+	switch a.Kind() {
+	case reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				*paths = append(*paths, path)
+			}
+			return
+		}
+		diffValue(a.Elem(), b.Elem(), path, paths)
+
+	case reflect.Struct:
+		for i := range a.NumField() {
+			field := a.Type().Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			diffValue(a.Field(i), b.Field(i), joinPath(path, name), paths)
+		}
+
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, k := range a.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for _, k := range b.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for name, k := range keys {
+			av, bv := a.MapIndex(k), b.MapIndex(k)
+			if !av.IsValid() || !bv.IsValid() {
+				*paths = append(*paths, joinPath(path, name))
+				continue
+			}
+			diffValue(av, bv, joinPath(path, name), paths)
+		}
+
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*paths = append(*paths, path)
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
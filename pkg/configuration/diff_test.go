@@ -0,0 +1,74 @@
+package configuration
+
+import (
+	"ip_service/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	base := func() *model.Cfg {
+		return &model.Cfg{
+			IPService: &model.IPService{
+				APIServer: model.APIServer{Addr: ":8080"},
+				Log:       model.Log{Level: "info"},
+				MaxMind: model.MaxMind{
+					UpdatePeriodicity: 3600,
+					DB: map[string]model.MaxMindDB{
+						model.MaxmindDBTypeASN: {FilePath: "/asn.mmdb"},
+					},
+				},
+			},
+		}
+	}
+
+	tts := []struct {
+		name   string
+		modify func(cfg *model.Cfg)
+		want   []Change
+	}{
+		{
+			name:   "no changes",
+			modify: func(cfg *model.Cfg) {},
+			want:   []Change{},
+		},
+		{
+			name: "hot reloadable",
+			modify: func(cfg *model.Cfg) {
+				cfg.IPService.Log.Level = "debug"
+				cfg.IPService.APIServer.BehindProxy = true
+				cfg.IPService.Whois.UpdatePeriodicity = time.Hour
+			},
+			want: []Change{
+				{Path: "ip_service.api_server.behind_proxy"},
+				{Path: "ip_service.log.level"},
+				{Path: "ip_service.whois.update_periodicity"},
+			},
+		},
+		{
+			name: "restart required",
+			modify: func(cfg *model.Cfg) {
+				cfg.IPService.APIServer.Addr = ":9090"
+				cfg.IPService.MaxMind.Password = "secret"
+				cfg.IPService.MaxMind.DB[model.MaxmindDBTypeCity] = model.MaxMindDB{FilePath: "/city.mmdb"}
+				cfg.IPService.APIServer.RateLimit.Allowlist = []string{"10.0.0.0/8"}
+			},
+			want: []Change{
+				{Path: "ip_service.api_server.addr", RestartRequired: true},
+				{Path: "ip_service.api_server.rate_limit.allowlist", RestartRequired: true},
+				{Path: "ip_service.maxmind.db.City", RestartRequired: true},
+				{Path: "ip_service.maxmind.password", RestartRequired: true},
+			},
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			current := base()
+			tt.modify(current)
+			assert.Equal(t, tt.want, Diff(base(), current))
+		})
+	}
+}
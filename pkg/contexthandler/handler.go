@@ -11,6 +11,8 @@ type RequestContext struct {
 	LookupIP  string
 	UserAgent string
 	Accept    string
+	// Language is the first language of the Accept-Language header
+	Language string
 }
 
 // Add adds a key value pair to the context
@@ -9,6 +9,36 @@ var (
 	// ErrMissingDBFile is returned when the DB file is missing
 	ErrMissingDBFile = errors.New("missing DB file")
 
+	// ErrDBNotLoaded is returned when a database has not been loaded yet
+	ErrDBNotLoaded = errors.New("database not loaded")
+
+	// ErrNoPreviousVersion is returned when there is no previous version of a database to roll back to
+	ErrNoPreviousVersion = errors.New("no previous version")
+
 	// ErrIpNotFound is returned when the IP is not found
 	ErrIpNotFound = errors.New("ip not found")
+
+	// ErrUnknownDataset is returned when the dataset name is not known
+	ErrUnknownDataset = errors.New("unknown dataset")
+
+	// ErrUpdateInProgress is returned when an update of the dataset is already queued or running
+	ErrUpdateInProgress = errors.New("update already in progress")
+
+	// ErrInvalidStoreKey is returned when a store key is empty or holds relative path segments
+	ErrInvalidStoreKey = errors.New("invalid store key")
+
+	// ErrDatasetChanged is returned when a database file no longer is the loaded one
+	ErrDatasetChanged = errors.New("dataset changed while it was read")
+
+	// ErrExportInProgress is returned when an export is already running
+	ErrExportInProgress = errors.New("export already in progress")
+
+	// ErrNoBlocklists is returned when no blocklist is configured
+	ErrNoBlocklists = errors.New("no blocklists configured")
+
+	// ErrAPIKeyNotFound is returned when the api key is not known
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	// ErrUnknownAPITier is returned when an api key is added to a tier that is not configured
+	ErrUnknownAPITier = errors.New("unknown api key tier")
 )
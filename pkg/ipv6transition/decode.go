@@ -0,0 +1,140 @@
+// Package ipv6transition decodes the IPv4 addresses embedded in IPv6 transition addresses, and the MAC address of
+// EUI-64 interface identifiers.
+package ipv6transition
+
+import (
+	"fmt"
+	"ip_service/pkg/model"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+var (
+	prefix6to4    = netip.MustParsePrefix("2002::/16")
+	prefixTeredo  = netip.MustParsePrefix("2001::/32")
+	prefixMapped  = netip.MustParsePrefix("::ffff:0:0/96")
+	prefixNAT64WK = netip.MustParsePrefix("64:ff9b::/96")
+)
+
+// Decoder decodes IPv6 addresses, with the local NAT64 prefixes besides the well-known prefix
+type Decoder struct {
+	nat64 []netip.Prefix
+}
+
+// NewDecoder returns a decoder of the well-known NAT64 prefix and nat64, which must be of length 32, 40, 48, 56,
+// 64 or 96 (RFC 6052 2.2)
+func NewDecoder(nat64 []string) (*Decoder, error) {
+	d := &Decoder{nat64: []netip.Prefix{prefixNAT64WK}}
+	for _, s := range nat64 {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		if !prefix.Addr().Is6() || !slices.Contains([]int{32, 40, 48, 56, 64, 96}, prefix.Bits()) {
+			return nil, fmt.Errorf("NAT64 prefix %s is not an IPv6 prefix of length 32, 40, 48, 56, 64 or 96", s)
+		}
+		d.nat64 = append(d.nat64, prefix.Masked())
+	}
+
+	// the most specific prefix wins
+	slices.SortStableFunc(d.nat64, func(a, b netip.Prefix) int {
+		return b.Bits() - a.Bits()
+	})
+
+	return d, nil
+}
+
+// Decode returns the transition mechanism and EUI-64 interface identifier of addr, nil for an IPv4 address or an
+// IPv6 address that tells neither. The vendor of an EUI-64 MAC is left to the caller.
+func (d *Decoder) Decode(addr netip.Addr) *model.Transition {
+	if !addr.Is6() {
+		return nil
+	}
+	addr = addr.WithZone("")
+	b := addr.As16()
+
+	t := &model.Transition{}
+	switch {
+	case prefixMapped.Contains(addr):
+		t.Mechanism = model.TransitionIPv4Mapped
+		t.Prefix = prefixMapped.String()
+		t.EmbeddedIPv4 = addr.Unmap().String()
+		return t
+
+	case prefixTeredo.Contains(addr):
+		t.Mechanism = model.TransitionTeredo
+		t.Prefix = prefixTeredo.String()
+		t.Teredo = &model.Teredo{
+			Server:     netip.AddrFrom4([4]byte(b[4:8])).String(),
+			Client:     netip.AddrFrom4([4]byte{^b[12], ^b[13], ^b[14], ^b[15]}).String(),
+			ClientPort: ^(uint16(b[10])<<8 | uint16(b[11])),
+			Cone:       b[8]&0x80 != 0,
+		}
+		t.EmbeddedIPv4 = t.Teredo.Client
+		return t
+
+	case prefix6to4.Contains(addr):
+		t.Mechanism = model.Transition6to4
+		t.Prefix = prefix6to4.String()
+		t.EmbeddedIPv4 = netip.AddrFrom4([4]byte(b[2:6])).String()
+		t.EUI64 = eui64(b)
+		return t
+	}
+
+	for _, prefix := range d.nat64 {
+		if prefix.Contains(addr) {
+			t.Mechanism = model.TransitionNAT64
+			t.Prefix = prefix.String()
+			t.EmbeddedIPv4 = nat64IPv4(b, prefix.Bits()).String()
+			return t
+		}
+	}
+
+	// ISATAP interface identifier, ::0:5efe:a.b.c.d or ::200:5efe:a.b.c.d with the universal bit set (RFC 5214 6.1)
+	if b[8]&^0x02 == 0 && b[9] == 0 && b[10] == 0x5e && b[11] == 0xfe {
+		t.Mechanism = model.TransitionISATAP
+		t.EmbeddedIPv4 = netip.AddrFrom4([4]byte(b[12:16])).String()
+		return t
+	}
+
+	if t.EUI64 = eui64(b); t.EUI64 != nil {
+		return t
+	}
+
+	return nil
+}
+
+// nat64IPv4 returns the IPv4 address embedded after a NAT64 prefix of bits, skipping bits 64 to 71 (RFC 6052 2.2)
+func nat64IPv4(b [16]byte, bits int) netip.Addr {
+	var v4 [4]byte
+	i := bits / 8
+	for n := range v4 {
+		if i == 8 {
+			i++
+		}
+		v4[n] = b[i]
+		i++
+	}
+	return netip.AddrFrom4(v4)
+}
+
+// eui64 returns the MAC address of an EUI-64 interface identifier, its fffe middle marks it, nil for other identifiers
+func eui64(b [16]byte) *model.EUI64 {
+	if b[11] != 0xff || b[12] != 0xfe {
+		return nil
+	}
+
+	// the universal/local bit is inverted in the interface identifier
+	mac := net.HardwareAddr{b[8] ^ 0x02, b[9], b[10], b[13], b[14], b[15]}
+	e := &model.EUI64{
+		MAC:                 mac.String(),
+		LocallyAdministered: mac[0]&0x02 != 0,
+	}
+	if !e.LocallyAdministered {
+		e.OUI = strings.ToUpper(fmt.Sprintf("%02x-%02x-%02x", mac[0], mac[1], mac[2]))
+	}
+
+	return e
+}
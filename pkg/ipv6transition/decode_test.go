@@ -0,0 +1,93 @@
+package ipv6transition
+
+import (
+	"ip_service/pkg/model"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	d, err := NewDecoder([]string{"2001:db8:100::/40", "2001:db8:64::/96"})
+	require.NoError(t, err)
+
+	tts := []struct {
+		ip   string
+		want *model.Transition
+	}{
+		{
+			ip:   "::ffff:130.242.1.1",
+			want: &model.Transition{Mechanism: "ipv4_mapped", Prefix: "::ffff:0.0.0.0/96", EmbeddedIPv4: "130.242.1.1"},
+		},
+		{
+			// RFC 4380 4 example, server 65.54.227.120, client 192.0.2.45 port 40000, cone
+			ip: "2001:0:4136:e378:8000:63bf:3fff:fdd2",
+			want: &model.Transition{
+				Mechanism:    "teredo",
+				Prefix:       "2001::/32",
+				EmbeddedIPv4: "192.0.2.45",
+				Teredo:       &model.Teredo{Server: "65.54.227.120", Client: "192.0.2.45", ClientPort: 40000, Cone: true},
+			},
+		},
+		{
+			ip:   "2002:82f2:101::1",
+			want: &model.Transition{Mechanism: "6to4", Prefix: "2002::/16", EmbeddedIPv4: "130.242.1.1"},
+		},
+		{
+			ip: "2002:82f2:101:1:250:56ff:fe01:203",
+			want: &model.Transition{
+				Mechanism:    "6to4",
+				Prefix:       "2002::/16",
+				EmbeddedIPv4: "130.242.1.1",
+				EUI64:        &model.EUI64{MAC: "00:50:56:01:02:03", OUI: "00-50-56"},
+			},
+		},
+		{
+			ip:   "64:ff9b::82f2:101",
+			want: &model.Transition{Mechanism: "nat64", Prefix: "64:ff9b::/96", EmbeddedIPv4: "130.242.1.1"},
+		},
+		{
+			// RFC 6052 2.4 example of a /40 prefix, the u octet is skipped
+			ip:   "2001:db8:1c0:2:21::",
+			want: &model.Transition{Mechanism: "nat64", Prefix: "2001:db8:100::/40", EmbeddedIPv4: "192.0.2.33"},
+		},
+		{
+			ip:   "2001:db8:64::c000:221",
+			want: &model.Transition{Mechanism: "nat64", Prefix: "2001:db8:64::/96", EmbeddedIPv4: "192.0.2.33"},
+		},
+		{
+			ip:   "2001:db8:1:2:200:5efe:82f2:101",
+			want: &model.Transition{Mechanism: "isatap", EmbeddedIPv4: "130.242.1.1"},
+		},
+		{
+			ip:   "fe80::5efe:a00:1%eth0",
+			want: &model.Transition{Mechanism: "isatap", EmbeddedIPv4: "10.0.0.1"},
+		},
+		{
+			ip:   "2001:6b0:1:2:ba27:ebff:fe01:203",
+			want: &model.Transition{EUI64: &model.EUI64{MAC: "b8:27:eb:01:02:03", OUI: "B8-27-EB"}},
+		},
+		{
+			// a locally administered MAC, e.g. of a qemu guest
+			ip:   "2001:6b0:1:2:5054:ff:fe12:3456",
+			want: &model.Transition{EUI64: &model.EUI64{MAC: "52:54:00:12:34:56", LocallyAdministered: true}},
+		},
+		{ip: "2001:6b0:1:2:1c8f:4d2e:a913:77b1"},
+		{ip: "130.242.1.1"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, d.Decode(netip.MustParseAddr(tt.ip)))
+		})
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	for _, prefix := range []string{"2001:db8::/33", "192.0.2.0/24", "2001:db8::"} {
+		_, err := NewDecoder([]string{prefix})
+		assert.Error(t, err, prefix)
+	}
+}
@@ -0,0 +1,135 @@
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// data types of the MaxMind DB data section, see https://maxmind.github.io/MaxMind-DB/
+const (
+	typeString  = 2
+	typeDouble  = 3
+	typeUint16  = 5
+	typeUint32  = 6
+	typeMap     = 7
+	typeInt32   = 8
+	typeUint64  = 9
+	typeArray   = 11
+	typeBoolean = 14
+)
+
+// encode appends v to buf. Maps are encoded with sorted keys, so equal values have equal encodings.
+func encode(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case string:
+		writeControl(buf, typeString, len(v))
+		buf.WriteString(v)
+	case float64:
+		writeControl(buf, typeDouble, 8)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		writeControl(buf, typeBoolean, size)
+	case uint16:
+		writeUint(buf, typeUint16, uint64(v))
+	case uint32:
+		writeUint(buf, typeUint32, uint64(v))
+	case uint64:
+		writeUint(buf, typeUint64, v)
+	case uint:
+		if v <= math.MaxUint32 {
+			writeUint(buf, typeUint32, uint64(v))
+		} else {
+			writeUint(buf, typeUint64, uint64(v))
+		}
+	case int:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return fmt.Errorf("int %d overflows int32", v)
+		}
+		if v >= 0 {
+			writeUint(buf, typeInt32, uint64(v))
+		} else {
+			writeControl(buf, typeInt32, 4)
+			binary.Write(buf, binary.BigEndian, int32(v))
+		}
+	case map[string]any:
+		writeControl(buf, typeMap, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			encode(buf, key)
+			if err := encode(buf, v[key]); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	case map[string]string:
+		writeControl(buf, typeMap, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			encode(buf, key)
+			encode(buf, v[key])
+		}
+	case []any:
+		writeControl(buf, typeArray, len(v))
+		for _, value := range v {
+			if err := encode(buf, value); err != nil {
+				return err
+			}
+		}
+	case []string:
+		writeControl(buf, typeArray, len(v))
+		for _, value := range v {
+			encode(buf, value)
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	return nil
+}
+
+// writeUint appends v big endian in as few bytes as possible, 0 has no bytes
+func writeUint(buf *bytes.Buffer, typ byte, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	size := 8
+	for size > 0 && b[8-size] == 0 {
+		size--
+	}
+	writeControl(buf, typ, size)
+	buf.Write(b[8-size:])
+}
+
+// writeControl appends the control byte of a value of typ and size, with the extended type and size bytes
+func writeControl(buf *bytes.Buffer, typ byte, size int) {
+	var control byte
+	if typ <= 7 {
+		control = typ << 5
+	}
+
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		control |= byte(size)
+	case size < 285:
+		control |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 65821:
+		control |= 30
+		n := size - 285
+		sizeBytes = []byte{byte(n >> 8), byte(n)}
+	default:
+		control |= 31
+		n := size - 65821
+		sizeBytes = []byte{byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+
+	buf.WriteByte(control)
+	if typ > 7 {
+		buf.WriteByte(typ - 7)
+	}
+	buf.Write(sizeBytes)
+}
@@ -0,0 +1,281 @@
+// Package mmdb writes MaxMind DB files, as read by the geoip2 modules of nginx and HAProxy, libmaxminddb and maxminddb-golang.
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"net/netip"
+	"time"
+)
+
+// metadataMarker starts the metadata section at the end of the file
+const metadataMarker = "\xab\xcd\xefMaxMind.com"
+
+// Metadata describes the database in its metadata section
+type Metadata struct {
+	DatabaseType string
+	Description  map[string]string
+	Languages    []string
+	BuildEpoch   time.Time
+}
+
+// Writer builds an IPv6 database, IPv4 networks are stored in ::/96 like in MaxMind's own databases
+type Writer struct {
+	meta    Metadata
+	root    *node
+	records map[string]*record
+	merged  map[[2]*record]*record
+	// recordSize is chosen from the size of the database when 0
+	recordSize int
+}
+
+// node is a node of the search tree, a node without children is a leaf holding the record of its network or none
+type node struct {
+	children [2]*node
+	record   *record
+}
+
+func (n *node) isLeaf() bool {
+	return n.children[0] == nil && n.children[1] == nil
+}
+
+// record is a data section value, interned by its encoding so networks with equal data share it
+type record struct {
+	data    map[string]any
+	encoded []byte
+	offset  int
+}
+
+// NewWriter returns an empty database
+func NewWriter(meta Metadata) *Writer {
+	return &Writer{
+		meta:    meta,
+		root:    &node{},
+		records: map[string]*record{},
+		merged:  map[[2]*record]*record{},
+	}
+}
+
+// SetLanguages sets the languages of the metadata, they are usually known once the records are inserted
+func (w *Writer) SetLanguages(languages []string) {
+	w.meta.Languages = languages
+}
+
+// Insert merges data into the records of the addresses of prefix, the keys of data replace the same keys already stored.
+// Insert less specific prefixes first, so the data of more specific ones wins.
+func (w *Writer) Insert(prefix netip.Prefix, data map[string]any) error {
+	if !prefix.IsValid() {
+		return fmt.Errorf("invalid prefix %s", prefix)
+	}
+
+	r, err := w.intern(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", prefix, err)
+	}
+
+	key, bits := treeKey(prefix)
+
+	n := w.root
+	for depth := 0; depth < bits; depth++ {
+		// a leaf on the path is split, its record stays with both halves
+		if n.isLeaf() && n.record != nil {
+			n.children = [2]*node{{record: n.record}, {record: n.record}}
+			n.record = nil
+		}
+
+		bit := key[depth/8] >> (7 - depth%8) & 1
+		if n.children[bit] == nil {
+			n.children[bit] = &node{}
+		}
+		n = n.children[bit]
+	}
+
+	w.mergeInto(n, r)
+
+	return nil
+}
+
+// treeKey returns the address of prefix in the IPv6 tree and its number of bits there
+func treeKey(prefix netip.Prefix) ([16]byte, int) {
+	prefix = prefix.Masked()
+	if prefix.Addr().Is4() {
+		var key [16]byte
+		v4 := prefix.Addr().As4()
+		copy(key[12:], v4[:])
+		return key, prefix.Bits() + 96
+	}
+	return prefix.Addr().As16(), prefix.Bits()
+}
+
+// mergeInto merges r into every record below n, and stores it where there is none
+func (w *Writer) mergeInto(n *node, r *record) {
+	if n.isLeaf() {
+		n.record = w.merge(n.record, r)
+		return
+	}
+	for i, child := range n.children {
+		if child == nil {
+			n.children[i] = &node{record: r}
+			continue
+		}
+		w.mergeInto(child, r)
+	}
+}
+
+// merge returns base with the keys of r replaced, merges are cached since many networks share both records
+func (w *Writer) merge(base, r *record) *record {
+	if base == nil || base == r {
+		return r
+	}
+	if merged, ok := w.merged[[2]*record{base, r}]; ok {
+		return merged
+	}
+
+	data := maps.Clone(base.data)
+	maps.Copy(data, r.data)
+
+	// both records are already encoded, so the merge is too
+	merged, _ := w.intern(data)
+	w.merged[[2]*record{base, r}] = merged
+	return merged
+}
+
+func (w *Writer) intern(data map[string]any) (*record, error) {
+	buf := &bytes.Buffer{}
+	if err := encode(buf, data); err != nil {
+		return nil, err
+	}
+
+	if r, ok := w.records[buf.String()]; ok {
+		return r, nil
+	}
+	r := &record{data: data, encoded: buf.Bytes()}
+	w.records[buf.String()] = r
+	return r, nil
+}
+
+// WriteTo writes the database to out
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	prune(w.root)
+	// the root is a node even when one record covers all addresses
+	if w.root.isLeaf() {
+		w.root.children = [2]*node{{record: w.root.record}, {record: w.root.record}}
+		w.root.record = nil
+	}
+
+	// number the nodes breadth first, and lay out the records in the order they are first used
+	nodes := []*node{w.root}
+	index := map[*node]int{w.root: 0}
+	data := &bytes.Buffer{}
+	placed := map[*record]bool{}
+
+	for i := 0; i < len(nodes); i++ {
+		for _, child := range nodes[i].children {
+			switch {
+			case child == nil:
+			case !child.isLeaf():
+				index[child] = len(nodes)
+				nodes = append(nodes, child)
+			case child.record != nil && !placed[child.record]:
+				child.record.offset = data.Len()
+				data.Write(child.record.encoded)
+				placed[child.record] = true
+			}
+		}
+	}
+
+	nodeCount := len(nodes)
+	recordSize := w.recordSize
+	if recordSize == 0 {
+		recordSize = chooseRecordSize(nodeCount + 16 + data.Len())
+	}
+
+	value := func(child *node) uint32 {
+		switch {
+		case child == nil:
+			return uint32(nodeCount)
+		case !child.isLeaf():
+			return uint32(index[child])
+		case child.record == nil:
+			return uint32(nodeCount)
+		default:
+			return uint32(nodeCount + 16 + child.record.offset)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Grow(nodeCount*recordSize/4 + 16 + data.Len())
+	for _, n := range nodes {
+		writeNode(buf, recordSize, value(n.children[0]), value(n.children[1]))
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(data.Bytes())
+	buf.WriteString(metadataMarker)
+
+	meta := map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(w.meta.BuildEpoch.Unix()),
+		"database_type":               w.meta.DatabaseType,
+		"description":                 w.meta.Description,
+		"ip_version":                  uint16(6),
+		"languages":                   w.meta.Languages,
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(recordSize),
+	}
+	// a nil description or languages is encoded as empty
+	if err := encode(buf, meta); err != nil {
+		return 0, err
+	}
+
+	return buf.WriteTo(out)
+}
+
+// prune turns nodes whose halves have the same record into leaves
+func prune(n *node) {
+	if n.isLeaf() {
+		return
+	}
+	for _, child := range n.children {
+		if child != nil {
+			prune(child)
+		}
+	}
+
+	left, right := n.children[0], n.children[1]
+	if left != nil && right != nil && left.isLeaf() && right.isLeaf() && left.record == right.record {
+		n.record = left.record
+		n.children = [2]*node{}
+	}
+}
+
+// chooseRecordSize returns the smallest record size holding values up to max
+func chooseRecordSize(max int) int {
+	switch {
+	case max < 1<<24:
+		return 24
+	case max < 1<<28:
+		return 28
+	default:
+		return 32
+	}
+}
+
+// writeNode appends a node of two records, a 28 bit node keeps the high nibbles of both records in its middle byte
+func writeNode(buf *bytes.Buffer, recordSize int, left, right uint32) {
+	switch recordSize {
+	case 24:
+		buf.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+	case 28:
+		buf.Write([]byte{
+			byte(left >> 16), byte(left >> 8), byte(left),
+			byte(left>>24)<<4 | byte(right>>24)&0x0f,
+			byte(right >> 16), byte(right >> 8), byte(right),
+		})
+	default:
+		binary.Write(buf, binary.BigEndian, [2]uint32{left, right})
+	}
+}
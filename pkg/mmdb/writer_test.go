@@ -0,0 +1,131 @@
+package mmdb
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRecord struct {
+	Country struct {
+		IsoCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	ASN    uint    `maxminddb:"autonomous_system_number"`
+	Label  string  `maxminddb:"label"`
+	Lat    float64 `maxminddb:"latitude"`
+	Signed int     `maxminddb:"signed"`
+	EU     bool    `maxminddb:"is_eu"`
+	Tags   []any   `maxminddb:"tags"`
+}
+
+func newTestWriter(t *testing.T) *Writer {
+	w := NewWriter(Metadata{
+		DatabaseType: "ip_service-test",
+		Description:  map[string]string{"en": "test"},
+		Languages:    []string{"en"},
+		BuildEpoch:   time.Unix(1700000000, 0),
+	})
+
+	require.NoError(t, w.Insert(netip.MustParsePrefix("192.0.2.0/24"), map[string]any{
+		"country": map[string]any{"iso_code": "SE", "names": map[string]string{"en": "Sweden", "de": "Schweden"}},
+		"latitude": 59.85,
+		"is_eu":    true,
+		"signed":   -7,
+		"tags":     []any{"a", uint32(1)},
+	}))
+	require.NoError(t, w.Insert(netip.MustParsePrefix("192.0.2.0/23"), map[string]any{"autonomous_system_number": uint(1653)}))
+	require.NoError(t, w.Insert(netip.MustParsePrefix("192.0.2.128/25"), map[string]any{"label": "eduroam"}))
+	require.NoError(t, w.Insert(netip.MustParsePrefix("2001:db8::/32"), map[string]any{"label": longLabel()}))
+
+	return w
+}
+
+func longLabel() string {
+	return string(bytes.Repeat([]byte("x"), 300))
+}
+
+func TestWriter(t *testing.T) {
+	for _, recordSize := range []int{0, 24, 28, 32} {
+		w := newTestWriter(t)
+		w.recordSize = recordSize
+
+		buf := &bytes.Buffer{}
+		_, err := w.WriteTo(buf)
+		require.NoError(t, err)
+
+		db, err := maxminddb.FromBytes(buf.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, db.Verify())
+
+		if recordSize != 0 {
+			assert.Equal(t, uint(recordSize), db.Metadata.RecordSize)
+		}
+		assert.Equal(t, "ip_service-test", db.Metadata.DatabaseType)
+		assert.Equal(t, uint(1700000000), db.Metadata.BuildEpoch)
+		assert.Equal(t, uint(6), db.Metadata.IPVersion)
+
+		got := testRecord{}
+		require.NoError(t, db.Lookup(net.ParseIP("192.0.2.1"), &got))
+		assert.Equal(t, "SE", got.Country.IsoCode)
+		assert.Equal(t, "Schweden", got.Country.Names["de"])
+		assert.Equal(t, uint(1653), got.ASN)
+		assert.Equal(t, 59.85, got.Lat)
+		assert.Equal(t, -7, got.Signed)
+		assert.True(t, got.EU)
+		assert.Equal(t, []any{"a", uint64(1)}, got.Tags)
+		assert.Empty(t, got.Label)
+
+		got = testRecord{}
+		require.NoError(t, db.Lookup(net.ParseIP("192.0.2.200"), &got))
+		assert.Equal(t, "SE", got.Country.IsoCode)
+		assert.Equal(t, "eduroam", got.Label)
+
+		got = testRecord{}
+		require.NoError(t, db.Lookup(net.ParseIP("192.0.3.1"), &got))
+		assert.Equal(t, uint(1653), got.ASN)
+		assert.Empty(t, got.Country.IsoCode)
+
+		got = testRecord{}
+		require.NoError(t, db.Lookup(net.ParseIP("2001:db8::1"), &got))
+		assert.Equal(t, longLabel(), got.Label)
+
+		var found any
+		network, ok, err := db.LookupNetwork(net.ParseIP("198.51.100.1"), &found)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.NotNil(t, network)
+	}
+}
+
+func TestWriterNetworks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	_, err := newTestWriter(t).WriteTo(buf)
+	require.NoError(t, err)
+
+	db, err := maxminddb.FromBytes(buf.Bytes())
+	require.NoError(t, err)
+
+	var got []string
+	networks := db.Networks(maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		network, err := networks.Network(&struct{}{})
+		require.NoError(t, err)
+		got = append(got, network.String())
+	}
+	require.NoError(t, networks.Err())
+
+	assert.Equal(t, []string{"192.0.2.0/25", "192.0.2.128/25", "192.0.3.0/24", "2001:db8::/32"}, got)
+}
+
+func TestEncodeUnsupported(t *testing.T) {
+	w := NewWriter(Metadata{DatabaseType: "test"})
+	assert.Error(t, w.Insert(netip.MustParsePrefix("192.0.2.0/24"), map[string]any{"bad": struct{}{}}))
+	assert.Error(t, w.Insert(netip.Prefix{}, map[string]any{}))
+}
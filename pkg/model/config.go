@@ -1,12 +1,15 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/fs"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -14,31 +17,232 @@ import (
 type APIServer struct {
 	Addr        string `yaml:"addr" validate:"required"`
 	BehindProxy bool   `yaml:"behind_proxy"`
+	// TrustedProxyHops is the number of proxies in front of the service appending to X-Forwarded-For, defaults to 1.
+	// The client is the entry appended by the outermost of them, entries to its left are written by the client.
+	TrustedProxyHops int       `yaml:"trusted_proxy_hops" validate:"min=0"`
+	RateLimit        RateLimit `yaml:"rate_limit"`
+	APIKeys          APIKeys   `yaml:"api_keys"`
+	TLS              TLS       `yaml:"tls"`
+	Admin            Admin     `yaml:"admin"`
+}
+
+// TLS holds the tls configuration of the api server
+type TLS struct {
+	Enable   bool   `yaml:"enable"`
+	CertFile string `yaml:"cert_file" validate:"required_if=Enable true"`
+	KeyFile  string `yaml:"key_file" validate:"required_if=Enable true"`
+	// ClientCAFile verifies client certificates when given, used to authenticate admins
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// Admin holds the admin api configuration, admins authenticate with a token or a client certificate
+type Admin struct {
+	Enable bool         `yaml:"enable"`
+	Tokens []AdminToken `yaml:"tokens" validate:"dive"`
+	// ClientCertNames holds the common names of the client certificates allowed as admins
+	ClientCertNames []string `yaml:"client_cert_names"`
+}
+
+// AdminToken is a bearer token of an admin
+type AdminToken struct {
+	Name  string `yaml:"name" validate:"required"`
+	Token string `yaml:"token" validate:"required"`
+}
+
+// APIKeys holds the api key configuration, keys can also be added to the store
+type APIKeys struct {
+	// Protected holds the route templates that require an api key
+	Protected []string           `yaml:"protected"`
+	Tiers     map[string]APITier `yaml:"tiers" validate:"dive"`
+	Keys      []APIKey           `yaml:"keys" validate:"dive"`
+	// InvalidKeyLimit limits the invalid keys a client may send before it gets 429, per /32 or /64, defaults to a
+	// rate of 1 and a burst of 10
+	InvalidKeyLimit RateLimitBucket `yaml:"invalid_key_limit"`
+}
+
+// APITier holds the routes and quota of an api key tier
+type APITier struct {
+	// Routes holds the protected route templates the tier may use, empty allows all
+	Routes []string `yaml:"routes"`
+	// RateLimit is the quota of each key in the tier, per route, a zero rate is unlimited
+	RateLimit RateLimitBucket `yaml:"rate_limit"`
+}
+
+// RateLimitBucket holds a token bucket, rate is in requests per second
+type RateLimitBucket struct {
+	Rate  float64 `yaml:"rate" validate:"min=0"`
+	Burst int     `yaml:"burst" validate:"min=0"`
+}
+
+// RateLimit holds the per client rate limit configuration for the api server
+type RateLimit struct {
+	Enabled bool `yaml:"enabled"`
+	// Default is used for routes not present in Routes, a zero rate disables limiting
+	Default RateLimitBucket `yaml:"default"`
+	// Routes is keyed by route template, e.g. /lookup/:ip
+	Routes map[string]RateLimitBucket `yaml:"routes"`
+	// Allowlist holds CIDRs that are never limited
+	Allowlist []string `yaml:"allowlist" validate:"dive,cidr"`
+	// IPv4PrefixLength and IPv6PrefixLength group clients into one bucket, defaults to 32 and 64
+	IPv4PrefixLength int `yaml:"ipv4_prefix_length" validate:"omitempty,min=1,max=32"`
+	IPv6PrefixLength int `yaml:"ipv6_prefix_length" validate:"omitempty,min=1,max=128"`
+	// IdleTimeout is how long an unused bucket is kept, defaults to 10 minutes
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+}
+
+// APIKey maps an api key to a tier, only the hash of the key is stored
+type APIKey struct {
+	Name string `yaml:"name" json:"name" validate:"required"`
+	Key  string `yaml:"key" json:"-" validate:"required"`
+	Hash string `yaml:"-" json:"hash"`
+	Tier string `yaml:"tier" json:"tier" validate:"required"`
+}
+
+// HashAPIKey returns the hex encoded sha256 of key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Bucket returns the token bucket for the route template
+func (r *RateLimit) Bucket(route string) RateLimitBucket {
+	if bucket, ok := r.Routes[route]; ok {
+		return bucket
+	}
+	return r.Default
 }
 
 // Log holds the log configuration
 type Log struct {
-	Level      string `yaml:"level"`
+	Level      string `yaml:"level" validate:"omitempty,oneof=trace debug info warn error"`
 	FolderPath string `yaml:"folder_path"`
 }
 
 // MaxMindDB holds maxmind db configuration
 type MaxMindDB struct {
-	FilePath string `yaml:"file_path"`
+	// Source is download (default) to fetch the edition from remote_url, or local to open file_path
+	Source   string `yaml:"source" validate:"omitempty,oneof=download local"`
+	FilePath string `yaml:"file_path" validate:"required_if=Source local"`
+	// PollInterval is how often a local file is checked for changes, defaults to 1m
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Provider is the vendor of the db, it selects the download URL and how its records are read, defaults to maxmind
+	Provider string `yaml:"provider" validate:"omitempty,oneof=maxmind dbip ipinfo ip2location url"`
+	// URL replaces the download URL of the provider, required for the url provider
+	URL string `yaml:"url" validate:"required_if=Provider url,omitempty,url"`
+	// Token authenticates ipinfo and ip2location downloads, and url downloads as a bearer token
+	Token string `yaml:"token"`
+	// Format of the download, defaults to the format of the provider or the suffix of url
+	Format string `yaml:"format" validate:"omitempty,oneof=tar.gz zip gz mmdb"`
 }
 
-// MaxMind holds the maxmind configuration
+const (
+	MaxMindSourceDownload = "download"
+	MaxMindSourceLocal    = "local"
+)
+
+const (
+	GeoProviderMaxMind     = "maxmind"
+	GeoProviderDBIP        = "dbip"
+	GeoProviderIPinfo      = "ipinfo"
+	GeoProviderIP2Location = "ip2location"
+	GeoProviderURL         = "url"
+)
+
+const (
+	DownloadFormatTarGz = "tar.gz"
+	DownloadFormatZip   = "zip"
+	DownloadFormatGz    = "gz"
+	DownloadFormatMMDB  = "mmdb"
+)
+
+// MaxMind holds the maxmind configuration.
+// Username, Password, RemoteURL and ArchiveFormat are only required for the db's that are downloaded from maxmind.
+// KeepVersions is the number of previous versions of each downloaded db kept for rollback, defaults to 2.
 type MaxMind struct {
 	AutomaticUpdate   bool                 `yaml:"automatic_update"`
 	UpdatePeriodicity time.Duration        `yaml:"update_periodicity"`
 	BaseFolder        string               `yaml:"base_folder" validate:"required"`
-	Username          string               `yaml:"username" validate:"required"`
-	Password          string               `yaml:"password" validate:"required"`
+	Username          string               `yaml:"username"`
+	Password          string               `yaml:"password"`
 	Enterprise        bool                 `yaml:"enterprise"`
 	RetryCounter      int                  `yaml:"retry_counter"`
-	DB                map[string]MaxMindDB `yaml:"db" validate:"required"`
-	RemoteURL         string               `yaml:"remote_url" validate:"required"`
-	ArchiveFormat     string               `yaml:"archive_format" validate:"required,oneof=tar.gz"`
+	DB                map[string]MaxMindDB `yaml:"db" validate:"required,dive"`
+	RemoteURL         string               `yaml:"remote_url"`
+	ArchiveFormat     string               `yaml:"archive_format" validate:"omitempty,oneof=tar.gz zip"`
+	KeepVersions      int                  `yaml:"keep_versions" validate:"omitempty,min=1"`
+}
+
+// CheckDownload returns an error if dbType is downloaded without the settings needed to download it
+func (m *MaxMind) CheckDownload(dbType string) error {
+	if m.IsLocal(dbType) {
+		return nil
+	}
+
+	db := m.DB[dbType]
+	switch m.Provider(dbType) {
+	case GeoProviderMaxMind:
+		if m.Username == "" || m.Password == "" || m.RemoteURL == "" || m.ArchiveFormat == "" {
+			return fmt.Errorf("maxmind %s is downloaded, username, password, remote_url and archive_format are required", dbType)
+		}
+	case GeoProviderIPinfo, GeoProviderIP2Location:
+		if db.Token == "" {
+			return fmt.Errorf("maxmind %s is downloaded from %s, token is required", dbType, db.Provider)
+		}
+	case GeoProviderURL:
+		if db.URL == "" {
+			return fmt.Errorf("maxmind %s is downloaded from url, url is required", dbType)
+		}
+	}
+	return nil
+}
+
+// Provider returns the provider of dbType, defaults to maxmind
+func (m *MaxMind) Provider(dbType string) string {
+	if provider := m.DB[dbType].Provider; provider != "" {
+		return provider
+	}
+	return GeoProviderMaxMind
+}
+
+// Format returns the download format of dbType, from format, the provider or the suffix of url
+func (m *MaxMind) Format(dbType string) string {
+	db := m.DB[dbType]
+	if db.Format != "" {
+		return db.Format
+	}
+
+	switch m.Provider(dbType) {
+	case GeoProviderDBIP:
+		return DownloadFormatGz
+	case GeoProviderIPinfo:
+		return DownloadFormatMMDB
+	case GeoProviderIP2Location:
+		return DownloadFormatZip
+	case GeoProviderURL:
+		u, err := url.Parse(db.URL)
+		if err != nil {
+			return DownloadFormatMMDB
+		}
+		switch p := strings.ToLower(u.Path); {
+		case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+			return DownloadFormatTarGz
+		case strings.HasSuffix(p, ".zip"):
+			return DownloadFormatZip
+		case strings.HasSuffix(p, ".gz"):
+			return DownloadFormatGz
+		}
+		return DownloadFormatMMDB
+	}
+
+	if m.ArchiveFormat != "" {
+		return m.ArchiveFormat
+	}
+	return DownloadFormatTarGz
+}
+
+// IsLocal reports whether the dbType is opened from a local file instead of downloaded
+func (m *MaxMind) IsLocal(dbType string) bool {
+	return m.DB[dbType].Source == MaxMindSourceLocal
 }
 
 func (m *MaxMind) URL(dbType string) (string, error) {
@@ -55,8 +259,23 @@ func (m *MaxMind) URL(dbType string) (string, error) {
 	return u.String(), nil
 }
 
+// SHA256URL returns the url of the published sha256 of the dbType archive
+func (m *MaxMind) SHA256URL(dbType string) (string, error) {
+	u, err := url.Parse(m.RemoteURL)
+	if err != nil {
+		return "", err
+	}
+
+	u = u.JoinPath("GeoLite2-" + dbType + "/download")
+
+	q := u.Query()
+	q.Set("suffix", m.ArchiveFormat+".sha256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
 func (m *MaxMind) IsArchivePresent(dbType string) bool {
-	p := filepath.Join(m.BaseFolder, fmt.Sprintf("GeoLite2-%s.tar.gz", dbType))
+	p := m.ArchiveFilePath(dbType)
 
 	if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
 		return true
@@ -78,15 +297,158 @@ func (m *MaxMind) IsDBPresent(dbType string) bool {
 	return true
 }
 
+// ArchiveFilePath returns the file path of the download of dbType (e.g. <basefolder>/GeoLite2-ASN.tar.gz)
 func (m *MaxMind) ArchiveFilePath(dbType string) string {
-	return filepath.Join(m.BaseFolder, fmt.Sprintf("GeoLite2-%s.tar.gz", dbType))
+	suffix := m.Format(dbType)
+	if suffix == DownloadFormatMMDB {
+		// the download is the db itself, keep it apart from the installed one
+		suffix = "download.mmdb"
+	}
+	return filepath.Join(m.BaseFolder, fmt.Sprintf("GeoLite2-%s.%s", dbType, suffix))
+}
+
+// VersionsFolder returns the folder holding the previous versions of the downloaded db's
+func (m *MaxMind) VersionsFolder() string {
+	return filepath.Join(m.BaseFolder, "versions")
 }
 
-// DBFilePath returns the file path for the given dbType (e.g. <basefolder>/GeoLite2-ASN.mmdb)
+// DBFilePath returns the file path for the given dbType (e.g. <basefolder>/GeoLite2-ASN.mmdb), or file_path for a local db
 func (m *MaxMind) DBFilePath(dbType string) string {
+	if m.IsLocal(dbType) {
+		return m.DB[dbType].FilePath
+	}
 	return filepath.Join(m.BaseFolder, fmt.Sprintf("GeoLite2-%s.mmdb", dbType))
 }
 
+// GeoRange holds the csv geolocation range tables, they are served as a geo provider
+type GeoRange struct {
+	// Primary asks the range tables before maxmind, otherwise they are a fallback
+	Primary bool `yaml:"primary"`
+	// UpdatePeriodicity is how often the sources are reloaded, defaults to 24h
+	UpdatePeriodicity time.Duration    `yaml:"update_periodicity"`
+	Sources           []GeoRangeSource `yaml:"sources" validate:"dive"`
+}
+
+// GeoRangeSource is a csv file of start_ip,end_ip,country,region,city,lat,lon rows, read from url or file_path.
+// Files ending in .gz are decompressed, and token is sent as a bearer token to url.
+type GeoRangeSource struct {
+	Name     string `yaml:"name" validate:"required"`
+	URL      string `yaml:"url" validate:"required_without=FilePath,omitempty,url"`
+	FilePath string `yaml:"file_path" validate:"required_without=URL"`
+	Token    string `yaml:"token"`
+}
+
+// Feeds holds the anonymity and proxy feeds, lists of addresses or prefixes that flag the IPs they hold
+type Feeds struct {
+	// UpdatePeriodicity is how often the feeds are reloaded, defaults to 1h
+	UpdatePeriodicity time.Duration `yaml:"update_periodicity"`
+	Sources           []FeedSource  `yaml:"sources" validate:"dive"`
+}
+
+// FeedSource is a list of addresses or prefixes, read from url or file_path, setting the flag of its kind.
+// A line holds an address or prefix as its first comma or space separated field, so Tor bulk exit lists,
+// Tor exit-addresses files and iCloud Private Relay egress-ip-ranges.csv are read as they are published.
+// Files ending in .gz are decompressed, and token is sent as a bearer token to url.
+type FeedSource struct {
+	Name     string `yaml:"name" validate:"required"`
+	Kind     string `yaml:"kind" validate:"required,oneof=tor_exit vpn relay"`
+	URL      string `yaml:"url" validate:"required_without=FilePath,omitempty,url"`
+	FilePath string `yaml:"file_path" validate:"required_without=URL"`
+	Token    string `yaml:"token"`
+}
+
+// Blocklists holds the blocklists lookups are checked against
+type Blocklists struct {
+	// UpdatePeriodicity is how often the lists are reloaded, defaults to 12h
+	UpdatePeriodicity time.Duration     `yaml:"update_periodicity"`
+	Sources           []BlocklistSource `yaml:"sources" validate:"dive"`
+}
+
+// BlocklistSource is a list of prefixes in Spamhaus DROP format, as text or json lines, or in FireHOL netset format,
+// read from url or file_path. Files ending in .gz are decompressed, and token is sent as a bearer token to url.
+type BlocklistSource struct {
+	Name     string `yaml:"name" validate:"required"`
+	URL      string `yaml:"url" validate:"required_without=FilePath,omitempty,url"`
+	FilePath string `yaml:"file_path" validate:"required_without=URL"`
+	Token    string `yaml:"token"`
+}
+
+// Cloud holds the published ranges of cloud and CDN providers
+type Cloud struct {
+	// UpdatePeriodicity is how often the range files are reloaded, defaults to 24h
+	UpdatePeriodicity time.Duration `yaml:"update_periodicity"`
+	Sources           []CloudSource `yaml:"sources" validate:"dive"`
+}
+
+// CloudSource is a range file published by a provider, read from url or file_path and parsed in the format of provider:
+// aws ip-ranges.json, gcp cloud.json, azure service tags, cloudflare ips-v4 and ips-v6, fastly public-ip-list or oracle public_ip_ranges.json.
+// Files ending in .gz are decompressed, and token is sent as a bearer token to url.
+type CloudSource struct {
+	Name     string `yaml:"name" validate:"required"`
+	Provider string `yaml:"provider" validate:"required,oneof=aws gcp azure cloudflare fastly oracle"`
+	URL      string `yaml:"url" validate:"required_without=FilePath,omitempty,url"`
+	FilePath string `yaml:"file_path" validate:"required_without=URL"`
+	Token    string `yaml:"token"`
+}
+
+// BGP holds the MRT TABLE_DUMP_V2 RIB dump of a route collector, e.g. a RouteViews rib or RIPE RIS bview file.
+// Files ending in .gz or .bz2 are decompressed.
+type BGP struct {
+	FilePath string `yaml:"file_path"`
+	// UpdatePeriodicity is how often the file is checked for a new dump, defaults to 1h
+	UpdatePeriodicity time.Duration `yaml:"update_periodicity"`
+}
+
+// IPv6Transition holds the settings of the IPv6 transition address analysis
+type IPv6Transition struct {
+	// NAT64Prefixes are local NAT64 prefixes (RFC 6052) besides 64:ff9b::/96, of length 32, 40, 48, 56, 64 or 96
+	NAT64Prefixes []string `yaml:"nat64_prefixes" validate:"dive,cidrv6"`
+	// OUIFilePath is the IEEE MA-L registry csv, oui.csv, for the vendors of EUI-64 interface identifiers.
+	// The registry embedded in pkg/oui is used without it.
+	OUIFilePath string `yaml:"oui_file_path"`
+}
+
+// DNS holds the resolver of the reverse DNS lookups, a pool of workers querying the DNS servers with a cache of
+// their answers, kept for their TTL
+type DNS struct {
+	// Resolver is the host:port of the DNS server to query, the nameservers of /etc/resolv.conf are used without it
+	Resolver string `yaml:"resolver" validate:"omitempty,hostname_port"`
+	// Timeout is the deadline of the PTR and forward lookups of a request, defaults to 2s.
+	// A lookup still running then is marked pending in the reply and finishes in the background, for the cache.
+	Timeout time.Duration `yaml:"timeout"`
+	// QueryTimeout is the timeout of a query to a DNS server, defaults to 5s
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// Workers is the number of concurrent queries, defaults to 16
+	Workers int `yaml:"workers" validate:"min=0"`
+	// QueueSize is the number of queries waiting for a worker, a lookup fails when the queue is full, defaults to 1024
+	QueueSize int `yaml:"queue_size" validate:"min=0"`
+	// CacheSize is the max number of cached answers, defaults to 10000
+	CacheSize int `yaml:"cache_size" validate:"min=0"`
+	// MaxTTL caps the TTL of cached answers, defaults to 1h
+	MaxTTL time.Duration `yaml:"max_ttl"`
+}
+
+// PrefixLabels holds the operator defined prefix labels file, a yaml or json list of prefixes with labels and overrides
+type PrefixLabels struct {
+	FilePath string `yaml:"file_path"`
+	// PollInterval is how often the file is checked for changes, defaults to 1m
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
+
+// Whois holds the whois configuration
+type Whois struct {
+	// UpdatePeriodicity is how often the RPSL sources are checked for updates, defaults to 24h
+	UpdatePeriodicity time.Duration `yaml:"update_periodicity"`
+}
+
+// Health holds the readiness thresholds, a dataset older than its max age fails the readiness probe
+type Health struct {
+	// MaxmindMaxAge is the max age of a maxmind database build, defaults to 30 days
+	MaxmindMaxAge time.Duration `yaml:"maxmind_max_age"`
+	// WhoisMaxAge is the max time since the RPSL sources were last updated, defaults to 72h
+	WhoisMaxAge time.Duration `yaml:"whois_max_age"`
+}
+
 type Radb struct {
 	FilePath string `yaml:"file_path"`
 }
@@ -105,6 +467,15 @@ type Store struct {
 	File FileStorage `yaml:"file"`
 }
 
+// LookupCache holds the configuration of the cache of assembled lookup replies
+type LookupCache struct {
+	Enable bool `yaml:"enable"`
+	// Size is the max number of replies per endpoint, defaults to 10000
+	Size int `yaml:"size" validate:"min=0"`
+	// TTL defaults to 1 hour, the cache is also flushed when a dataset is reloaded
+	TTL time.Duration `yaml:"ttl"`
+}
+
 // Tracing holds the tracing configuration
 type Tracing struct {
 	Addr   string `yaml:"addr"`
@@ -113,14 +484,25 @@ type Tracing struct {
 
 // IPService configs ip_service
 type IPService struct {
-	APIServer  APIServer `yaml:"api_server"`
-	Production bool      `yaml:"production"`
-	Log        Log       `yaml:"log"`
-	MaxMind    MaxMind   `yaml:"maxmind" validate:"required"`
-	Radb       Radb      `yaml:"radb" validate:"required"`
-	RIPE       RIPE      `yaml:"ripe" validate:"required"`
-	Store      Store     `yaml:"store"`
-	Tracing    Tracing   `yaml:"tracing"`
+	APIServer      APIServer      `yaml:"api_server"`
+	Production     bool           `yaml:"production"`
+	Log            Log            `yaml:"log"`
+	MaxMind        MaxMind        `yaml:"maxmind" validate:"required"`
+	Radb           Radb           `yaml:"radb" validate:"required"`
+	RIPE           RIPE           `yaml:"ripe" validate:"required"`
+	Whois          Whois          `yaml:"whois"`
+	Store          Store          `yaml:"store"`
+	Tracing        Tracing        `yaml:"tracing"`
+	LookupCache    LookupCache    `yaml:"lookup_cache"`
+	Health         Health         `yaml:"health"`
+	GeoRange       GeoRange       `yaml:"geo_range"`
+	PrefixLabels   PrefixLabels   `yaml:"prefix_labels"`
+	Feeds          Feeds          `yaml:"feeds"`
+	Blocklists     Blocklists     `yaml:"blocklists"`
+	Cloud          Cloud          `yaml:"cloud"`
+	BGP            BGP            `yaml:"bgp"`
+	IPv6Transition IPv6Transition `yaml:"ipv6_transition"`
+	DNS            DNS            `yaml:"dns"`
 }
 
 // Cfg holds the configuration for the service
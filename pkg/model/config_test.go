@@ -124,6 +124,16 @@ func TestMaxMindArchiveFilepath(t *testing.T) {
 			have:   &Cfg{IPService: &IPService{MaxMind: MaxMind{BaseFolder: "/tmp/db"}}},
 			want:   "/tmp/db/GeoLite2-city.tar.gz",
 		},
+		{
+			dbType: "dbip",
+			have:   &Cfg{IPService: &IPService{MaxMind: MaxMind{BaseFolder: "/tmp/db", DB: map[string]MaxMindDB{"dbip": {Provider: GeoProviderDBIP}}}}},
+			want:   "/tmp/db/GeoLite2-dbip.gz",
+		},
+		{
+			dbType: "ipinfo",
+			have:   &Cfg{IPService: &IPService{MaxMind: MaxMind{BaseFolder: "/tmp/db", DB: map[string]MaxMindDB{"ipinfo": {Provider: GeoProviderIPinfo}}}}},
+			want:   "/tmp/db/GeoLite2-ipinfo.download.mmdb",
+		},
 	}
 
 	for _, tt := range tts {
@@ -133,3 +143,95 @@ func TestMaxMindArchiveFilepath(t *testing.T) {
 		})
 	}
 }
+
+func TestMaxMindFormat(t *testing.T) {
+	tts := []struct {
+		name string
+		have MaxMind
+		want string
+	}{
+		{
+			name: "maxmind default",
+			have: MaxMind{},
+			want: DownloadFormatTarGz,
+		},
+		{
+			name: "maxmind zip",
+			have: MaxMind{ArchiveFormat: DownloadFormatZip},
+			want: DownloadFormatZip,
+		},
+		{
+			name: "ip2location",
+			have: MaxMind{DB: map[string]MaxMindDB{MaxmindDBTypeASN: {Provider: GeoProviderIP2Location}}},
+			want: DownloadFormatZip,
+		},
+		{
+			name: "url suffix",
+			have: MaxMind{DB: map[string]MaxMindDB{MaxmindDBTypeASN: {Provider: GeoProviderURL, URL: "https://example.com/geo/asn.tgz?key=1"}}},
+			want: DownloadFormatTarGz,
+		},
+		{
+			name: "url without suffix",
+			have: MaxMind{DB: map[string]MaxMindDB{MaxmindDBTypeASN: {Provider: GeoProviderURL, URL: "https://example.com/geo/asn"}}},
+			want: DownloadFormatMMDB,
+		},
+		{
+			name: "format overrides",
+			have: MaxMind{DB: map[string]MaxMindDB{MaxmindDBTypeASN: {Provider: GeoProviderDBIP, Format: DownloadFormatMMDB}}},
+			want: DownloadFormatMMDB,
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.have.Format(MaxmindDBTypeASN))
+		})
+	}
+}
+
+func TestMaxMindCheckDownload(t *testing.T) {
+	tts := []struct {
+		name    string
+		have    MaxMind
+		wantErr bool
+	}{
+		{
+			name:    "maxmind without credentials",
+			have:    MaxMind{},
+			wantErr: true,
+		},
+		{
+			name: "maxmind",
+			have: MaxMind{Username: "u", Password: "p", RemoteURL: "https://example.com", ArchiveFormat: DownloadFormatTarGz},
+		},
+		{
+			name: "dbip needs no credentials",
+			have: MaxMind{DB: map[string]MaxMindDB{MaxmindDBTypeASN: {Provider: GeoProviderDBIP}}},
+		},
+		{
+			name:    "ipinfo without token",
+			have:    MaxMind{DB: map[string]MaxMindDB{MaxmindDBTypeASN: {Provider: GeoProviderIPinfo}}},
+			wantErr: true,
+		},
+		{
+			name:    "url without url",
+			have:    MaxMind{DB: map[string]MaxMindDB{MaxmindDBTypeASN: {Provider: GeoProviderURL}}},
+			wantErr: true,
+		},
+		{
+			name: "local",
+			have: MaxMind{DB: map[string]MaxMindDB{MaxmindDBTypeASN: {Source: MaxMindSourceLocal, FilePath: "/tmp/asn.mmdb", Provider: GeoProviderIPinfo}}},
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.have.CheckDownload(MaxmindDBTypeASN)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
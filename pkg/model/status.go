@@ -70,6 +70,11 @@ type BuildVariables struct {
 	Version   string `json:"version,omitempty"`
 }
 
+// Healthy reports whether every probe of the reply passed
+func (r *StatusReply) Healthy() bool {
+	return r.Data != nil && r.Data.Status == fmt.Sprintf(StatusOK, r.Data.ServiceName)
+}
+
 // Check checks the status of each status, return the first that does not pass.
 func (probes StatusProbes) Check(serviceName string) *StatusReply {
 	health := &StatusReply{
@@ -2,13 +2,43 @@ package model
 
 import (
 	"ip_service/pkg/rpsl"
+	"strings"
+	"time"
 
 	ua "github.com/mileusna/useragent"
 )
 
 type ReplyIPInformation struct {
-	IP              string       `json:"ip"`
-	IPDecimal       string       `json:"ip_decimal"`
+	IP        string `json:"ip"`
+	IPDecimal string `json:"ip_decimal"`
+	ReplyFields
+	Hostname       string          `json:"hostname"`
+	UserAgent      ua.UserAgent    `json:"user_agent"`
+	Cloud          *CloudRange     `json:"cloud,omitempty"`
+	IPv6Transition *Transition     `json:"ipv6_transition,omitempty"`
+	ReverseDNS     *ReverseDNS     `json:"reverse_dns,omitempty"`
+	SpecialPurpose *SpecialPurpose `json:"special_purpose,omitempty"`
+	Labels         *Labels         `json:"labels,omitempty"`
+}
+
+type ReplyLookUp struct {
+	IP        string `json:"ip"`
+	IPDecimal string `json:"ip_decimal"`
+	ReplyFields
+	Hostname       string                  `json:"hostname"`
+	PTR            string                  `json:"ptr"`
+	Whois          map[string]*rpsl.Object `json:"whois,omitempty"`
+	Blocklists     []BlocklistMatch        `json:"blocklists,omitempty"`
+	Cloud          *CloudRange             `json:"cloud,omitempty"`
+	BGP            *BGPRoute               `json:"bgp,omitempty"`
+	IPv6Transition *Transition             `json:"ipv6_transition,omitempty"`
+	ReverseDNS     *ReverseDNS             `json:"reverse_dns,omitempty"`
+	SpecialPurpose *SpecialPurpose         `json:"special_purpose,omitempty"`
+	Labels         *Labels                 `json:"labels,omitempty"`
+}
+
+// ReplyFields are the geo, ASN and feed fields /all and /lookup share
+type ReplyFields struct {
 	ASN             uint         `json:"asn"`
 	ASNOrganization string       `json:"asn_organization"`
 	City            string       `json:"city"`
@@ -21,38 +51,289 @@ type ReplyIPInformation struct {
 	PostalCode      string       `json:"postal_code"`
 	Coordinates     *Coordinates `json:"coordinates"`
 	Timezone        string       `json:"timezone"`
-	Hostname        string       `json:"hostname"`
-	UserAgent       ua.UserAgent `json:"user_agent"`
 	Continent       string       `json:"continent"`
+	IsTorExit       bool         `json:"is_tor_exit"`
+	IsVPN           bool         `json:"is_vpn"`
+	IsRelay         bool         `json:"is_relay"`
+	Feeds           []string     `json:"feeds,omitempty"`
 }
 
-type ReplyLookUp struct {
-	IP              string                  `json:"ip"`
-	IPDecimal       string                  `json:"ip_decimal"`
-	ASN             uint                    `json:"asn"`
-	ASNOrganization string                  `json:"asn_organization"`
-	City            string                  `json:"city"`
-	Country         string                  `json:"country"`
-	CountryISO      string                  `json:"country_iso"`
-	IsEU            bool                    `json:"is_eu"`
-	Is1918Network   bool                    `json:"is_1918_network"`
-	Region          string                  `json:"region"`
-	RegionCode      string                  `json:"region_code"`
-	PostalCode      string                  `json:"postal_code"`
-	Coordinates     *Coordinates            `json:"coordinates"`
-	Timezone        string                  `json:"timezone"`
-	Hostname        string                  `json:"hostname"`
-	PTR             string                  `json:"ptr"`
-	Continent       string                  `json:"continent"`
-	Whois           map[string]*rpsl.Object `json:"whois,omitempty"`
+// ReplyBlocklist is the blocklist membership of an IP
+type ReplyBlocklist struct {
+	IP      string           `json:"ip"`
+	Listed  bool             `json:"listed"`
+	Matches []BlocklistMatch `json:"matches"`
+	// Lists names the loaded lists, listing the IP or not
+	Lists []string `json:"lists"`
+}
+
+// BlocklistMatch is a blocklist listing an IP
+type BlocklistMatch struct {
+	List string `json:"list"`
+	// Prefix is the most specific listed prefix holding the IP
+	Prefix string `json:"prefix"`
+	// Reference is the listing reference, e.g. the SBL id of a DROP entry
+	Reference string `json:"reference,omitempty"`
+	// ListUpdated is when the list was last modified, or loaded when its source has no date
+	ListUpdated time.Time `json:"list_updated"`
+	// ListAge is the age of the list in seconds when the reply was served
+	ListAge int64 `json:"list_age_seconds"`
+}
+
+// IRRChangeSet holds the route object changes between two IRR updates, per source
+type IRRChangeSet struct {
+	Timestamp time.Time                `json:"timestamp"`
+	Serials   map[string]string        `json:"serials"`
+	Sources   map[string]*rpsl.Changes `json:"sources"`
+}
+
+// DatasetState is the download and parse state of a dataset
+type DatasetState struct {
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Downloading bool      `json:"downloading"`
+	Parsing     bool      `json:"parsing"`
+	Version     string    `json:"version,omitempty"`
+	LastChecked string    `json:"last_checked,omitempty"`
+	BuildTime   time.Time `json:"build_time,omitzero"`
 }
 
+const (
+	DatasetKindMaxmind   = "maxmind"
+	DatasetKindIRR       = "irr"
+	DatasetKindGeoRange  = "georange"
+	DatasetKindFeeds     = "feeds"
+	DatasetKindBlocklist = "blocklist"
+	DatasetKindCloud     = "cloud"
+	DatasetKindBGP       = "bgp"
+)
+
 const (
 	MaxmindDBTypeASN  string = "ASN"
 	MaxmindDBTypeCity string = "City"
 )
 
 type Coordinates struct {
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude" yaml:"latitude"`
+	Longitude float64 `json:"longitude" yaml:"longitude"`
+}
+
+// Labels is the labels section of a reply, merged from the operator defined prefixes holding the IP.
+// A more specific prefix replaces the organization and coordinates, and the labels of the same key, of a less specific one.
+type Labels struct {
+	// Prefixes holds the matching prefixes, least specific first
+	Prefixes     []string          `json:"prefixes"`
+	Organization string            `json:"organization,omitempty"`
+	Coordinates  *Coordinates      `json:"coordinates,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	// Override holds the reply fields to replace, merged like the labels
+	Override *LabelOverride `json:"-"`
+}
+
+// LabelOverride holds the reply fields an operator defined prefix replaces, empty fields are kept
+type LabelOverride struct {
+	ASN             uint         `yaml:"asn"`
+	ASNOrganization string       `yaml:"asn_organization"`
+	City            string       `yaml:"city"`
+	Country         string       `yaml:"country"`
+	CountryISO      string       `yaml:"country_iso"`
+	Region          string       `yaml:"region"`
+	RegionCode      string       `yaml:"region_code"`
+	PostalCode      string       `yaml:"postal_code"`
+	Coordinates     *Coordinates `yaml:"coordinates"`
+	Timezone        string       `yaml:"timezone"`
+	Continent       string       `yaml:"continent"`
+}
+
+const (
+	FeedKindTorExit = "tor_exit"
+	FeedKindVPN     = "vpn"
+	FeedKindRelay   = "relay"
+)
+
+// FeedMatch holds the anonymity and proxy flags of an IP, set by the kinds of the feeds listing it
+type FeedMatch struct {
+	IsTorExit bool
+	IsVPN     bool
+	IsRelay   bool
+	// Feeds names the feeds listing the IP, in configured order
+	Feeds []string
+}
+
+const (
+	CloudProviderAWS        = "aws"
+	CloudProviderGCP        = "gcp"
+	CloudProviderAzure      = "azure"
+	CloudProviderCloudflare = "cloudflare"
+	CloudProviderFastly     = "fastly"
+	CloudProviderOracle     = "oracle"
+)
+
+// CloudRange is the most specific published range of a cloud or CDN provider holding an IP.
+// Service and region are empty when the provider does not publish them.
+type CloudRange struct {
+	Provider string `json:"provider"`
+	Service  string `json:"service,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Prefix   string `json:"prefix"`
+}
+
+// BGPRoute is the most specific announced prefix holding an IP in the loaded MRT RIB dump.
+// More than one origin AS is a multiple origin (MOAS) announcement, the most seen first.
+type BGPRoute struct {
+	Prefix     string   `json:"prefix"`
+	OriginASNs []uint32 `json:"origin_asns"`
+	// ASPathLength is the shortest AS path to the prefix among the peers
+	ASPathLength int `json:"as_path_length"`
+	// PeersSeen is the number of collector peers announcing the prefix, out of Peers
+	PeersSeen int       `json:"peers_seen"`
+	Peers     int       `json:"peers"`
+	DumpTime  time.Time `json:"dump_time"`
+	// IRROrigins are the origins of the most specific IRR route objects holding the IP
+	IRROrigins []string `json:"irr_origins,omitempty"`
+	// IRROriginMismatch is set when IRR route objects hold the IP and none has an announced origin
+	IRROriginMismatch bool `json:"irr_origin_mismatch"`
+}
+
+const (
+	TransitionIPv4Mapped = "ipv4_mapped"
+	Transition6to4       = "6to4"
+	TransitionTeredo     = "teredo"
+	TransitionNAT64      = "nat64"
+	TransitionISATAP     = "isatap"
+)
+
+// Transition is what an IPv6 address tells about its transition mechanism and interface identifier.
+// Mechanism is empty when only an EUI-64 interface identifier was decoded.
+type Transition struct {
+	Mechanism string `json:"mechanism,omitempty"`
+	// Prefix is the prefix of the mechanism, e.g. 2002::/16 or the NAT64 prefix
+	Prefix       string            `json:"prefix,omitempty"`
+	EmbeddedIPv4 string            `json:"embedded_ipv4,omitempty"`
+	Teredo       *Teredo           `json:"teredo,omitempty"`
+	EUI64        *EUI64            `json:"eui64,omitempty"`
+	Embedded     *EmbeddedIPv4Info `json:"embedded,omitempty"`
+}
+
+// Teredo is the server and the deobfuscated client mapping of a Teredo address (RFC 4380)
+type Teredo struct {
+	Server     string `json:"server"`
+	Client     string `json:"client"`
+	ClientPort uint16 `json:"client_port"`
+	Cone       bool   `json:"cone"`
+}
+
+// EUI64 is the MAC address of an EUI-64 interface identifier (RFC 4291 appendix A).
+// A locally administered MAC has no OUI.
+type EUI64 struct {
+	MAC                 string `json:"mac"`
+	OUI                 string `json:"oui,omitempty"`
+	Vendor              string `json:"vendor,omitempty"`
+	LocallyAdministered bool   `json:"locally_administered"`
+}
+
+// EmbeddedIPv4Info is the geo and ASN of the IPv4 address embedded in an IPv6 address
+type EmbeddedIPv4Info struct {
+	IP              string          `json:"ip"`
+	ASN             uint            `json:"asn"`
+	ASNOrganization string          `json:"asn_organization"`
+	City            string          `json:"city"`
+	Country         string          `json:"country"`
+	CountryISO      string          `json:"country_iso"`
+	Coordinates     *Coordinates    `json:"coordinates"`
+	SpecialPurpose  *SpecialPurpose `json:"special_purpose,omitempty"`
+}
+
+// SpecialPurpose is the entry of the IANA special-purpose address registries holding an IP
+type SpecialPurpose struct {
+	Prefix      string   `json:"prefix"`
+	Name        string   `json:"name"`
+	RFC         []string `json:"rfc"`
+	Forwardable bool     `json:"forwardable"`
+	// GloballyReachable is nil when it depends on the IPv4 address embedded in the IP, for 6to4 and Teredo
+	GloballyReachable *bool `json:"globally_reachable"`
+}
+
+// Unreachable reports whether the IP is never globally reachable, so it has no geolocation, AS or route objects
+func (s *SpecialPurpose) Unreachable() bool {
+	return s != nil && s.GloballyReachable != nil && !*s.GloballyReachable
+}
+
+// ReverseDNS is the PTR names of an IP, each resolved forward. Error is set when the PTR lookup failed,
+// an IP without PTR records has no names and no error. Pending is set when the lookup did not finish before the
+// deadline of the request, its answer is then cached by the resolver for the next request.
+type ReverseDNS struct {
+	Names   []PTRName `json:"names"`
+	Error   string    `json:"error,omitempty"`
+	Pending bool      `json:"pending,omitempty"`
+}
+
+// PTRName is a PTR name of an IP, forward confirmed when one of its addresses is the IP
+type PTRName struct {
+	Name             string `json:"name"`
+	ForwardConfirmed bool   `json:"forward_confirmed"`
+	Error            string `json:"error,omitempty"`
+	Pending          bool   `json:"pending,omitempty"`
+}
+
+// Hostname returns the first forward confirmed name without its trailing dot, empty if there is none
+func (r *ReverseDNS) Hostname() string {
+	if r == nil {
+		return ""
+	}
+	for _, name := range r.Names {
+		if name.ForwardConfirmed {
+			return strings.TrimSuffix(name.Name, ".")
+		}
+	}
+	return ""
+}
+
+// GeoRecord is the geolocation of an IP, normalized from the records of a geo provider.
+// Names are keyed by locale, providers without translations only have en.
+type GeoRecord struct {
+	City          map[string]string
+	Country       map[string]string
+	CountryISO    string
+	IsEU          bool
+	Region        map[string]string
+	RegionCode    string
+	PostalCode    string
+	Coordinates   *Coordinates
+	Timezone      string
+	Continent     map[string]string
+	ContinentCode string
+	// Provider names the provider the record is from
+	Provider string
+}
+
+// Empty reports whether the provider had no geolocation for the IP
+func (r *GeoRecord) Empty() bool {
+	return r == nil || (r.CountryISO == "" && len(r.Country) == 0 && len(r.City) == 0 && r.Coordinates == nil)
+}
+
+// ASNRecord is the origin AS of an IP, normalized from the records of a geo provider
+type ASNRecord struct {
+	ASN          uint
+	Organization string
+	// Provider names the provider the record is from
+	Provider string
+}
+
+// Empty reports whether the provider had no AS for the IP
+func (r *ASNRecord) Empty() bool {
+	return r == nil || (r.ASN == 0 && r.Organization == "")
+}
+
+// euCountries are the ISO codes of the EU member states
+var euCountries = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true, "DK": true,
+	"EE": true, "ES": true, "FI": true, "FR": true, "GR": true, "HR": true, "HU": true,
+	"IE": true, "IT": true, "LT": true, "LU": true, "LV": true, "MT": true, "NL": true,
+	"PL": true, "PT": true, "RO": true, "SE": true, "SI": true, "SK": true,
+}
+
+// IsEUCountry reports whether iso is the code of an EU member state, for providers that do not flag them
+func IsEUCountry(iso string) bool {
+	return euCountries[strings.ToUpper(iso)]
 }
@@ -0,0 +1,339 @@
+// Package mrt reads the RIB entries of MRT TABLE_DUMP_V2 files (RFC 6396), as dumped by RouteViews and RIPE RIS.
+package mrt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+)
+
+const (
+	typeTableDumpV2 = 13
+
+	subtypePeerIndexTable        = 1
+	subtypeRIBIPv4Unicast        = 2
+	subtypeRIBIPv6Unicast        = 4
+	subtypeRIBIPv4UnicastAddPath = 8
+	subtypeRIBIPv6UnicastAddPath = 10
+	attributeASPath              = 2
+	attributeFlagExtendedLength  = 0x10
+	peerTypeIPv6                 = 0x01
+	peerTypeAS4                  = 0x02
+	headerLength                 = 12
+	maxRecordLength              = 16 << 20
+)
+
+// AS_PATH segment types
+const (
+	SegmentASSet          = 1
+	SegmentASSequence     = 2
+	SegmentConfedSequence = 3
+	SegmentConfedSet      = 4
+)
+
+// ErrNoPeerIndex is returned for a RIB record read before the PEER_INDEX_TABLE
+var ErrNoPeerIndex = errors.New("RIB record before the peer index table")
+
+// Peer is an entry of the PEER_INDEX_TABLE, a BGP peer of the collector
+type Peer struct {
+	BGPID netip.Addr
+	Addr  netip.Addr
+	AS    uint32
+}
+
+// Route is a RIB record, a prefix and the entries of the peers announcing it
+type Route struct {
+	Prefix  netip.Prefix
+	Entries []Entry
+}
+
+// Entry is the route of a prefix as announced by a peer
+type Entry struct {
+	// PeerIndex is the index of the peer in Reader.Peers
+	PeerIndex  int
+	Originated time.Time
+	ASPath     []Segment
+}
+
+// Segment is an AS_PATH segment, its ASNs are always 4 bytes in TABLE_DUMP_V2
+type Segment struct {
+	Type uint8
+	ASNs []uint32
+}
+
+// Origin returns the origin AS of the entry, the last AS of the path. A path ending in an AS_SET only has an origin
+// when the set holds a single AS.
+func (e Entry) Origin() (uint32, bool) {
+	for i := len(e.ASPath) - 1; i >= 0; i-- {
+		segment := e.ASPath[i]
+		if len(segment.ASNs) == 0 {
+			continue
+		}
+		switch segment.Type {
+		case SegmentASSequence:
+			return segment.ASNs[len(segment.ASNs)-1], true
+		case SegmentASSet:
+			if len(segment.ASNs) == 1 {
+				return segment.ASNs[0], true
+			}
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// PathLength returns the AS path length as counted by the BGP decision process, an AS_SET counts as one and
+// confederation segments are not counted (RFC 4271 9.1.2.2, RFC 5065 5.3)
+func (e Entry) PathLength() int {
+	length := 0
+	for _, segment := range e.ASPath {
+		switch segment.Type {
+		case SegmentASSequence:
+			length += len(segment.ASNs)
+		case SegmentASSet:
+			length++
+		}
+	}
+	return length
+}
+
+// Reader reads the RIB records of a TABLE_DUMP_V2 file, records of other types and subtypes are skipped
+type Reader struct {
+	r   *bufio.Reader
+	buf []byte
+	// Peers is the PEER_INDEX_TABLE, set when it has been read
+	Peers []Peer
+	// Time is the timestamp of the last record read
+	Time time.Time
+}
+
+// NewReader returns a reader of the MRT records of r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, 1<<20)}
+}
+
+// Next returns the next unicast RIB record, or io.EOF at the end of the file
+func (r *Reader) Next() (*Route, error) {
+	header := make([]byte, headerLength)
+	for {
+		if _, err := io.ReadFull(r.r, header); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("truncated MRT header: %w", err)
+			}
+			return nil, err
+		}
+
+		timestamp := binary.BigEndian.Uint32(header[0:4])
+		recordType := binary.BigEndian.Uint16(header[4:6])
+		subtype := binary.BigEndian.Uint16(header[6:8])
+		length := binary.BigEndian.Uint32(header[8:12])
+		if length > maxRecordLength {
+			return nil, fmt.Errorf("MRT record of %d bytes is too long", length)
+		}
+
+		if cap(r.buf) < int(length) {
+			r.buf = make([]byte, length)
+		}
+		body := r.buf[:length]
+		if _, err := io.ReadFull(r.r, body); err != nil {
+			return nil, fmt.Errorf("truncated MRT record: %w", io.ErrUnexpectedEOF)
+		}
+		r.Time = time.Unix(int64(timestamp), 0).UTC()
+
+		if recordType != typeTableDumpV2 {
+			continue
+		}
+
+		switch subtype {
+		case subtypePeerIndexTable:
+			peers, err := parsePeerIndexTable(body)
+			if err != nil {
+				return nil, err
+			}
+			r.Peers = peers
+		case subtypeRIBIPv4Unicast, subtypeRIBIPv4UnicastAddPath, subtypeRIBIPv6Unicast, subtypeRIBIPv6UnicastAddPath:
+			if r.Peers == nil {
+				return nil, ErrNoPeerIndex
+			}
+			ipv6 := subtype == subtypeRIBIPv6Unicast || subtype == subtypeRIBIPv6UnicastAddPath
+			addPath := subtype == subtypeRIBIPv4UnicastAddPath || subtype == subtypeRIBIPv6UnicastAddPath
+			return r.parseRIB(body, ipv6, addPath)
+		}
+	}
+}
+
+// decoder reads big endian fields of a record, the first read past its end sets err
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.b) {
+		d.err = fmt.Errorf("truncated MRT record: %w", io.ErrUnexpectedEOF)
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) uint8() uint8 {
+	if b := d.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint16() uint16 {
+	if b := d.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) addr(ipv6 bool) netip.Addr {
+	if ipv6 {
+		if b := d.bytes(16); b != nil {
+			return netip.AddrFrom16([16]byte(b))
+		}
+		return netip.Addr{}
+	}
+	if b := d.bytes(4); b != nil {
+		return netip.AddrFrom4([4]byte(b))
+	}
+	return netip.Addr{}
+}
+
+// parsePeerIndexTable reads the collector's peers, RFC 6396 4.3.1
+func parsePeerIndexTable(body []byte) ([]Peer, error) {
+	d := &decoder{b: body}
+	d.bytes(4) // collector BGP ID
+	d.bytes(int(d.uint16()))
+
+	count := int(d.uint16())
+	peers := make([]Peer, 0, count)
+	for range count {
+		peerType := d.uint8()
+		peer := Peer{
+			BGPID: d.addr(false),
+			Addr:  d.addr(peerType&peerTypeIPv6 != 0),
+		}
+		if peerType&peerTypeAS4 != 0 {
+			peer.AS = d.uint32()
+		} else {
+			peer.AS = uint32(d.uint16())
+		}
+		if d.err != nil {
+			return nil, fmt.Errorf("peer index table: %w", d.err)
+		}
+		peers = append(peers, peer)
+	}
+
+	return peers, nil
+}
+
+// parseRIB reads an AFI/SAFI specific RIB record, RFC 6396 4.3.2 and RFC 8050 with add-path
+func (r *Reader) parseRIB(body []byte, ipv6, addPath bool) (*Route, error) {
+	d := &decoder{b: body}
+	d.uint32() // sequence number
+
+	bits := int(d.uint8())
+	if (ipv6 && bits > 128) || (!ipv6 && bits > 32) {
+		return nil, fmt.Errorf("invalid prefix length %d", bits)
+	}
+	var raw [16]byte
+	copy(raw[:], d.bytes((bits+7)/8))
+
+	var addr netip.Addr
+	if ipv6 {
+		addr = netip.AddrFrom16(raw)
+	} else {
+		addr = netip.AddrFrom4([4]byte(raw[:4]))
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return nil, err
+	}
+
+	count := int(d.uint16())
+	route := &Route{Prefix: prefix, Entries: make([]Entry, 0, count)}
+	for range count {
+		entry := Entry{PeerIndex: int(d.uint16())}
+		entry.Originated = time.Unix(int64(d.uint32()), 0).UTC()
+		if addPath {
+			d.uint32() // path identifier
+		}
+		attributes := d.bytes(int(d.uint16()))
+		if d.err != nil {
+			return nil, fmt.Errorf("%s: %w", prefix, d.err)
+		}
+		if entry.PeerIndex >= len(r.Peers) {
+			return nil, fmt.Errorf("%s: peer index %d out of %d peers", prefix, entry.PeerIndex, len(r.Peers))
+		}
+
+		entry.ASPath, err = parseASPath(attributes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", prefix, err)
+		}
+		route.Entries = append(route.Entries, entry)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("%s: %w", prefix, d.err)
+	}
+
+	return route, nil
+}
+
+// parseASPath returns the AS_PATH segments of the BGP path attributes, nil without an AS_PATH
+func parseASPath(attributes []byte) ([]Segment, error) {
+	d := &decoder{b: attributes}
+	for len(d.b) > 0 {
+		flags := d.uint8()
+		attributeType := d.uint8()
+		var length int
+		if flags&attributeFlagExtendedLength != 0 {
+			length = int(d.uint16())
+		} else {
+			length = int(d.uint8())
+		}
+		value := d.bytes(length)
+		if d.err != nil {
+			return nil, fmt.Errorf("path attributes: %w", d.err)
+		}
+		if attributeType != attributeASPath {
+			continue
+		}
+
+		var segments []Segment
+		v := &decoder{b: value}
+		for len(v.b) > 0 {
+			segment := Segment{Type: v.uint8()}
+			count := int(v.uint8())
+			segment.ASNs = make([]uint32, 0, count)
+			for range count {
+				segment.ASNs = append(segment.ASNs, v.uint32())
+			}
+			if v.err != nil {
+				return nil, fmt.Errorf("AS_PATH: %w", v.err)
+			}
+			segments = append(segments, segment)
+		}
+		return segments, nil
+	}
+	return nil, nil
+}
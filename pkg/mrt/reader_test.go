@@ -0,0 +1,181 @@
+package mrt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// record returns an MRT record of type and subtype holding body
+func record(recordType, subtype uint16, body []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, 1704236103)
+	b = binary.BigEndian.AppendUint16(b, recordType)
+	b = binary.BigEndian.AppendUint16(b, subtype)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(body)))
+	return append(b, body...)
+}
+
+// peerIndexTable returns a PEER_INDEX_TABLE body, an IPv4 peer with a 2 byte AS and an IPv6 peer with a 4 byte AS
+func peerIndexTable() []byte {
+	b := []byte{192, 0, 2, 1}
+	b = binary.BigEndian.AppendUint16(b, 4)
+	b = append(b, "rrc0"...)
+	b = binary.BigEndian.AppendUint16(b, 2)
+
+	b = append(b, 0)
+	b = append(b, 192, 0, 2, 10)
+	b = append(b, 192, 0, 2, 10)
+	b = binary.BigEndian.AppendUint16(b, 64496)
+
+	b = append(b, peerTypeIPv6|peerTypeAS4)
+	b = append(b, 192, 0, 2, 11)
+	b = append(b, netip.MustParseAddr("2001:db8::11").AsSlice()...)
+	b = binary.BigEndian.AppendUint32(b, 4200000000)
+	return b
+}
+
+// asPath returns the path attributes, an ORIGIN and an AS_PATH of segments
+func asPath(segments ...Segment) []byte {
+	var value []byte
+	for _, segment := range segments {
+		value = append(value, segment.Type, uint8(len(segment.ASNs)))
+		for _, asn := range segment.ASNs {
+			value = binary.BigEndian.AppendUint32(value, asn)
+		}
+	}
+
+	b := []byte{0x40, 1, 1, 0}
+	b = append(b, 0x50, attributeASPath)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(value)))
+	return append(b, value...)
+}
+
+// rib returns a RIB record body for prefix, with an entry of attributes per peer index
+func rib(prefix netip.Prefix, addPath bool, entries map[uint16][]byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, 7)
+	b = append(b, uint8(prefix.Bits()))
+	b = append(b, prefix.Addr().AsSlice()[:(prefix.Bits()+7)/8]...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(entries)))
+	for _, peer := range []uint16{0, 1} {
+		attributes, ok := entries[peer]
+		if !ok {
+			continue
+		}
+		b = binary.BigEndian.AppendUint16(b, peer)
+		b = binary.BigEndian.AppendUint32(b, 1704000000)
+		if addPath {
+			b = binary.BigEndian.AppendUint32(b, 1)
+		}
+		b = binary.BigEndian.AppendUint16(b, uint16(len(attributes)))
+		b = append(b, attributes...)
+	}
+	return b
+}
+
+func TestReader(t *testing.T) {
+	file := &bytes.Buffer{}
+	file.Write(record(typeTableDumpV2, subtypePeerIndexTable, peerIndexTable()))
+	// a BGP4MP record is skipped
+	file.Write(record(16, 4, []byte{1, 2, 3}))
+	file.Write(record(typeTableDumpV2, subtypeRIBIPv4Unicast, rib(netip.MustParsePrefix("198.51.100.0/22"), false, map[uint16][]byte{
+		0: asPath(Segment{Type: SegmentASSequence, ASNs: []uint32{64496, 64500, 64500, 64511}}),
+		1: asPath(Segment{Type: SegmentASSequence, ASNs: []uint32{64497}}, Segment{Type: SegmentASSet, ASNs: []uint32{64511, 64512}}),
+	})))
+	file.Write(record(typeTableDumpV2, subtypeRIBIPv6UnicastAddPath, rib(netip.MustParsePrefix("2001:db8:1000::/36"), true, map[uint16][]byte{
+		1: asPath(Segment{Type: SegmentConfedSequence, ASNs: []uint32{65001}}, Segment{Type: SegmentASSequence, ASNs: []uint32{64497, 64499}}),
+	})))
+
+	r := NewReader(file)
+
+	route, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []Peer{
+		{BGPID: netip.MustParseAddr("192.0.2.10"), Addr: netip.MustParseAddr("192.0.2.10"), AS: 64496},
+		{BGPID: netip.MustParseAddr("192.0.2.11"), Addr: netip.MustParseAddr("2001:db8::11"), AS: 4200000000},
+	}, r.Peers)
+	assert.Equal(t, time.Unix(1704236103, 0).UTC(), r.Time)
+
+	assert.Equal(t, netip.MustParsePrefix("198.51.100.0/22"), route.Prefix)
+	require.Len(t, route.Entries, 2)
+	assert.Equal(t, 0, route.Entries[0].PeerIndex)
+	assert.Equal(t, time.Unix(1704000000, 0).UTC(), route.Entries[0].Originated)
+	origin, ok := route.Entries[0].Origin()
+	assert.True(t, ok)
+	assert.Equal(t, uint32(64511), origin)
+	assert.Equal(t, 4, route.Entries[0].PathLength())
+
+	// an AS_SET of two ASNs has no single origin and counts as one
+	_, ok = route.Entries[1].Origin()
+	assert.False(t, ok)
+	assert.Equal(t, 2, route.Entries[1].PathLength())
+
+	route, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, netip.MustParsePrefix("2001:db8:1000::/36"), route.Prefix)
+	require.Len(t, route.Entries, 1)
+	assert.Equal(t, 1, route.Entries[0].PeerIndex)
+	origin, ok = route.Entries[0].Origin()
+	assert.True(t, ok)
+	assert.Equal(t, uint32(64499), origin)
+	assert.Equal(t, 2, route.Entries[0].PathLength())
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderErrors(t *testing.T) {
+	prefix := netip.MustParsePrefix("198.51.100.0/24")
+	path := asPath(Segment{Type: SegmentASSequence, ASNs: []uint32{64496}})
+
+	tts := []struct {
+		name string
+		file []byte
+		want error
+	}{
+		{
+			name: "rib before peer index",
+			file: record(typeTableDumpV2, subtypeRIBIPv4Unicast, rib(prefix, false, map[uint16][]byte{0: path})),
+			want: ErrNoPeerIndex,
+		},
+		{
+			name: "truncated header",
+			file: []byte{0, 0, 0},
+			want: io.ErrUnexpectedEOF,
+		},
+		{
+			name: "truncated record",
+			file: record(typeTableDumpV2, subtypePeerIndexTable, peerIndexTable())[:20],
+			want: io.ErrUnexpectedEOF,
+		},
+		{
+			name: "truncated rib entry",
+			file: append(record(typeTableDumpV2, subtypePeerIndexTable, peerIndexTable()),
+				record(typeTableDumpV2, subtypeRIBIPv4Unicast, rib(prefix, false, map[uint16][]byte{0: path})[:14])...),
+			want: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(bytes.NewReader(tt.file)).Next()
+			assert.True(t, errors.Is(err, tt.want), "got %v", err)
+		})
+	}
+
+	t.Run("peer index out of range", func(t *testing.T) {
+		file := append(record(typeTableDumpV2, subtypePeerIndexTable, peerIndexTable()),
+			record(typeTableDumpV2, subtypeRIBIPv4Unicast, rib(prefix, false, map[uint16][]byte{0: path}))...)
+		// point the entry at peer 2 of 2
+		file[len(file)-len(path)-7] = 2
+
+		_, err := NewReader(bytes.NewReader(file)).Next()
+		assert.ErrorContains(t, err, "peer index 2")
+	})
+}
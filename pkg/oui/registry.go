@@ -0,0 +1,105 @@
+// Package oui maps the OUI of a MAC address to its vendor with the IEEE MA-L registry.
+//
+// The registry is embedded as oui.csv, the default for lookups, go generate refreshes it from
+// https://standards-oui.ieee.org/oui/oui.csv. Load reads another copy, e.g. a newer one.
+package oui
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:generate curl -sSfL -o oui.csv https://standards-oui.ieee.org/oui/oui.csv
+
+//go:embed oui.csv
+var embedded []byte
+
+// Registry holds the vendors of the assigned OUIs
+type Registry struct {
+	vendors map[[3]byte]string
+}
+
+// defaultRegistry is the embedded registry
+var defaultRegistry = mustLoad()
+
+func mustLoad() *Registry {
+	r, err := Parse(bytes.NewReader(embedded))
+	if err != nil {
+		panic(fmt.Sprintf("oui: embedded registry: %v", err))
+	}
+	return r
+}
+
+// Default returns the embedded registry
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Load reads the registry csv at path
+func Load(path string) (*Registry, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Parse reads a registry in IEEE's csv format, Registry,Assignment,Organization Name,Organization Address
+func Parse(r io.Reader) (*Registry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(header) < 3 || header[1] != "Assignment" || header[2] != "Organization Name" {
+		return nil, errors.New("not an IEEE registry csv")
+	}
+
+	registry := &Registry{vendors: map[[3]byte]string{}}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 || record[0] != "MA-L" {
+			continue
+		}
+
+		assignment, err := hex.DecodeString(record[1])
+		if err != nil || len(assignment) != 3 {
+			return nil, fmt.Errorf("invalid assignment %q", record[1])
+		}
+		registry.vendors[[3]byte(assignment)] = strings.TrimSpace(record[2])
+	}
+
+	return registry, nil
+}
+
+// Vendor returns the organization the OUI of mac is assigned to, empty when it is not in the registry
+func (r *Registry) Vendor(mac net.HardwareAddr) string {
+	if len(mac) < 3 {
+		return ""
+	}
+	return r.vendors[[3]byte(mac[:3])]
+}
+
+// Len returns the number of OUIs in the registry
+func (r *Registry) Len() int {
+	return len(r.vendors)
+}
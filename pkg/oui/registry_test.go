@@ -0,0 +1,77 @@
+package oui
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefault(t *testing.T) {
+	r := Default()
+	assert.Greater(t, r.Len(), 0)
+
+	mac, err := net.ParseMAC("00:50:56:01:02:03")
+	require.NoError(t, err)
+	assert.Equal(t, "VMware, Inc.", r.Vendor(mac))
+
+	mac, err = net.ParseMAC("02:00:00:01:02:03")
+	require.NoError(t, err)
+	assert.Empty(t, r.Vendor(mac))
+}
+
+func TestParse(t *testing.T) {
+	tts := []struct {
+		name    string
+		file    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "ma-l rows",
+			file: "Registry,Assignment,Organization Name,Organization Address\n" +
+				"MA-L,00000C,\"Cisco Systems, Inc\",170 WEST TASMAN DRIVE SAN JOSE CA US 95134\n" +
+				"MA-L,b827eb,Raspberry Pi Foundation,Mitchell Wood House Caldecote Cambridgeshire GB CB23 7NU\n",
+			want: map[string]string{"00:00:0c:00:00:01": "Cisco Systems, Inc", "b8:27:eb:00:00:01": "Raspberry Pi Foundation"},
+		},
+		{
+			name: "other registries are skipped",
+			file: "Registry,Assignment,Organization Name,Organization Address\nMA-M,70B3D5F,Example,\n",
+			want: map[string]string{"70:b3:d5:f0:00:01": ""},
+		},
+		{name: "not a registry", file: "prefix,name\n", wantErr: true},
+		{name: "invalid assignment", file: "Registry,Assignment,Organization Name,Organization Address\nMA-L,00000X,Example,\n", wantErr: true},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(strings.NewReader(tt.file))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			for s, vendor := range tt.want {
+				mac, err := net.ParseMAC(s)
+				require.NoError(t, err)
+				assert.Equal(t, vendor, r.Vendor(mac))
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oui.csv")
+	require.NoError(t, os.WriteFile(path, []byte("Registry,Assignment,Organization Name,Organization Address\nMA-L,001C42,\"Parallels, Inc.\",\n"), 0600))
+
+	r, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Len())
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.csv"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package rpsl
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// SourceUnknown is used for objects without a source attribute
+const SourceUnknown = "unknown"
+
+// Changes holds the route objects added, removed and modified within one source
+type Changes struct {
+	Added    []*Object `json:"added,omitempty"`
+	Removed  []*Object `json:"removed,omitempty"`
+	Modified []*Object `json:"modified,omitempty"`
+}
+
+// IsEmpty returns true if there are no changes
+func (c *Changes) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// Equal returns true if both objects carry the same attributes
+func (no *Object) Equal(other *Object) bool {
+	if no == nil || other == nil {
+		return no == other
+	}
+
+	return no.Network == other.Network &&
+		no.Origin == other.Origin &&
+		no.LastModified == other.LastModified &&
+		no.Owner == other.Owner &&
+		no.ORGName == other.ORGName &&
+		no.ORG == other.ORG &&
+		no.OwnerID == other.OwnerID &&
+		no.Source == other.Source &&
+		slices.Equal(no.Country, other.Country) &&
+		slices.Equal(no.Remarks, other.Remarks) &&
+		slices.Equal(no.Created, other.Created)
+}
+
+// sourceName returns the lower case source of the object, e.g. "ripe" or "radb"
+func (no *Object) sourceName() string {
+	if no.Source == "" {
+		return SourceUnknown
+	}
+	return strings.ToLower(no.Source)
+}
+
+// Diff compares two RouterClass snapshots and returns the changes grouped by source.
+// A route object is identified by its network and origin.
+func Diff(ctx context.Context, previous, current RouterClass) map[string]*Changes {
+	reply := map[string]*Changes{}
+
+	changesFor := func(obj *Object) *Changes {
+		source := obj.sourceName()
+		changes, ok := reply[source]
+		if !ok {
+			changes = &Changes{}
+			reply[source] = changes
+		}
+		return changes
+	}
+
+	for network, currentASN := range current {
+		previousASN := previous[network]
+		for origin, obj := range currentASN {
+			if obj == nil {
+				continue
+			}
+			previousObj, ok := previousASN[origin]
+			switch {
+			case !ok || previousObj == nil:
+				changes := changesFor(obj)
+				changes.Added = append(changes.Added, obj)
+			case !previousObj.Equal(obj):
+				changes := changesFor(obj)
+				changes.Modified = append(changes.Modified, obj)
+			}
+		}
+	}
+
+	for network, previousASN := range previous {
+		currentASN := current[network]
+		for origin, obj := range previousASN {
+			if obj == nil {
+				continue
+			}
+			if currentObj, ok := currentASN[origin]; !ok || currentObj == nil {
+				changes := changesFor(obj)
+				changes.Removed = append(changes.Removed, obj)
+			}
+		}
+	}
+
+	return reply
+}
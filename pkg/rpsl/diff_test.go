@@ -0,0 +1,87 @@
+package rpsl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	ripeObject := &Object{Network: "192.0.2.0/24", Origin: "AS64500", Source: "RIPE"}
+	radbObject := &Object{Network: "2001:db8::/32", Origin: "AS64501", Source: "RADB"}
+
+	tts := []struct {
+		name     string
+		previous RouterClass
+		current  RouterClass
+		want     map[string]*Changes
+	}{
+		{
+			name:     "no changes",
+			previous: RouterClass{"192.0.2.0/24": ASN{"AS64500": ripeObject}},
+			current:  RouterClass{"192.0.2.0/24": ASN{"AS64500": ripeObject}},
+			want:     map[string]*Changes{},
+		},
+		{
+			name:     "added",
+			previous: RouterClass{},
+			current: RouterClass{
+				"192.0.2.0/24":  ASN{"AS64500": ripeObject},
+				"2001:db8::/32": ASN{"AS64501": radbObject},
+			},
+			want: map[string]*Changes{
+				"ripe": {Added: []*Object{ripeObject}},
+				"radb": {Added: []*Object{radbObject}},
+			},
+		},
+		{
+			name: "removed",
+			previous: RouterClass{
+				"192.0.2.0/24":  ASN{"AS64500": ripeObject},
+				"2001:db8::/32": ASN{"AS64501": radbObject},
+			},
+			current: RouterClass{"192.0.2.0/24": ASN{"AS64500": ripeObject}},
+			want: map[string]*Changes{
+				"radb": {Removed: []*Object{radbObject}},
+			},
+		},
+		{
+			name:     "modified",
+			previous: RouterClass{"192.0.2.0/24": ASN{"AS64500": ripeObject}},
+			current: RouterClass{"192.0.2.0/24": ASN{"AS64500": &Object{
+				Network: "192.0.2.0/24", Origin: "AS64500", Source: "RIPE", LastModified: "2024-01-01T00:00:00Z",
+			}}},
+			want: map[string]*Changes{
+				"ripe": {Modified: []*Object{{Network: "192.0.2.0/24", Origin: "AS64500", Source: "RIPE", LastModified: "2024-01-01T00:00:00Z"}}},
+			},
+		},
+		{
+			name:     "new origin for existing network",
+			previous: RouterClass{"192.0.2.0/24": ASN{"AS64500": ripeObject}},
+			current: RouterClass{"192.0.2.0/24": ASN{
+				"AS64500": ripeObject,
+				"AS64502": {Network: "192.0.2.0/24", Origin: "AS64502"},
+			}},
+			want: map[string]*Changes{
+				SourceUnknown: {Added: []*Object{{Network: "192.0.2.0/24", Origin: "AS64502"}}},
+			},
+		},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(t.Context(), tt.previous, tt.current)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestObjectEqual(t *testing.T) {
+	a := &Object{Network: "192.0.2.0/24", Origin: "AS64500", Remarks: []string{"a"}}
+	b := &Object{Network: "192.0.2.0/24", Origin: "AS64500", Remarks: []string{"a"}}
+	c := &Object{Network: "192.0.2.0/24", Origin: "AS64500", Remarks: []string{"b"}}
+
+	assert.True(t, a.Equal(b))
+	assert.False(t, a.Equal(c))
+	assert.False(t, a.Equal(nil))
+}
@@ -136,3 +136,12 @@ func RouterClassOpinionatedMerge(ctx context.Context, r1, r2 RouterClass) (Route
 
 	return r2, nil
 }
+
+// CountObjects returns the number of route objects, counting each origin of a network
+func (r RouterClass) CountObjects() int {
+	count := 0
+	for _, asn := range r {
+		count += len(asn)
+	}
+	return count
+}
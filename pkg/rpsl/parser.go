@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"time"
+	"unique"
 
 	"github.com/3th1nk/cidr"
 )
@@ -134,6 +135,7 @@ type Object struct {
 	ORGName      string   `json:"org-name,omitempty"`
 	ORG          string   `json:"org,omitempty"`
 	OwnerID      string   `json:"ownerid,omitempty"`
+	Source       string   `json:"source,omitempty"`
 }
 
 func (no *Object) FindNetwork(ctx context.Context, ip string) (bool, error) {
@@ -174,6 +176,9 @@ func (r *Object) Add(key, value string) error {
 		r.ORG = value
 	case OwnerID:
 		r.OwnerID = value
+	case Source:
+		// Only a handful of distinct sources exist, share the backing string between objects
+		r.Source = unique.Make(value).Value()
 	}
 	return nil
 }
@@ -0,0 +1,133 @@
+// Package specialpurpose classifies IPs by the IANA IPv4 and IPv6 special-purpose address registries.
+//
+// The registries are embedded as published by IANA, iana-ipv4-special-registry-1.csv and iana-ipv6-special-registry-1.csv
+// from https://www.iana.org/assignments/iana-ipv4-special-registry and https://www.iana.org/assignments/iana-ipv6-special-registry,
+// replace the files to update them.
+package specialpurpose
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"ip_service/pkg/model"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+//go:embed iana-ipv4-special-registry-1.csv
+var ipv4Registry []byte
+
+//go:embed iana-ipv6-special-registry-1.csv
+var ipv6Registry []byte
+
+// multicast are the multicast blocks of the IANA address space registries, which the special-purpose registries leave out
+var multicast = []entry{
+	{prefix: netip.MustParsePrefix("224.0.0.0/4"), record: model.SpecialPurpose{Prefix: "224.0.0.0/4", Name: "Multicast", RFC: []string{"RFC5771"}, Forwardable: true, GloballyReachable: new(bool)}},
+	{prefix: netip.MustParsePrefix("ff00::/8"), record: model.SpecialPurpose{Prefix: "ff00::/8", Name: "Multicast", RFC: []string{"RFC4291"}, Forwardable: true, GloballyReachable: new(bool)}},
+}
+
+type entry struct {
+	prefix netip.Prefix
+	record model.SpecialPurpose
+}
+
+// entries holds the registries, most specific first
+var entries = mustLoad()
+
+func mustLoad() []entry {
+	v4, err := parse(bytes.NewReader(ipv4Registry))
+	if err != nil {
+		panic(fmt.Sprintf("specialpurpose: ipv4 registry: %v", err))
+	}
+	v6, err := parse(bytes.NewReader(ipv6Registry))
+	if err != nil {
+		panic(fmt.Sprintf("specialpurpose: ipv6 registry: %v", err))
+	}
+
+	all := slices.Concat(v4, v6, multicast)
+	slices.SortStableFunc(all, func(a, b entry) int {
+		return b.prefix.Bits() - a.prefix.Bits()
+	})
+	return all
+}
+
+// Lookup returns the most specific registry entry holding addr, or nil for an ordinary address.
+// IPv4-mapped IPv6 addresses are classified as their IPv4 address.
+func Lookup(addr netip.Addr) *model.SpecialPurpose {
+	addr = addr.Unmap()
+	for _, e := range entries {
+		if e.prefix.Contains(addr) {
+			record := e.record
+			return &record
+		}
+	}
+	return nil
+}
+
+var (
+	// footnote matches the footnote references of IANA's cells, e.g. "False [1]"
+	footnote = regexp.MustCompile(`\s*\[\d+\]`)
+	// rfcReference matches the references of the RFC column, e.g. "[RFC8880][RFC7050], Section 2.2"
+	rfcReference = regexp.MustCompile(`\[([^\]]+)\]`)
+)
+
+// parse reads a registry in IANA's csv format, terminated entries are skipped
+func parse(r io.Reader) ([]entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 10
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty registry")
+	}
+
+	var entries []entry
+	for _, row := range rows[1:] {
+		if row[4] != "N/A" {
+			continue
+		}
+
+		forwardable := parseBool(row[7])
+		record := model.SpecialPurpose{
+			Name:              row[1],
+			Forwardable:       forwardable != nil && *forwardable,
+			GloballyReachable: parseBool(row[8]),
+		}
+		for _, match := range rfcReference.FindAllStringSubmatch(row[2], -1) {
+			record.RFC = append(record.RFC, match[1])
+		}
+
+		// a cell may hold several blocks, e.g. "192.0.0.170/32, 192.0.0.171/32"
+		for block := range strings.SplitSeq(footnote.ReplaceAllString(row[0], ""), ",") {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(block))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", row[1], err)
+			}
+			record := record
+			record.Prefix = prefix.String()
+			entries = append(entries, entry{prefix: prefix, record: record})
+		}
+	}
+
+	return entries, nil
+}
+
+// parseBool returns the value of a True, False or N/A cell, nil for N/A
+func parseBool(cell string) *bool {
+	var b bool
+	switch footnote.ReplaceAllString(cell, "") {
+	case "True":
+		b = true
+	case "False":
+	default:
+		return nil
+	}
+	return &b
+}
@@ -0,0 +1,103 @@
+package specialpurpose
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	tts := []struct {
+		name            string
+		ip              string
+		wantPrefix      string
+		wantName        string
+		wantRFC         []string
+		wantUnreachable bool
+	}{
+		{name: "global ipv4", ip: "130.242.0.1"},
+		{name: "global ipv6", ip: "2001:6b0::1"},
+		{name: "rfc1918", ip: "10.1.2.3", wantPrefix: "10.0.0.0/8", wantName: "Private-Use", wantRFC: []string{"RFC1918"}, wantUnreachable: true},
+		{name: "ipv4-mapped rfc1918", ip: "::ffff:192.168.1.1", wantPrefix: "192.168.0.0/16", wantName: "Private-Use", wantRFC: []string{"RFC1918"}, wantUnreachable: true},
+		{name: "cgnat", ip: "100.64.1.1", wantPrefix: "100.64.0.0/10", wantName: "Shared Address Space", wantRFC: []string{"RFC6598"}, wantUnreachable: true},
+		{name: "loopback", ip: "127.0.0.1", wantPrefix: "127.0.0.0/8", wantName: "Loopback", wantRFC: []string{"RFC1122"}, wantUnreachable: true},
+		{name: "this host", ip: "0.0.0.0", wantPrefix: "0.0.0.0/32", wantName: `"This host on this network"`, wantRFC: []string{"RFC1122"}, wantUnreachable: true},
+		{name: "most specific wins", ip: "192.0.0.9", wantPrefix: "192.0.0.9/32", wantName: "Port Control Protocol Anycast", wantRFC: []string{"RFC7723"}},
+		{name: "several blocks in a cell", ip: "192.0.0.171", wantPrefix: "192.0.0.171/32", wantName: "NAT64/DNS64 Discovery", wantRFC: []string{"RFC8880", "RFC7050"}, wantUnreachable: true},
+		{name: "documentation", ip: "198.51.100.7", wantPrefix: "198.51.100.0/24", wantName: "Documentation (TEST-NET-2)", wantRFC: []string{"RFC5737"}, wantUnreachable: true},
+		{name: "benchmarking", ip: "198.19.0.1", wantPrefix: "198.18.0.0/15", wantName: "Benchmarking", wantRFC: []string{"RFC2544"}, wantUnreachable: true},
+		{name: "multicast", ip: "239.1.1.1", wantPrefix: "224.0.0.0/4", wantName: "Multicast", wantRFC: []string{"RFC5771"}, wantUnreachable: true},
+		{name: "limited broadcast", ip: "255.255.255.255", wantPrefix: "255.255.255.255/32", wantName: "Limited Broadcast", wantRFC: []string{"RFC8190", "RFC919"}, wantUnreachable: true},
+		{name: "reserved", ip: "250.0.0.1", wantPrefix: "240.0.0.0/4", wantName: "Reserved", wantRFC: []string{"RFC1112"}, wantUnreachable: true},
+		{name: "deprecated entries are skipped", ip: "192.88.99.1"},
+		{name: "ipv6 loopback", ip: "::1", wantPrefix: "::1/128", wantName: "Loopback Address", wantRFC: []string{"RFC4291"}, wantUnreachable: true},
+		{name: "ula", ip: "fd00::1", wantPrefix: "fc00::/7", wantName: "Unique-Local", wantRFC: []string{"RFC4193", "RFC8190"}, wantUnreachable: true},
+		{name: "ipv6 link local", ip: "fe80::1", wantPrefix: "fe80::/10", wantName: "Link-Local Unicast", wantRFC: []string{"RFC4291"}, wantUnreachable: true},
+		{name: "ipv6 documentation", ip: "2001:db8::1", wantPrefix: "2001:db8::/32", wantName: "Documentation", wantRFC: []string{"RFC3849"}, wantUnreachable: true},
+		{name: "ipv6 multicast", ip: "ff02::1", wantPrefix: "ff00::/8", wantName: "Multicast", wantRFC: []string{"RFC4291"}, wantUnreachable: true},
+		{name: "teredo depends on the embedded address", ip: "2001:0:4136:e378:8000:63bf:3fff:fdd2", wantPrefix: "2001::/32", wantName: "TEREDO", wantRFC: []string{"RFC4380", "RFC8190"}},
+		{name: "6to4 depends on the embedded address", ip: "2002:c000:204::1", wantPrefix: "2002::/16", wantName: "6to4", wantRFC: []string{"RFC3056"}},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lookup(netip.MustParseAddr(tt.ip))
+			if tt.wantName == "" {
+				assert.Nil(t, got)
+				return
+			}
+
+			require.NotNil(t, got)
+			assert.Equal(t, tt.wantPrefix, got.Prefix)
+			assert.Equal(t, tt.wantName, got.Name)
+			assert.Equal(t, tt.wantRFC, got.RFC)
+			assert.Equal(t, tt.wantUnreachable, got.Unreachable())
+		})
+	}
+}
+
+func TestLookupTristate(t *testing.T) {
+	teredo := Lookup(netip.MustParseAddr("2001::1"))
+	require.NotNil(t, teredo)
+	assert.Nil(t, teredo.GloballyReachable)
+	assert.True(t, teredo.Forwardable)
+
+	pcp := Lookup(netip.MustParseAddr("192.0.0.9"))
+	require.NotNil(t, pcp)
+	require.NotNil(t, pcp.GloballyReachable)
+	assert.True(t, *pcp.GloballyReachable)
+
+	// the returned entry is a copy
+	pcp.Name = "changed"
+	assert.Equal(t, "Port Control Protocol Anycast", Lookup(netip.MustParseAddr("192.0.0.9")).Name)
+}
+
+func TestParse(t *testing.T) {
+	tts := []struct {
+		name    string
+		csv     string
+		want    int
+		wantErr bool
+	}{
+		{name: "header only", csv: "Address Block,Name,RFC,Allocation Date,Termination Date,Source,Destination,Forwardable,Globally Reachable,Reserved-by-Protocol\n"},
+		{name: "empty", wantErr: true},
+		{name: "invalid prefix", csv: "h,h,h,h,h,h,h,h,h,h\nnope,x,[RFC1],2000-01,N/A,True,True,True,False,False\n", wantErr: true},
+		{name: "wrong number of fields", csv: "h,h,h,h,h,h,h,h,h,h\n10.0.0.0/8,x\n", wantErr: true},
+		{name: "two blocks", csv: "h,h,h,h,h,h,h,h,h,h\n\"10.0.0.0/8 [1], 11.0.0.0/8\",x,[RFC1],2000-01,N/A,True,True,True,False,False\n", want: 2},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(strings.NewReader(tt.csv))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, got, tt.want)
+		})
+	}
+}